@@ -0,0 +1,172 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"image/color"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// The about pages, one markdown-lite file per page in the order of their
+// names: "# " starts a title, "- " a bullet, a blank line a paragraph.
+// hidden.md is only shown when paging on past the last page.
+//
+//go:embed assets/about/*.md
+var aboutFiles embed.FS
+
+// Layout of the about pages, in screen pixels
+const (
+	aboutMargin     = 48
+	aboutTitleScale = 0.75
+	aboutTextScale  = 0.375
+	aboutLineGap    = 4
+)
+
+// aboutLine is a line of a page laid out at a font scale
+type aboutLine struct {
+	text  string
+	scale float64
+}
+
+// AboutOverlay shows a few pages about the original screen and this
+// remake, in the scroller font colored by the rasters
+type AboutOverlay struct {
+	pages   [][]aboutLine
+	hidden  []aboutLine
+	page    int // len(pages) for the hidden page
+	visible bool
+
+	// OnHidden is called when the hidden page is found
+	OnHidden func()
+
+	font   *BigFont
+	canvas *ebiten.Image
+	drawn  int // page drawn into canvas, -1 for none
+}
+
+// NewAboutOverlay lays out the embedded pages
+func NewAboutOverlay(font *BigFont) *AboutOverlay {
+	a := &AboutOverlay{font: font, drawn: -1}
+	names, err := fs.Glob(aboutFiles, "assets/about/*.md")
+	if err != nil {
+		log.Printf("Failed to list about pages: %v", err)
+		return a
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		data, err := aboutFiles.ReadFile(name)
+		if err != nil {
+			log.Printf("Failed to read about page %s: %v", name, err)
+			continue
+		}
+		if path.Base(name) == "hidden.md" {
+			a.hidden = layoutPage(font, string(data))
+			continue
+		}
+		a.pages = append(a.pages, layoutPage(font, string(data)))
+	}
+	return a
+}
+
+// layoutPage wraps the paragraphs of a markdown-lite page to the screen
+// width
+func layoutPage(font *BigFont, page string) []aboutLine {
+	var lines []aboutLine
+	for _, para := range strings.Split(strings.ReplaceAll(page, "\r\n", "\n"), "\n") {
+		para = strings.TrimSpace(para)
+		scale, indent := aboutTextScale, ""
+		switch {
+		case para == "":
+			lines = append(lines, aboutLine{scale: aboutTextScale})
+			continue
+		case strings.HasPrefix(para, "# "):
+			scale = aboutTitleScale
+			para = para[2:]
+		case strings.HasPrefix(para, "- "):
+			// Bullets are drawn with a dot, the text lines up after it
+			para = ". " + para[2:]
+			indent = "  "
+		}
+
+		line := ""
+		for _, word := range strings.Fields(font.Printable(para)) {
+			if line != "" && font.Measure(line+" "+word, scale) > screenWidth-2*aboutMargin {
+				lines = append(lines, aboutLine{line, scale})
+				line = indent
+			}
+			if line != "" && line != indent {
+				line += " "
+			}
+			line += word
+		}
+		lines = append(lines, aboutLine{line, scale})
+	}
+	return lines
+}
+
+// Toggle shows or hides the pages
+func (a *AboutOverlay) Toggle() {
+	a.visible = !a.visible
+}
+
+// Update turns the pages with the arrow keys
+func (a *AboutOverlay) Update() {
+	if !a.visible || len(a.pages) == 0 {
+		return
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyRight) || inpututil.IsKeyJustPressed(ebiten.KeyDown) {
+		last := len(a.pages) - 1
+		if a.hidden != nil {
+			last++
+		}
+		if a.page == len(a.pages)-1 && last > a.page && a.OnHidden != nil {
+			a.OnHidden()
+		}
+		a.page = min(a.page+1, last)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyLeft) || inpututil.IsKeyJustPressed(ebiten.KeyUp) {
+		a.page = max(a.page-1, 0)
+	}
+}
+
+// Draw renders the current page over the demo
+func (a *AboutOverlay) Draw(screen *ebiten.Image) {
+	if !a.visible || len(a.pages) == 0 {
+		return
+	}
+	if a.canvas == nil {
+		a.canvas = ebiten.NewImage(screenWidth, screenHeight)
+	}
+	if a.drawn != a.page {
+		a.drawPage()
+		a.drawn = a.page
+	}
+
+	vector.DrawFilledRect(screen, 0, 0, screenWidth, screenHeight, color.RGBA{0, 0, 0, 0xd0}, false)
+	screen.DrawImage(a.canvas, nil)
+}
+
+// drawPage draws the lines of the page into the canvas
+func (a *AboutOverlay) drawPage() {
+	a.canvas.Clear()
+	lines := a.hidden
+	if a.page < len(a.pages) {
+		lines = a.pages[a.page]
+		footer := fmt.Sprintf("%d OF %d", a.page+1, len(a.pages))
+		a.font.DrawString(a.canvas, footer, screenWidth-aboutMargin-a.font.Measure(footer, aboutTextScale),
+			screenHeight-aboutMargin, aboutTextScale, nil)
+	}
+	y := float64(aboutMargin)
+	for _, line := range lines {
+		a.font.DrawString(a.canvas, line.text, aboutMargin, y, line.scale, nil)
+		y += a.font.LineHeight(line.scale) + aboutLineGap
+	}
+}
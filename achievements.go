@@ -0,0 +1,159 @@
+package main
+
+import (
+	"image/color"
+	"log"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// Achievement is a badge earned while watching the demo
+type Achievement struct {
+	ID    string
+	Title string
+}
+
+// achievementList holds every achievement, titles in the letters the
+// font can draw
+var achievementList = []Achievement{
+	{"read", "READ THE WHOLE TEXT"},
+	{"hidden", "FOUND THE HIDDEN PAGE"},
+	{"loops", "WATCHED 3 MUSIC LOOPS"},
+	{"forms", "SAW EVERY WAVEFORM"},
+}
+
+// achievementLoops is how many music loops earn the loops achievement
+const achievementLoops = 3
+
+// Toast layout: slides up from the bottom, stays, then slides back
+const (
+	toastFrames  = 240
+	toastSlide   = 20
+	toastScale   = 0.5
+	toastHeight  = 56
+	toastHeading = "ACHIEVEMENT UNLOCKED"
+)
+
+// Achievements tracks the badges earned, remembered in the settings
+// file, and shows a toast for every new one
+type Achievements struct {
+	settings *Settings
+	path     string // settings file, empty to forget at exit
+
+	queue  []string // titles waiting for their toast
+	toast  string
+	frames int
+	canvas *ebiten.Image
+	drawn  string // toast drawn into canvas
+
+	forms uint // bit mask of the scroller forms seen
+
+	// OnUnlock is called when an achievement is earned
+	OnUnlock func(Achievement)
+}
+
+// NewAchievements loads the achievements earned before from the
+// settings file at path. A file that can't be read is never saved over.
+func NewAchievements(path string) *Achievements {
+	a := &Achievements{path: path, settings: &Settings{Achievements: make(map[string]time.Time)}}
+	if path == "" {
+		return a
+	}
+	s, err := LoadSettings(path)
+	if err != nil {
+		// Saving would overwrite the settings that could not be read
+		log.Printf("Failed to load achievements, leaving %s as it is: %v", path, err)
+		a.path = ""
+	}
+	a.settings = s
+	return a
+}
+
+// Unlocked reports whether the achievement id was earned
+func (a *Achievements) Unlocked(id string) bool {
+	_, ok := a.settings.Achievements[id]
+	return ok
+}
+
+// Unlock earns the achievement id, saving it and queueing its toast
+func (a *Achievements) Unlock(id string) {
+	if a.Unlocked(id) {
+		return
+	}
+	for _, ach := range achievementList {
+		if ach.ID != id {
+			continue
+		}
+		a.settings.Achievements[id] = time.Now()
+		a.queue = append(a.queue, ach.Title)
+		if a.OnUnlock != nil {
+			a.OnUnlock(ach)
+		}
+		if a.path != "" {
+			if err := a.settings.Save(a.path); err != nil {
+				log.Printf("Failed to save achievements: %v", err)
+			}
+		}
+		return
+	}
+}
+
+// Update plays the toasts one after the other
+func (a *Achievements) Update() {
+	if a.frames > 0 {
+		a.frames--
+		return
+	}
+	if len(a.queue) > 0 {
+		a.toast = a.queue[0]
+		a.queue = a.queue[1:]
+		a.frames = toastFrames
+	}
+}
+
+// Draw renders the toast at the bottom of the screen in the demo font
+func (a *Achievements) Draw(screen *ebiten.Image, font *BigFont) {
+	if a.frames == 0 {
+		return
+	}
+	if a.canvas == nil {
+		a.canvas = ebiten.NewImage(screenWidth, toastHeight)
+	}
+	if a.drawn != a.toast {
+		a.canvas.Clear()
+		for i, text := range []string{toastHeading, a.toast} {
+			font.DrawString(a.canvas, text, (screenWidth-font.Measure(text, toastScale))/2, 4+float64(i)*(font.LineHeight(toastScale)+4), toastScale, nil)
+		}
+		a.drawn = a.toast
+	}
+
+	// Slide in and out
+	shown := min(a.frames, toastFrames-a.frames, toastSlide)
+	y := float64(screenHeight) - float64(toastHeight)*float64(shown)/toastSlide
+	vector.DrawFilledRect(screen, 0, float32(y), screenWidth, toastHeight, color.RGBA{0, 0, 0, 0xc0}, false)
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(0, y)
+	screen.DrawImage(a.canvas, op)
+}
+
+// updateAchievements checks the achievements earned by watching
+func (g *Game) updateAchievements() {
+	a := g.achievements
+	a.Update()
+
+	s := g.scroller
+	if s.Feed == nil && s.lettersLeft() <= s.onScreen() {
+		a.Unlock("read")
+	}
+	if g.music != nil && g.music.Loops() >= achievementLoops {
+		a.Unlock("loops")
+	}
+	if s.lockedForm < 0 {
+		a.forms |= 1 << s.form
+		if a.forms == 1<<len(s.Forms)-1 {
+			a.Unlock("forms")
+		}
+	}
+}
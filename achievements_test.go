@@ -0,0 +1,28 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAchievementsKeepUnreadableSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	broken := []byte(`{"achievements": {"read": "yesterday"}, "volume": 3`)
+	if err := os.WriteFile(path, broken, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	a := NewAchievements(path)
+	a.Unlock("hidden")
+	if !a.Unlocked("hidden") {
+		t.Error("the achievement was not earned")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != string(broken) {
+		t.Errorf("settings overwritten with %s", data)
+	}
+}
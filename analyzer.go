@@ -0,0 +1,71 @@
+package main
+
+import "math"
+
+// Bands of the envelope analysis, standing in for the voices of chip
+// music
+const analyzerBands = 3
+
+// envelopeAnalyzer follows the loudness of the bass, middle and treble
+// of sampled audio, so music without register data can drive the music
+// synced effects
+type envelopeAnalyzer struct {
+	lowCoef, midCoef float64 // one-pole low-pass filters
+	low, mid         float64
+
+	attack, release, peakDecay float64
+
+	band [analyzerBands]float64 // last band signals
+	env  [analyzerBands]float64
+	peak [analyzerBands]float64
+
+	beat *beatTracker
+}
+
+// onePole returns the coefficient of a one-pole filter at cutoff hz
+func onePole(hz, sampleRate float64) float64 {
+	return 1 - math.Exp(-2*math.Pi*hz/sampleRate)
+}
+
+// timeCoef returns the coefficient of a smoothing over seconds
+func timeCoef(seconds, sampleRate float64) float64 {
+	return 1 - math.Exp(-1/(seconds*sampleRate))
+}
+
+func newEnvelopeAnalyzer(sampleRate int) *envelopeAnalyzer {
+	rate := float64(sampleRate)
+	return &envelopeAnalyzer{
+		lowCoef:   onePole(150, rate),
+		midCoef:   onePole(2000, rate),
+		attack:    timeCoef(0.005, rate),
+		release:   timeCoef(0.15, rate),
+		peakDecay: math.Exp(-1 / (3 * rate)),
+		beat:      newBeatTracker(sampleRate),
+	}
+}
+
+// process analyzes one sample in [-1,1]
+func (a *envelopeAnalyzer) process(x float64) {
+	a.low += a.lowCoef * (x - a.low)
+	a.mid += a.midCoef * (x - a.mid)
+	a.band = [analyzerBands]float64{a.low, a.mid - a.low, x - a.mid}
+
+	for b, v := range a.band {
+		v = math.Abs(v)
+		if v > a.env[b] {
+			a.env[b] += a.attack * (v - a.env[b])
+		} else {
+			a.env[b] += a.release * (v - a.env[b])
+		}
+		a.peak[b] = max(a.env[b], a.peak[b]*a.peakDecay)
+	}
+	a.beat.process(&a.env)
+}
+
+// levels returns the loudness of every band in [0,1], relative to its
+// recent peak so quiet and loud music both use the whole range
+func (a *envelopeAnalyzer) levels(dst []float64) {
+	for b := range a.env {
+		dst[b] = min(1, a.env[b]/max(a.peak[b], 0.05))
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// AudioDevice is an audio output the demo can play to
+type AudioDevice struct {
+	Name        string // value of -audio-device
+	Description string
+	sound       string // sound system routing to it
+}
+
+// printAudioDevices lists the outputs for -audio-device list
+func printAudioDevices() {
+	devices := listAudioDevices()
+	if len(devices) == 0 {
+		fmt.Println("No selectable audio device, the default one is used")
+		return
+	}
+	for _, d := range devices {
+		fmt.Printf("%-48s %s (%s)\n", d.Name, d.Description, d.sound)
+	}
+}
+
+// findAudioDevice returns the listed device called name
+func findAudioDevice(name string) (AudioDevice, error) {
+	for _, d := range listAudioDevices() {
+		if d.Name == name {
+			return d, nil
+		}
+	}
+	return AudioDevice{}, fmt.Errorf("unknown audio device %q, see -audio-device list", name)
+}
+
+// setenvIfUnset keeps what the user set in the environment
+func setenvIfUnset(key, value string) {
+	if os.Getenv(key) == "" {
+		os.Setenv(key, value)
+	}
+}
@@ -0,0 +1,57 @@
+//go:build linux
+
+package main
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// alsaCardLine matches " 0 [PCH            ]: HDA-Intel - HDA Intel PCH"
+var alsaCardLine = regexp.MustCompile(`^\s*\d+\s+\[(\S+)\s*\]:\s*(.*)$`)
+
+// listAudioDevices returns the PulseAudio or PipeWire sinks and the ALSA
+// cards
+func listAudioDevices() []AudioDevice {
+	var devices []AudioDevice
+	if out, err := exec.Command("pactl", "list", "short", "sinks").Output(); err == nil {
+		for _, line := range strings.Split(string(out), "\n") {
+			fields := strings.Split(line, "\t")
+			if len(fields) >= 2 {
+				devices = append(devices, AudioDevice{Name: fields[1], Description: "sink " + fields[0], sound: "pulse"})
+			}
+		}
+	}
+
+	if f, err := os.Open("/proc/asound/cards"); err == nil {
+		defer f.Close()
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			if m := alsaCardLine.FindStringSubmatch(sc.Text()); m != nil {
+				devices = append(devices, AudioDevice{Name: m[1], Description: m[2], sound: "alsa"})
+			}
+		}
+	}
+	return devices
+}
+
+// selectAudioDevice routes the default output to the device. Ebiten
+// always opens the ALSA default device, which the sound server and ALSA
+// redirect according to these variables.
+func selectAudioDevice(name string) error {
+	d, err := findAudioDevice(name)
+	if err != nil {
+		return err
+	}
+	switch d.sound {
+	case "pulse":
+		setenvIfUnset("PULSE_SINK", d.Name)
+		setenvIfUnset("PIPEWIRE_NODE", d.Name)
+	case "alsa":
+		setenvIfUnset("ALSA_CARD", d.Name)
+	}
+	return nil
+}
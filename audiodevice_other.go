@@ -0,0 +1,15 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// listAudioDevices returns nothing, Ebiten plays to the system default
+// device on this platform
+func listAudioDevices() []AudioDevice {
+	return nil
+}
+
+func selectAudioDevice(name string) error {
+	return errors.New("audio device selection is not supported on this platform, change the default device instead")
+}
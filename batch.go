@@ -0,0 +1,120 @@
+package main
+
+import (
+	"image"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// letterAtlasColumns is the width of the font atlas, in tiles
+const letterAtlasColumns = 16
+
+// letterKey is a tile of the atlas: a rune of one of the fonts, and
+// the frame of an animated glyph
+type letterKey struct {
+	font  int
+	r     rune
+	frame int
+}
+
+// letterBatch collects letters drawn from an atlas of the font tiles,
+// so a pass of the scroller costs one DrawTriangles call
+type letterBatch struct {
+	atlas    *ebiten.Image
+	cells    map[letterKey]image.Rectangle
+	vertices []ebiten.Vertex
+	indices  []uint16
+}
+
+// newLetterBatch copies the tiles of every font into a fresh atlas
+func newLetterBatch(fonts []map[rune]*ebiten.Image) *letterBatch {
+	var keys []letterKey
+	var frames []*ebiten.Image
+	for font, tiles := range fonts {
+		runes := make([]rune, 0, len(tiles))
+		for r := range tiles {
+			runes = append(runes, r)
+		}
+		slices.Sort(runes)
+		for _, r := range runes {
+			for i, tile := range animFrames(tiles[r]) {
+				keys = append(keys, letterKey{font, r, i})
+				frames = append(frames, tile)
+			}
+		}
+	}
+
+	rows := (len(keys) + letterAtlasColumns - 1) / letterAtlasColumns
+	b := &letterBatch{
+		atlas: ebiten.NewImage(letterAtlasColumns*fontTileWidth, max(rows, 1)*fontTileHeight),
+		cells: make(map[letterKey]image.Rectangle, len(keys)),
+	}
+	for i, k := range keys {
+		tile := frames[i]
+		at := image.Pt(i%letterAtlasColumns*fontTileWidth, i/letterAtlasColumns*fontTileHeight)
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Translate(float64(at.X), float64(at.Y))
+		b.atlas.DrawImage(tile, op)
+		b.cells[k] = image.Rectangle{Min: at, Max: at.Add(tile.Bounds().Size())}
+	}
+	return b
+}
+
+// add queues the tile of k transformed by geoM and scaled by c
+func (b *letterBatch) add(k letterKey, geoM ebiten.GeoM, c ebiten.ColorScale) {
+	cell, ok := b.cells[k]
+	if !ok {
+		return
+	}
+	var q quad
+	w, h := float64(cell.Dx()), float64(cell.Dy())
+	for i, corner := range [4][2]float64{{0, 0}, {w, 0}, {0, h}, {w, h}} {
+		q[i][0], q[i][1] = geoM.Apply(corner[0], corner[1])
+	}
+	b.addQuad(k, q, c)
+}
+
+// addQuad queues the tile of k stretched onto q and scaled by c
+func (b *letterBatch) addQuad(k letterKey, q quad, c ebiten.ColorScale) {
+	cell, ok := b.cells[k]
+	if !ok {
+		return
+	}
+	base := uint16(len(b.vertices))
+	w, h := float64(cell.Dx()), float64(cell.Dy())
+	for i, corner := range [4][2]float64{{0, 0}, {w, 0}, {0, h}, {w, h}} {
+		b.vertices = append(b.vertices, ebiten.Vertex{
+			DstX:   float32(q[i][0]),
+			DstY:   float32(q[i][1]),
+			SrcX:   float32(cell.Min.X) + float32(corner[0]),
+			SrcY:   float32(cell.Min.Y) + float32(corner[1]),
+			ColorR: c.R(),
+			ColorG: c.G(),
+			ColorB: c.B(),
+			ColorA: c.A(),
+		})
+	}
+	b.indices = append(b.indices, base, base+1, base+2, base+1, base+3, base+2)
+}
+
+// flush draws the queued letters onto dst
+func (b *letterBatch) flush(dst *ebiten.Image) {
+	if len(b.indices) == 0 {
+		return
+	}
+	op := &ebiten.DrawTrianglesOptions{
+		ColorScaleMode: ebiten.ColorScaleModePremultipliedAlpha,
+		Filter:         ebiten.FilterNearest,
+	}
+	dst.DrawTriangles(b.vertices, b.indices, b.atlas, op)
+	b.vertices = b.vertices[:0]
+	b.indices = b.indices[:0]
+}
+
+// flushLetters draws the letters batched since the last flush
+func (s *Scroller) flushLetters() {
+	if s.batch != nil {
+		s.batch.flush(s.canvas)
+	}
+}
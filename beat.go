@@ -0,0 +1,121 @@
+package main
+
+import "math"
+
+// Beat tracking works on the onset strength sampled every 10 ms
+const (
+	beatHopsPerSecond = 100
+	beatWindow        = 6 * beatHopsPerSecond // hops searched for the tempo
+	beatMinBPM        = 60
+	beatMaxBPM        = 180
+)
+
+// beatTracker finds the tempo of sampled music from the rises of its band
+// envelopes and counts the beats, giving the music synced effects a beat
+// grid when there is no register data or pattern to follow
+type beatTracker struct {
+	hop     int // samples per hop
+	samples int
+	prevEnv [analyzerBands]float64
+
+	onsets []float64 // ring of onset strengths
+	pos    int
+	hops   int
+
+	bpm   float64 // estimated tempo
+	fixed float64 // tempo set by the user, 0 to estimate
+	phase float64 // hops since the last beat
+	beats int64
+}
+
+func newBeatTracker(sampleRate int) *beatTracker {
+	return &beatTracker{
+		hop:    max(1, sampleRate/beatHopsPerSecond),
+		onsets: make([]float64, beatWindow),
+		bpm:    120,
+	}
+}
+
+// process takes the band envelopes after every sample
+func (t *beatTracker) process(env *[analyzerBands]float64) {
+	t.samples++
+	if t.samples < t.hop {
+		return
+	}
+	t.samples = 0
+
+	onset := 0.0
+	for b, e := range env {
+		onset += max(0, e-t.prevEnv[b])
+		t.prevEnv[b] = e
+	}
+	t.onsets[t.pos] = onset
+	t.pos = (t.pos + 1) % len(t.onsets)
+	t.hops++
+	if t.hops%beatHopsPerSecond == 0 && t.hops >= beatWindow {
+		t.estimate()
+	}
+
+	period := t.period()
+	t.phase++
+	if t.phase >= period {
+		t.phase -= period
+		t.beats++
+	}
+
+	// Pull the grid toward strong onsets close to a beat
+	if onset > 2*t.meanOnset() {
+		switch {
+		case t.phase < period/8:
+			t.phase *= 0.5
+		case t.phase > period*7/8:
+			t.phase += (period - t.phase) * 0.5
+		}
+	}
+}
+
+// period returns the hops between two beats
+func (t *beatTracker) period() float64 {
+	bpm := t.bpm
+	if t.fixed > 0 {
+		bpm = t.fixed
+	}
+	return 60 * beatHopsPerSecond / bpm
+}
+
+func (t *beatTracker) meanOnset() float64 {
+	sum := 0.0
+	for _, v := range t.onsets {
+		sum += v
+	}
+	return sum / float64(len(t.onsets))
+}
+
+// estimate picks the tempo whose period best repeats the onsets,
+// favoring tempos around 120 BPM over their halves and doubles
+func (t *beatTracker) estimate() {
+	n := len(t.onsets)
+	bestLag, best := 0, 0.0
+	for lag := 60 * beatHopsPerSecond / beatMaxBPM; lag <= 60*beatHopsPerSecond/beatMinBPM; lag++ {
+		sum := 0.0
+		for i := lag; i < n; i++ {
+			sum += t.onsets[(t.pos+i)%n] * t.onsets[(t.pos+i-lag)%n]
+		}
+		bpm := 60 * beatHopsPerSecond / float64(lag)
+		weight := math.Exp(-0.5 * math.Pow(math.Log2(bpm/120), 2))
+		if score := sum / float64(n-lag) * weight; score > best {
+			bestLag, best = lag, score
+		}
+	}
+	if bestLag > 0 {
+		t.bpm += (60*beatHopsPerSecond/float64(bestLag) - t.bpm) * 0.5
+	}
+}
+
+// BPM returns the tempo followed
+func (t *beatTracker) BPM() float64 {
+	if t.fixed > 0 {
+		return t.fixed
+	}
+	return t.bpm
+}
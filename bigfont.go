@@ -0,0 +1,158 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Spacing of the big font, in pixels of the 32x33 tiles
+const (
+	bigFontHeight = 33
+	bigFontGap    = 3  // between two glyphs
+	bigFontSpace  = 14 // width of a space
+)
+
+// bigFontFallback stands in for characters the font lacks
+var bigFontFallback = map[rune]rune{
+	'\'': ',', '`': ',', '"': ',',
+	'[': '(', '{': '(', '<': '(',
+	']': ')', '}': ')', '>': ')',
+	'-': '.', '_': '.',
+}
+
+// BigFont draws any string in the scroller font at any size: letters
+// are spaced by their own width and colored by the rasters, as the
+// scroller draws them
+type BigFont struct {
+	tiles   map[rune]*ebiten.Image
+	spans   map[rune][2]int // drawn columns of every glyph, end excluded
+	rasters *ebiten.Image
+	scratch *ebiten.Image
+}
+
+// NewBigFont creates the font from its tiles, the columns each glyph
+// covers and the rasters filling them. Glyphs without a span take the
+// whole tile.
+func NewBigFont(tiles map[rune]*ebiten.Image, spans map[rune][2]int, rasters *ebiten.Image) *BigFont {
+	return &BigFont{tiles: tiles, spans: spans, rasters: rasters}
+}
+
+// glyphSpan finds the columns of r holding opaque pixels in img
+func glyphSpan(img image.Image, r image.Rectangle) [2]int {
+	span := [2]int{r.Dx(), 0}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a != 0 {
+				span[0] = min(span[0], x-r.Min.X)
+				span[1] = max(span[1], x-r.Min.X+1)
+			}
+		}
+	}
+	if span[1] <= span[0] {
+		return [2]int{0, 0}
+	}
+	return span
+}
+
+// Printable replaces the characters the font lacks by their uppercase,
+// a look-alike, or a space
+func (f *BigFont) Printable(text string) string {
+	return strings.Map(func(r rune) rune {
+		for _, c := range []rune{r, unicode.ToUpper(r)} {
+			if _, ok := f.tiles[c]; ok {
+				return c
+			}
+			if s, ok := bigFontFallback[c]; ok {
+				if _, ok := f.tiles[s]; ok {
+					return s
+				}
+			}
+		}
+		return ' '
+	}, text)
+}
+
+// advance returns the columns of glyph r and the room it takes
+func (f *BigFont) advance(r rune) (span [2]int, width int) {
+	if r == ' ' {
+		return [2]int{0, 0}, bigFontSpace
+	}
+	span, ok := f.spans[r]
+	if !ok {
+		span = [2]int{0, 32}
+	}
+	return span, span[1] - span[0] + bigFontGap
+}
+
+// Measure returns the width of text drawn at scale
+func (f *BigFont) Measure(text string, scale float64) float64 {
+	text = f.Printable(text)
+	w := 0
+	for _, r := range text {
+		_, adv := f.advance(r)
+		w += adv
+	}
+	// No gap after the last glyph
+	if w > 0 && !strings.HasSuffix(text, " ") {
+		w -= bigFontGap
+	}
+	return float64(w) * scale
+}
+
+// LineHeight returns the height of a line drawn at scale
+func (f *BigFont) LineHeight(scale float64) float64 {
+	return bigFontHeight * scale
+}
+
+// DrawString draws text with its top left corner at (x, y), colored by
+// the rasters and multiplied by tint, nil for none
+func (f *BigFont) DrawString(dst *ebiten.Image, text string, x, y, scale float64, tint color.Color) {
+	text = f.Printable(text)
+	w := int(math.Ceil(f.Measure(text, scale)))
+	h := int(math.Ceil(f.LineHeight(scale)))
+	if w <= 0 || h <= 0 {
+		return
+	}
+	if f.scratch == nil || f.scratch.Bounds().Dx() < w || f.scratch.Bounds().Dy() < h {
+		f.scratch = ebiten.NewImage(max(w, screenWidth), max(h, 2*bigFontHeight))
+	}
+	line := f.scratch.SubImage(image.Rect(0, 0, w, h)).(*ebiten.Image)
+	line.Clear()
+
+	pen := 0
+	for _, r := range text {
+		span, adv := f.advance(r)
+		if tile, ok := f.tiles[r]; ok && r != ' ' {
+			op := &ebiten.DrawImageOptions{}
+			op.GeoM.Translate(float64(pen-span[0]), 0)
+			op.GeoM.Scale(scale, scale)
+			op.Filter = ebiten.FilterLinear
+			line.DrawImage(tile, op)
+		}
+		pen += adv
+	}
+	fillRasters(line, f.rasters)
+
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(x, y)
+	if tint != nil {
+		op.ColorScale.ScaleWithColor(tint)
+	}
+	dst.DrawImage(line, op)
+}
+
+// fillRasters colors what was drawn into dst with the rasters stretched
+// over it. Source-atop keeps them inside the letters, as on the scroller.
+func fillRasters(dst, rasters *ebiten.Image) {
+	b := dst.Bounds()
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(float64(b.Dx())/float64(rasters.Bounds().Dx()), float64(b.Dy())/float64(rasters.Bounds().Dy()))
+	op.GeoM.Translate(float64(b.Min.X), float64(b.Min.Y))
+	op.CompositeMode = ebiten.CompositeModeSourceAtop
+	dst.DrawImage(rasters, op)
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Blur shader sources
+var (
+	//go:embed shaders/blur.kage
+	blurShaderSrc []byte
+	//go:embed shaders/brightpass.kage
+	brightPassShaderSrc []byte
+)
+
+// BlurQuality selects how many downsample levels and taps a blur uses
+type BlurQuality int
+
+const (
+	BlurLow BlurQuality = iota
+	BlurMedium
+	BlurHigh
+)
+
+var blurQualityNames = []string{"low", "medium", "high"}
+
+func (q BlurQuality) String() string {
+	if q < BlurLow || q > BlurHigh {
+		return "unknown"
+	}
+	return blurQualityNames[q]
+}
+
+// Set implements flag.Value
+func (q *BlurQuality) Set(s string) error {
+	v, err := ParseBlurQuality(s)
+	if err != nil {
+		return err
+	}
+	*q = v
+	return nil
+}
+
+// ParseBlurQuality converts a preset name to a BlurQuality
+func ParseBlurQuality(s string) (BlurQuality, error) {
+	for i, name := range blurQualityNames {
+		if strings.EqualFold(s, name) {
+			return BlurQuality(i), nil
+		}
+	}
+	return BlurMedium, fmt.Errorf("unknown blur quality %q", s)
+}
+
+// preset returns the number of downsample levels and taps per side
+func (q BlurQuality) preset() (levels, taps int) {
+	switch q {
+	case BlurLow:
+		return 1, 3
+	case BlurHigh:
+		return 3, 8
+	default:
+		return 2, 5
+	}
+}
+
+// gaussianWeights returns normalized weights for taps samples per side,
+// padded to the 8 uniforms of the blur shader
+func gaussianWeights(taps int) []float32 {
+	sigma := float64(taps) / 2
+	weights := make([]float32, 8)
+	sum := 0.0
+	for i := 0; i < taps; i++ {
+		w := math.Exp(-float64(i*i) / (2 * sigma * sigma))
+		weights[i] = float32(w)
+		if i == 0 {
+			sum += w
+		} else {
+			sum += 2 * w
+		}
+	}
+	for i := range weights {
+		weights[i] /= float32(sum)
+	}
+	return weights
+}
+
+// Blurrer owns the blur shaders and the quality preset shared by every
+// effect that blurs (glow, bloom, ...)
+type Blurrer struct {
+	blurShader   *ebiten.Shader
+	brightShader *ebiten.Shader
+	Quality      BlurQuality
+}
+
+// NewBlurrer compiles the blur shaders
+func NewBlurrer(quality BlurQuality) (*Blurrer, error) {
+	blurShader, err := ebiten.NewShader(blurShaderSrc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile blur shader: %w", err)
+	}
+	brightShader, err := ebiten.NewShader(brightPassShaderSrc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile bright pass shader: %w", err)
+	}
+	return &Blurrer{
+		blurShader:   blurShader,
+		brightShader: brightShader,
+		Quality:      quality,
+	}, nil
+}
+
+type blurLevel struct {
+	img   *ebiten.Image
+	tmp   *ebiten.Image
+	scale float64
+}
+
+// BlurTarget holds the downsample pyramid for one source size
+type BlurTarget struct {
+	blurrer *Blurrer
+	bright  *ebiten.Image
+	levels  []blurLevel
+}
+
+// NewTarget allocates a pyramid for sources of size w x h
+func (b *Blurrer) NewTarget(w, h int) *BlurTarget {
+	maxLevels, _ := BlurHigh.preset()
+	t := &BlurTarget{
+		blurrer: b,
+		bright:  ebiten.NewImage(w, h),
+	}
+	for i := 1; i <= maxLevels; i++ {
+		lw := max(1, w>>i)
+		lh := max(1, h>>i)
+		t.levels = append(t.levels, blurLevel{
+			img:   ebiten.NewImage(lw, lh),
+			tmp:   ebiten.NewImage(lw, lh),
+			scale: float64(int(1) << i),
+		})
+	}
+	return t
+}
+
+// pass runs one direction of the separable blur from src into dst
+func (t *BlurTarget) pass(dst, src *ebiten.Image, dx, dy float64, weights []float32) {
+	b := src.Bounds()
+	op := &ebiten.DrawRectShaderOptions{}
+	op.Images[0] = src
+	op.Uniforms = map[string]any{
+		"Direction": []float32{float32(dx), float32(dy)},
+		"Weights":   weights,
+	}
+	op.Blend = ebiten.BlendCopy
+	dst.DrawRectShader(b.Dx(), b.Dy(), t.blurrer.blurShader, op)
+}
+
+// pyramid downsamples src level by level, blurring each level, and
+// calls visit with every blurred level
+func (t *BlurTarget) pyramid(src *ebiten.Image, radius float64, visit func(level blurLevel)) {
+	levels, taps := t.blurrer.Quality.preset()
+	weights := gaussianWeights(taps)
+
+	prev := src
+	for i := 0; i < levels; i++ {
+		l := t.levels[i]
+
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Scale(
+			float64(l.img.Bounds().Dx())/float64(prev.Bounds().Dx()),
+			float64(l.img.Bounds().Dy())/float64(prev.Bounds().Dy()),
+		)
+		op.Filter = ebiten.FilterLinear
+		op.Blend = ebiten.BlendCopy
+		l.img.DrawImage(prev, op)
+
+		// Spread the taps so the whole pyramid covers about radius
+		// source pixels
+		step := max(1, radius/(l.scale*float64(taps)*float64(levels)))
+		t.pass(l.tmp, l.img, step, 0, weights)
+		t.pass(l.img, l.tmp, 0, step, weights)
+
+		if visit != nil {
+			visit(l)
+		}
+		prev = l.img
+	}
+}
+
+// Blur blurs src over about radius pixels and returns the result at
+// reduced resolution, together with the factor to scale it up by
+func (t *BlurTarget) Blur(src *ebiten.Image, radius float64) (*ebiten.Image, float64) {
+	var last blurLevel
+	t.pyramid(src, radius, func(l blurLevel) { last = l })
+	return last.img, last.scale
+}
+
+// Bloom adds the blurred bright parts of src onto dst
+func (t *BlurTarget) Bloom(dst, src *ebiten.Image, threshold, strength, radius float64) {
+	b := src.Bounds()
+	op := &ebiten.DrawRectShaderOptions{}
+	op.Images[0] = src
+	op.Uniforms = map[string]any{
+		"Threshold": float32(threshold),
+	}
+	op.Blend = ebiten.BlendCopy
+	t.bright.DrawRectShader(b.Dx(), b.Dy(), t.blurrer.brightShader, op)
+
+	// Every level contributes, the wide ones giving the soft halo
+	levels, _ := t.blurrer.Quality.preset()
+	s := float32(strength / float64(levels))
+	t.pyramid(t.bright, radius, func(l blurLevel) {
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Scale(l.scale, l.scale)
+		op.Filter = ebiten.FilterLinear
+		op.ColorScale.Scale(s, s, s, s)
+		op.Blend = ebiten.BlendLighter
+		dst.DrawImage(l.img, op)
+	})
+}
+
+// BloomEffect makes the bright parts of the final frame bleed light
+type BloomEffect struct {
+	target    *BlurTarget
+	source    *ebiten.Image
+	Threshold float64
+	Strength  float64
+	Radius    float64
+}
+
+// NewBloomEffect creates a bloom pass for canvases of size w x h
+func NewBloomEffect(blurrer *Blurrer, w, h int) *BloomEffect {
+	return &BloomEffect{
+		target:    blurrer.NewTarget(w, h),
+		source:    ebiten.NewImage(w, h),
+		Threshold: 0.6,
+		Strength:  0.8,
+		Radius:    24,
+	}
+}
+
+// Update has nothing to animate
+func (e *BloomEffect) Update() {}
+
+// Apply adds the bloom onto the canvas
+func (e *BloomEffect) Apply(canvas *ebiten.Image) {
+	// The canvas is both read and written, work from a copy
+	op := &ebiten.DrawImageOptions{}
+	op.Blend = ebiten.BlendCopy
+	e.source.DrawImage(canvas, op)
+	e.target.Bloom(canvas, e.source, e.Threshold, e.Strength, e.Radius)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Display calibration shader source
+//
+//go:embed shaders/calibrate.kage
+var calibrateShaderSrc []byte
+
+// Calibration adjusts the final frame for the display: the dark
+// 16-color assets can look murky on modern panels
+type Calibration struct {
+	shader *ebiten.Shader
+
+	Gamma      float64 // 1 for none
+	Brightness float64 // 0 for none
+	Contrast   float64 // 1 for none
+	Saturation float64 // 1 for none
+}
+
+// NewCalibration creates the pass with the settings of cfg
+func NewCalibration(cfg *Config) (*Calibration, error) {
+	shader, err := ebiten.NewShader(calibrateShaderSrc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile calibration shader: %w", err)
+	}
+	return &Calibration{
+		shader:     shader,
+		Gamma:      cfg.Gamma,
+		Brightness: cfg.Brightness,
+		Contrast:   cfg.Contrast,
+		Saturation: cfg.Saturation,
+	}, nil
+}
+
+// identity reports whether the pass leaves the frame as it is
+func (c *Calibration) identity() bool {
+	return c.Gamma == 1 && c.Brightness == 0 && c.Contrast == 1 && c.Saturation == 1
+}
+
+// Draw draws src onto dst with op, calibrated. A nil calibration draws
+// src as it is.
+func (c *Calibration) Draw(dst, src *ebiten.Image, op *ebiten.DrawImageOptions) {
+	if c == nil || c.identity() {
+		dst.DrawImage(src, op)
+		return
+	}
+
+	b := src.Bounds()
+	sop := &ebiten.DrawRectShaderOptions{}
+	sop.GeoM = op.GeoM
+	sop.ColorScale = op.ColorScale
+	sop.Images[0] = src
+	sop.Uniforms = map[string]any{
+		"Gamma":      float32(max(c.Gamma, 0.1)),
+		"Brightness": float32(c.Brightness),
+		"Contrast":   float32(c.Contrast),
+		"Saturation": float32(c.Saturation),
+	}
+	dst.DrawRectShader(b.Dx(), b.Dy(), c.shader, sop)
+}
+
+// calibrationStep changes a calibration setting by delta steps, within
+// [lo, hi]. Rounding to the step brings it back to exactly neutral.
+func calibrationStep(v *float64, delta int, step, lo, hi float64) {
+	*v = min(max(math.Round(*v/step+float64(delta))*step, lo), hi)
+}
+
+// addCalibrationOptions adds the calibration settings to the options
+// menu
+func (g *Game) addCalibrationOptions() {
+	c := g.calibration
+	if c == nil {
+		return
+	}
+	for _, o := range []struct {
+		label  string
+		v      *float64
+		format string
+		step   float64
+		lo, hi float64
+	}{
+		{"Gamma", &c.Gamma, "%.1f", 0.1, 0.5, 2.5},
+		{"Brightness", &c.Brightness, "%+.2f", 0.05, -0.5, 0.5},
+		{"Contrast", &c.Contrast, "%.1f", 0.1, 0.5, 2},
+		{"Saturation", &c.Saturation, "%.1f", 0.1, 0, 2},
+	} {
+		g.options.Add(Option{
+			Label:  o.label,
+			Value:  func() string { return fmt.Sprintf(o.format, *o.v) },
+			Change: func(delta int) { calibrationStep(o.v, delta, o.step, o.lo, o.hi) },
+		})
+	}
+}
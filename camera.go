@@ -0,0 +1,87 @@
+package main
+
+import (
+	"math"
+
+	"tcb-multi-plane-3d-scroller/easings"
+)
+
+// Depth factors of the planes for the camera pan, 1 moving with the
+// camera and 0 staying still. The landscape layers use their own scroll
+// speeds and the scroller letters their projection scale.
+const (
+	logoDepth      = 0.35
+	landscapeSpeed = 8.0 // speed of the nearest landscape layer
+)
+
+// cameraReturn is how many updates the camera takes to glide back to the
+// center once disabled, along cameraReturnCurve: quickly at first, then
+// settling slowly
+const cameraReturn = 60
+
+var cameraReturnCurve = easings.OutExpo
+
+// Camera pans the whole scene slowly from side to side, shifting every
+// plane according to its depth
+type Camera struct {
+	X         float64 // current pan in ST pixels
+	Amplitude float64
+	Speed     float64
+	Enabled   bool
+	ticks     Ticks
+	from      float64 // pan the glide back to the center started from
+	back      int     // updates since the camera was disabled
+}
+
+// NewCamera creates a camera swinging amplitude pixels each side
+func NewCamera(amplitude, speed float64, enabled bool) *Camera {
+	return &Camera{
+		Amplitude: amplitude,
+		Speed:     speed,
+		Enabled:   enabled,
+	}
+}
+
+// Update advances the pan, easing back to the center when disabled
+func (c *Camera) Update() {
+	if c.Enabled {
+		c.ticks++
+		c.X = c.Amplitude * math.Sin(c.ticks.Phase(c.Speed))
+		c.back = 0
+		return
+	}
+
+	if c.back == 0 {
+		c.from = c.X
+	}
+	c.back++
+	c.X = easings.Lerp(c.from, 0, cameraReturnCurve(min(1, float64(c.back)/cameraReturn)))
+	if c.back >= cameraReturn {
+		c.X = 0
+		c.ticks = 0
+	}
+}
+
+// Shift returns the offset of a plane at the given depth factor
+func (c *Camera) Shift(depth float64) float64 {
+	return c.X * depth
+}
+
+// landscapeX returns the strip position of a mountain layer, shifted by
+// the camera according to the layer depth
+func (g *Game) landscapeX(i int) float64 {
+	x := float64(int(g.bgPos[i]) * 2)
+	if g.camera.X == 0 {
+		return x
+	}
+
+	// papercanvas2 is not scaled, one ST pixel is two canvas pixels
+	x += g.camera.Shift(g.layers[i].Speed/landscapeSpeed) * 2
+
+	// The strips repeat every 512 pixels
+	x = math.Mod(x, 512)
+	if x > 0 {
+		x -= 512
+	}
+	return x
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// readWAVHeader reads the header of a 16-bit PCM WAV stream up to the
+// start of its samples. The data size is ignored, pipes do not know it.
+func readWAVHeader(r io.Reader) (channels, sampleRate int, err error) {
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return 0, 0, fmt.Errorf("failed to read WAV header: %w", err)
+	}
+	if string(riff[:4]) != "RIFF" || string(riff[8:]) != "WAVE" {
+		return 0, 0, errors.New("not a WAV stream")
+	}
+
+	for {
+		var head [8]byte
+		if _, err := io.ReadFull(r, head[:]); err != nil {
+			return 0, 0, fmt.Errorf("failed to read WAV chunk: %w", err)
+		}
+		size := binary.LittleEndian.Uint32(head[4:])
+		switch string(head[:4]) {
+		case "fmt ":
+			fmtChunk := make([]byte, size+size%2)
+			if size < 16 {
+				return 0, 0, errors.New("invalid WAV format chunk")
+			}
+			if _, err := io.ReadFull(r, fmtChunk); err != nil {
+				return 0, 0, fmt.Errorf("failed to read WAV format: %w", err)
+			}
+			format := binary.LittleEndian.Uint16(fmtChunk)
+			channels = int(binary.LittleEndian.Uint16(fmtChunk[2:]))
+			sampleRate = int(binary.LittleEndian.Uint32(fmtChunk[4:]))
+			bits := binary.LittleEndian.Uint16(fmtChunk[14:])
+			if (format != 1 && format != 0xfffe) || bits != 16 || channels < 1 || sampleRate <= 0 {
+				return 0, 0, fmt.Errorf("unsupported WAV format %d with %d bits, expected 16-bit PCM", format, bits)
+			}
+		case "data":
+			if channels == 0 {
+				return 0, 0, errors.New("WAV data before its format")
+			}
+			return channels, sampleRate, nil
+		default:
+			if _, err := io.CopyN(io.Discard, r, int64(size+size%2)); err != nil {
+				return 0, 0, fmt.Errorf("failed to skip WAV chunk: %w", err)
+			}
+		}
+	}
+}
+
+// CaptureSource analyzes audio played elsewhere, read as a WAV stream
+// from a file or a pipe, and plays nothing itself. The music synced
+// effects follow its bass, middle and treble as three voices.
+type CaptureSource struct {
+	mu       sync.Mutex
+	in       io.ReadCloser
+	channels int
+	rate     int
+	analyzer *envelopeAnalyzer
+	levels   [analyzerBands]float64
+	scope    *scopeRing
+	samples  int64
+}
+
+// NewCaptureSource starts analyzing the WAV stream at path, "-" for
+// standard input
+func NewCaptureSource(path string) (*CaptureSource, error) {
+	in := io.ReadCloser(os.Stdin)
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audio input: %w", err)
+		}
+		in = f
+	}
+
+	rd := bufio.NewReaderSize(in, 64<<10)
+	channels, rate, err := readWAVHeader(rd)
+	if err != nil {
+		in.Close()
+		return nil, err
+	}
+
+	c := &CaptureSource{
+		in:       in,
+		channels: channels,
+		rate:     rate,
+		analyzer: newEnvelopeAnalyzer(rate),
+		scope:    newScopeRing(analyzerBands),
+	}
+	go c.run(rd)
+	return c, nil
+}
+
+// run analyzes the stream in blocks of 10 ms, a frame at least, at the
+// pace of the audio when reading a file faster than real time
+func (c *CaptureSource) run(r io.Reader) {
+	frameSize := 2 * c.channels
+	block := make([]byte, max(1, c.rate/100)*frameSize)
+	start := time.Now()
+	for {
+		n, err := io.ReadFull(r, block)
+		c.analyze(block[:n-n%frameSize])
+		if err != nil {
+			if err != io.EOF && err != io.ErrUnexpectedEOF {
+				log.Printf("Audio input stopped: %v", err)
+			}
+			return
+		}
+
+		c.mu.Lock()
+		played := time.Duration(c.samples) * time.Second / time.Duration(c.rate)
+		c.mu.Unlock()
+		if ahead := played - time.Since(start); ahead > 0 {
+			time.Sleep(ahead)
+		}
+	}
+}
+
+// analyze feeds the analyzer with a block of interleaved frames
+func (c *CaptureSource) analyze(block []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	frameSize := 2 * c.channels
+	for i := 0; i+frameSize <= len(block); i += frameSize {
+		x := 0.0
+		for ch := 0; ch < c.channels; ch++ {
+			x += float64(int16(binary.LittleEndian.Uint16(block[i+2*ch:])))
+		}
+		c.analyzer.process(x / float64(c.channels) / 32768)
+		c.scope.push(c.analyzer.band[:])
+		c.samples++
+	}
+	c.analyzer.levels(c.levels[:])
+}
+
+// Read returns silence, the audio is heard from where it comes from
+func (c *CaptureSource) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+// ChannelLevels returns the loudness of the bass, middle and treble
+func (c *CaptureSource) ChannelLevels() []float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]float64(nil), c.levels[:]...)
+}
+
+// Scope returns the last samples of the three bands
+func (c *CaptureSource) Scope() [][]float32 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.scope.copy()
+}
+
+// Sync returns the band levels, with frames counted at the YM rate
+func (c *CaptureSource) Sync() MusicSync {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return MusicSync{
+		Frame:    c.samples * ymFrameRate / int64(c.rate),
+		Levels:   append([]float64(nil), c.levels[:]...),
+		Envelope: make([]bool, analyzerBands),
+		Beat:     c.analyzer.beat.beats,
+		BPM:      c.analyzer.beat.BPM(),
+	}
+}
+
+// SetBPM sets the tempo of the beat grid, 0 to detect it
+func (c *CaptureSource) SetBPM(bpm float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.analyzer.beat.fixed = bpm
+}
+
+// Elapsed returns how much audio was analyzed
+func (c *CaptureSource) Elapsed() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return time.Duration(c.samples) * time.Second / time.Duration(c.rate)
+}
+
+// Volume returns 0, nothing is played
+func (c *CaptureSource) Volume() float64 { return 0 }
+
+// SetVolume does nothing, the volume belongs to the audio source
+func (c *CaptureSource) SetVolume(float64) {}
+
+// FadeOut does nothing either
+func (c *CaptureSource) FadeOut(time.Duration) {}
+
+func (c *CaptureSource) Loops() int    { return 0 }
+func (c *CaptureSource) Subsongs() int { return 1 }
+
+func (c *CaptureSource) SetSubsong(n int) error {
+	return errors.New("the audio input has no subsongs")
+}
+
+// Close stops reading the input
+func (c *CaptureSource) Close() error {
+	return c.in.Close()
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// ChatBridge relays the messages of an IRC channel (Twitch chat
+// included) into a text feed, dropping messages rejected by the filter
+// and messages beyond the rate limit
+type ChatBridge struct {
+	Server  string // host:port
+	TLS     bool
+	Nick    string
+	Pass    string
+	Channel string
+	Filter  *TextFilter
+	PerMin  int // messages relayed per minute at most
+
+	feed   *TextFeed
+	tokens float64
+	last   time.Time
+}
+
+// NewChatBridge creates a bridge feeding feed. Call Run to connect.
+func NewChatBridge(server, channel string, feed *TextFeed) *ChatBridge {
+	return &ChatBridge{
+		Server:  server,
+		TLS:     true,
+		Nick:    "justinfan1989", // anonymous read-only login on Twitch
+		Channel: "#" + strings.TrimPrefix(channel, "#"),
+		Filter:  &TextFilter{Chars: fontChars},
+		PerMin:  20,
+		feed:    feed,
+	}
+}
+
+// Run keeps the bridge connected, reconnecting with a growing delay
+func (c *ChatBridge) Run() {
+	c.tokens = float64(c.PerMin)
+	c.last = time.Now()
+
+	delay := 5 * time.Second
+	for {
+		start := time.Now()
+		if err := c.session(); err != nil {
+			log.Printf("Chat connection failed: %v", err)
+		}
+		if time.Since(start) > time.Minute {
+			delay = 5 * time.Second
+		}
+		time.Sleep(delay)
+		delay = min(2*delay, time.Minute)
+	}
+}
+
+// session connects, joins the channel and relays messages until the
+// connection drops
+func (c *ChatBridge) session() error {
+	var conn net.Conn
+	var err error
+	if c.TLS {
+		conn, err = tls.Dial("tcp", c.Server, nil)
+	} else {
+		conn, err = net.Dial("tcp", c.Server)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", c.Server, err)
+	}
+	defer conn.Close()
+
+	if c.Pass != "" {
+		fmt.Fprintf(conn, "PASS %s\r\n", c.Pass)
+	}
+	fmt.Fprintf(conn, "NICK %s\r\n", c.Nick)
+	fmt.Fprintf(conn, "JOIN %s\r\n", c.Channel)
+
+	rd := bufio.NewReader(conn)
+	for {
+		line, err := rd.ReadString('\n')
+		if err != nil {
+			if err == io.EOF {
+				return fmt.Errorf("connection closed")
+			}
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if payload, ok := strings.CutPrefix(line, "PING "); ok {
+			fmt.Fprintf(conn, "PONG %s\r\n", payload)
+			continue
+		}
+		if nick, text, ok := parsePrivmsg(line); ok {
+			c.relay(nick, text)
+		}
+	}
+}
+
+// parsePrivmsg extracts the sender and text of a channel message
+func parsePrivmsg(line string) (nick, text string, ok bool) {
+	// Skip IRCv3 tags
+	if strings.HasPrefix(line, "@") {
+		_, line, _ = strings.Cut(line, " ")
+	}
+	if !strings.HasPrefix(line, ":") {
+		return "", "", false
+	}
+	prefix, rest, _ := strings.Cut(line[1:], " ")
+	if !strings.HasPrefix(rest, "PRIVMSG ") {
+		return "", "", false
+	}
+	_, text, found := strings.Cut(rest, " :")
+	if !found {
+		return "", "", false
+	}
+	nick, _, _ = strings.Cut(prefix, "!")
+	return nick, text, true
+}
+
+// relay filters a message and queues it for the scroller
+func (c *ChatBridge) relay(nick, text string) {
+	msg, ok := c.Filter.Apply(nick + ": " + text)
+	if !ok {
+		return
+	}
+
+	// Token bucket refilled at PerMin per minute
+	now := time.Now()
+	c.tokens = min(float64(c.PerMin), c.tokens+now.Sub(c.last).Minutes()*float64(c.PerMin))
+	c.last = now
+	if c.tokens < 1 {
+		return
+	}
+	c.tokens--
+
+	c.feed.Push(msg)
+}
+
+// chatBaseline is the row of the scroller canvas the chat runs on
+const chatBaseline = canvasHeight - 4
+
+// initChat starts the chat bridge and the secondary scroller showing
+// its messages along the bottom of the screen
+func (g *Game) initChat() {
+	feed := NewTextQueue(g.cfg.StdinQueue)
+	bridge := NewChatBridge(g.cfg.ChatServer, g.cfg.ChatChannel, feed)
+	bridge.TLS = g.cfg.ChatTLS
+	if g.cfg.ChatNick != "" {
+		bridge.Nick = g.cfg.ChatNick
+		bridge.Pass = os.Getenv("TCB_CHAT_PASS")
+	}
+	bridge.PerMin = g.cfg.ChatRate
+	bridge.Filter = g.cfg.FilterFor("chat")
+	go bridge.Run()
+
+	g.chatcanvas = ebiten.NewImage(canvasWidth, canvasHeight)
+	g.chat = g.newScroller()
+	g.chat.canvas = g.chatcanvas
+	g.chat.Mode = ScrollPath
+	g.chat.Path = func(float64) float64 { return chatBaseline }
+	g.chat.Overlay = nil
+	g.chat.Text = strings.Repeat(" ", scrollLetters+2)
+	g.chat.Feed = feed
+	g.chat.Restart()
+}
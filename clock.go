@@ -0,0 +1,115 @@
+package main
+
+import (
+	"image/color"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// The original font has no digits, they are built from segments in the
+// size of the font tiles. Segments of each digit: top, top left, top
+// right, middle, bottom left, bottom right, bottom.
+var digitSegments = [10][7]bool{
+	{true, true, true, false, true, true, true},
+	{false, false, true, false, false, true, false},
+	{true, false, true, true, true, false, true},
+	{true, false, true, true, false, true, true},
+	{false, true, true, true, false, true, false},
+	{true, true, false, true, false, true, true},
+	{true, true, false, true, true, true, true},
+	{true, false, true, false, false, true, false},
+	{true, true, true, true, true, true, true},
+	{true, true, true, true, false, true, true},
+}
+
+// Outer columns of the digits
+const digitLeft, digitRight = 6, 26
+
+// addDigitTiles adds 32x33 tiles for '0' to '9' to the font
+func addDigitTiles(tiles map[rune]*ebiten.Image) {
+	const (
+		l, r   = digitLeft, digitRight
+		t, m   = 3, 15 // top and middle rows
+		b      = 27    // bottom row
+		weight = 5
+	)
+	segments := [7][4]float32{
+		{l, t, r - l, weight},
+		{l, t, weight, m - t + weight},
+		{r - weight, t, weight, m - t + weight},
+		{l, m, r - l, weight},
+		{l, m, weight, b - m + weight},
+		{r - weight, m, weight, b - m + weight},
+		{l, b, r - l, weight},
+	}
+
+	for d, on := range digitSegments {
+		tile := ebiten.NewImage(32, 33)
+		for i, seg := range segments {
+			if on[i] {
+				vector.DrawFilledRect(tile, seg[0], seg[1], seg[2], seg[3], color.White, false)
+			}
+		}
+		tiles[rune('0'+d)] = tile
+	}
+}
+
+// timeField is replaced in the scroll text by the current time
+const timeField = "%TIME%"
+
+// timeMarker stands for one character of the time in the laid out text
+const timeMarker = '\x01'
+
+// Clock formats the current time for the corner clock and the scroll
+// text
+type Clock struct {
+	Seconds bool
+}
+
+// Layout returns the time format
+func (c *Clock) Layout() string {
+	if c.Seconds {
+		return "15:04:05"
+	}
+	return "15:04"
+}
+
+// Text returns the current time
+func (c *Clock) Text() string {
+	return time.Now().Format(c.Layout())
+}
+
+// Expand replaces the time fields of text with markers as wide as the
+// time, so the text keeps its length while the time changes
+func (c *Clock) Expand(text string) string {
+	return expandTimeField(text, len(c.Layout()))
+}
+
+// expandTimeField replaces the time fields of text with width markers
+func expandTimeField(text string, width int) string {
+	return strings.ReplaceAll(text, timeField, strings.Repeat(string(timeMarker), width))
+}
+
+// timeChar returns the character of the time shown by the marker at i
+func (s *Scroller) timeChar(i int) string {
+	start := i
+	for start > 0 && s.Text[start-1] == timeMarker {
+		start--
+	}
+	t := s.TimeText()
+	if i-start >= len(t) {
+		return " "
+	}
+	return string(t[i-start])
+}
+
+// drawClock draws the time in the top right corner of the scroller
+func (g *Game) drawClock(s *Scroller) {
+	const scale = 0.5
+	text := g.clock.Text()
+	x := float64(s.canvas.Bounds().Dx()) - float64(len(text))*32*scale - 8
+	s.drawText(text, x, 8, scale)
+}
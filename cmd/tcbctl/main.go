@@ -0,0 +1,113 @@
+// Command tcbctl controls a demo running with -remote from the terminal
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const usage = `Usage: tcbctl [flags] command
+
+Commands:
+  text set FILE      replace the scroll text (- reads standard input)
+  text append FILE   append to the scroll text
+  volume LEVEL       set the music volume, 0 to 1
+  scene next         move to the next scene
+
+Flags:
+`
+
+func main() {
+	addr := flag.String("addr", envOr("TCB_REMOTE_ADDR", "http://localhost:8080"), "address of the demo remote API, $TCB_REMOTE_ADDR when set")
+	token := flag.String("token", os.Getenv("TCB_REMOTE_TOKEN"), "bearer token of the remote API (default $TCB_REMOTE_TOKEN)")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	method, path, body, err := command(flag.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "tcbctl: %v\n\n", err)
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := send(*addr, *token, method, path, body); err != nil {
+		fmt.Fprintf(os.Stderr, "tcbctl: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// command maps the command line to a request of the remote API
+func command(args []string) (method, path string, body []byte, err error) {
+	switch {
+	case len(args) == 3 && args[0] == "text" && (args[1] == "set" || args[1] == "append"):
+		body, err = readFile(args[2])
+		if err != nil {
+			return "", "", nil, err
+		}
+		method = http.MethodPut
+		if args[1] == "append" {
+			method = http.MethodPost
+		}
+		return method, "/text", body, nil
+	case len(args) == 2 && args[0] == "volume":
+		return http.MethodPut, "/volume", []byte(args[1]), nil
+	case len(args) == 2 && args[0] == "scene" && args[1] == "next":
+		return http.MethodPost, "/scene/next", nil, nil
+	case len(args) == 0:
+		return "", "", nil, fmt.Errorf("missing command")
+	}
+	return "", "", nil, fmt.Errorf("unknown command %q", strings.Join(args, " "))
+}
+
+// readFile reads a file, or standard input for "-"
+func readFile(name string) ([]byte, error) {
+	if name == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read text: %w", err)
+	}
+	return data, nil
+}
+
+// send makes the request and reports the demo's answer
+func send(addr, token, method, path string, body []byte) error {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	req, err := http.NewRequest(method, strings.TrimRight(addr, "/")+path, strings.NewReader(string(body)))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach the demo: %w", err)
+	}
+	defer resp.Body.Close()
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+)
+
+// Compare runs two demos side by side, the left one with the timing of
+// the ST (50 Hz, whole pixels), the right one with the settings given,
+// to check the port against the original
+type Compare struct {
+	demos  [2]*Game
+	labels [2]string
+	canvas [2]*ebiten.Image
+}
+
+// NewCompare creates both demos. Only the left one plays the music, the
+// right one follows it.
+func NewCompare(cfg *Config) *Compare {
+	st := *cfg
+	st.TickRate = 50
+	st.FixedPoint = true
+	// The demos are drawn at half size, away from the mouse
+	st.STPointer = false
+
+	// The outside connections belong to the left demo alone
+	twin := *cfg
+	twin.STPointer = false
+	twin.Silent = true
+	twin.Stdin = false
+	twin.RemoteAddr = ""
+	twin.ChatChannel = ""
+	twin.FeedURL = ""
+	twin.Subtitles = ""
+	twin.Settings = ""
+
+	c := &Compare{
+		demos:  [2]*Game{NewGame(&st), NewGame(&twin)},
+		labels: [2]string{"50 HZ FIXED POINT", compareLabel(cfg)},
+	}
+	for i := range c.canvas {
+		c.canvas[i] = ebiten.NewImage(screenWidth, screenHeight)
+	}
+	return c
+}
+
+// compareLabel describes the timing of cfg
+func compareLabel(cfg *Config) string {
+	rate := ebiten.TPS()
+	if cfg.TickRate > 0 {
+		rate = cfg.TickRate
+	}
+	label := fmt.Sprintf("%d HZ", rate)
+	if cfg.FixedPoint {
+		return label + " FIXED POINT"
+	}
+	return label + " FLOAT"
+}
+
+// Update steps both demos, stopping when either quits
+func (c *Compare) Update() error {
+	c.demos[1].music = c.demos[0].music
+	for _, g := range c.demos {
+		if err := g.Update(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Draw shows the demos at half size, next to each other
+func (c *Compare) Draw(screen *ebiten.Image) {
+	for i, g := range c.demos {
+		g.Draw(c.canvas[i])
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Scale(0.5, 0.5)
+		op.GeoM.Translate(float64(i*screenWidth/2), screenHeight/4)
+		op.Filter = ebiten.FilterLinear
+		screen.DrawImage(c.canvas[i], op)
+		ebitenutil.DebugPrintAt(screen, c.labels[i], i*screenWidth/2+8, screenHeight/4-20)
+	}
+}
+
+func (c *Compare) Layout(outsideWidth, outsideHeight int) (int, int) {
+	return screenWidth, screenHeight
+}
@@ -0,0 +1,476 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image/color"
+	"os"
+	"strings"
+	"time"
+
+	"tcb-multi-plane-3d-scroller/easings"
+)
+
+// Config holds the user-tunable settings of the demo
+type Config struct {
+	// Heat haze over the horizon rows of the landscape
+	HeatHaze          bool
+	HeatHazeIntensity float64
+
+	// Water ripples over the landscape foreground
+	WaterRipple bool
+
+	// Music file played instead of the built-in YM tune (.ym, .mod, .wav,
+	// .ogg or .mp3)
+	Music string
+	// Subsong of the music played first, counted from 1
+	Subsong int
+
+	// Audio output: sample rate, player buffer (0 for Ebiten's default)
+	// and samples computed at once by the YM replayer. A larger buffer
+	// adds latency but survives a busy sound server.
+	AudioRate   int
+	AudioBuffer time.Duration
+	AudioChunk  int
+	// WAV stream of audio played elsewhere driving the music synced
+	// effects instead of the demo's own music, "-" for standard input
+	AudioInput string
+	// LRC file of lyrics following the music, and how to show them
+	Lyrics     string
+	LyricsMode LyricsMode
+	// Stereo placement of the YM voices, and the ST output filter
+	YMStereo YMStereo
+	LowPass  bool
+	// Loops of the music before it fades out over FadeOut and the demo
+	// ends, 0 to loop forever
+	MusicLoops int
+	FadeOut    time.Duration
+	// Volume of the sound effects on key presses, 0 to disable them
+	SFXVolume float64
+	// Rate of the demo animation in Hz, 0 for every update, and whether
+	// positions snap to whole pixels as with the ST's fixed-point maths
+	TickRate   int
+	FixedPoint bool
+	// Compare shows the demo at 50 Hz in fixed point next to the demo
+	// with the settings given
+	Compare bool
+	// Silent leaves the audio out, for the second demo of the comparison
+	Silent bool
+
+	// Tempo of sampled music and audio input in BPM, 0 to detect it
+	BPM float64
+
+	// Audio output device, empty for the default one
+	AudioDevice string
+
+	// Glow around the scroller letters pulsing with a music channel
+	Glow        bool
+	GlowColor   color.RGBA
+	GlowRadius  float64
+	GlowChannel int
+
+	// Bloom over the final frame
+	Bloom bool
+
+	// Shader effects over the planes and the final frame, off on slow
+	// GPUs
+	PostEffects bool
+
+	// Frames drawn per second, 0 to draw every update
+	DrawFPS int
+
+	// .tcbdemo bundle of a show applied under the command line flags,
+	// and the flags of the show exported with Ctrl+X
+	Demo      string
+	showFlags []DemoFlag
+	demoDir   string // where the files of the bundle were extracted
+
+	// Performance profile applied under the command line flags
+	Profile string
+	// When the power saving profile replaces it
+	PowerSave PowerSave
+	// The joke 512K mode, through its profile
+	HalfMeg bool
+
+	// Quality preset shared by every blurring effect
+	BlurQuality BlurQuality
+
+	// Final post pass: film grain and 16-color ST palette reduction
+	Grain    float64
+	Quantize bool
+	Dither   bool
+
+	// Display calibration of the final frame, neutral at gamma,
+	// contrast and saturation 1 and brightness 0
+	Gamma      float64
+	Brightness float64
+	Contrast   float64
+	Saturation float64
+
+	// Colors of the rasters, background and color banks, with
+	// alternatives for color blindness
+	Palette Palette
+
+	// Slow camera pan shifting every plane by its depth
+	CameraPan bool
+
+	// Layers hidden from the start, as with the number keys
+	HideLayers LayerSet
+
+	// ST mouse pointer drawn in place of the system one
+	STPointer bool
+
+	// How the scrollers draw their letters, timed at startup when auto
+	DrawPath DrawPath
+
+	// Frame pacing diagnostics, and the demo timed by the clock rather
+	// than by the updates, for throttled browser tabs
+	Pacing bool
+
+	// Layout of the scroll text
+	ScrollMode ScrollMode
+
+	// Ring scroller geometry, tilt in degrees
+	RingRadius float64
+	RingTilt   float64
+	RingSpeed  float64
+
+	// What happens when the scroll text runs out
+	TextEnd TextEndMode
+
+	// Scroll right to left (switchable in the text with ^R and ^L)
+	RightToLeft bool
+	// Pixels the main scroller moves per update (changed with [ and ])
+	ScrollSpeed float64
+	// Seconds the scrollers take to morph into a new waveform, and the
+	// easing curve of the morph
+	FormMorph      float64
+	FormMorphCurve easings.Curve
+	// Width in letters over which the letters grow in as they enter the
+	// line, 0 for none, and the easing curve they grow along
+	Entrance      float64
+	EntranceCurve easings.Curve
+
+	// File holding the scroll text, empty for assets/scrolltext.txt or
+	// the built-in text, and the address it is downloaded from first
+	ScrollText    string
+	ScrollTextURL string
+
+	// Second scroller plane: the file of its text, empty for none, its
+	// speed and layout, its waveform (-1 to follow the ^0-^9 codes) and
+	// the canvas row it runs on in path layout
+	Scroller2      string
+	Scroller2Speed float64
+	Scroller2Mode  ScrollMode
+	Scroller2Form  int
+	Scroller2Y     int
+
+	// JSON descriptor of a font replacing bgfont.png, empty for the
+	// built-in one
+	Font string
+
+	// TrueType or OpenType font rasterized in place of the bitmap one,
+	// at TTFSize pixels
+	TTF     string
+	TTFSize float64
+
+	// Letters of the scrollers turned in perspective with the waves
+	Perspective bool
+
+	// Letters of the scrollers spaced by their glyph widths
+	Proportional bool
+
+	// Letters drawn in the middle of each scroller line, 0 for all, and
+	// their size
+	MaxLetters  int
+	LetterScale float64
+
+	// JSON descriptors or TrueType fonts, more fonts for the ^F codes
+	ExtraFonts []string
+
+	// JSON file naming the images of the ^[img:name] codes
+	Stamps string
+
+	// JSON file of the waveforms replacing the built-in ones, empty for
+	// those
+	Forms string
+
+	// Scroll the lines read from standard input, queueing at most
+	// StdinQueue bytes
+	Stdin      bool
+	StdinQueue int
+
+	// RSS, Atom or JSON endpoint whose headlines are spliced into the
+	// scroll text, fetched every FeedInterval
+	FeedURL      string
+	FeedInterval time.Duration
+
+	// Clock in the corner of the scroller, also shown in place of
+	// %TIME% in the scroll text
+	Clock        bool
+	ClockSeconds bool
+
+	// Countdown scene shown first until the Countdown deadline, then
+	// the CountdownNext scene
+	Countdown     string
+	CountdownText string
+	CountdownNext string
+
+	// IRC or Twitch channel relayed into a second scroller, empty for
+	// none. The password of ChatNick is read from TCB_CHAT_PASS.
+	ChatServer  string
+	ChatTLS     bool
+	ChatChannel string
+	ChatNick    string
+	ChatRate    int
+
+	// Filter applied to all external text, with per-source overrides
+	// (stdin, feed, chat, remote) in the same key=value syntax
+	Filter          TextFilter
+	FilterOverrides map[string]string
+
+	// HTTP remote control API, empty address for none. The text
+	// endpoint requires RemoteToken as a bearer token, and checks the
+	// texts with the Textlint command.
+	RemoteAddr  string
+	RemoteToken string
+	Textlint    string
+
+	// Seed of every random number of the demo, making runs reproducible
+	Seed int64
+
+	// Token of a shared moment to start from, as saved with M
+	Moment string
+
+	// Directory receiving the waveform gallery, empty to run the demo
+	Gallery string
+	// Background image replacing mountains.png, and the descriptor of
+	// its layers, empty for the built-in ones
+	Mountains      string
+	MountainLayers string
+
+	// Table of the palette and shift changes at chosen scanlines, empty
+	// for none
+	Scanlines string
+
+	// PNG file receiving the mountain layers contact sheet, empty to run
+	// the demo
+	MountainSheet string
+
+	// WAV file receiving the music rendered once through, empty to run
+	// the demo, and the format of its samples
+	DumpAudio       string
+	DumpAudioFormat PCMFormat
+
+	// JSON file receiving the statistics at exit, empty for none
+	StatsFile string
+
+	// Soak test: how long the demo runs minimized while its invariants
+	// are checked, 0 for none, and the file receiving the report
+	Soak       time.Duration
+	SoakReport string
+
+	// Heap and VRAM megabytes a scene may use before it is reported, 0
+	// for no budget
+	MemoryBudget int
+
+	// JSON file remembering the achievements between runs, empty to
+	// forget them
+	Settings string
+
+	// SRT or WebVTT file receiving the timing of the scroll text
+	// sentences at exit, empty for none
+	Subtitles string
+}
+
+// DefaultConfig returns the settings matching the original screen
+func DefaultConfig() *Config {
+	return &Config{
+		HeatHaze:          false,
+		HeatHazeIntensity: 1.5,
+		GlowColor:         color.RGBA{0x40, 0xa0, 0xff, 0xff},
+		GlowRadius:        6,
+		GlowChannel:       0,
+		PostEffects:       true,
+		BlurQuality:       BlurMedium,
+		Dither:            true,
+		TTFSize:           26,
+		LetterScale:       1,
+		Perspective:       true,
+		ScrollSpeed:       4,
+		FormMorph:         defaultFormMorph,
+		FormMorphCurve:    easings.MustParse(defaultMorphCurve),
+		EntranceCurve:     easings.MustParse(defaultEntranceCurve),
+		SoakReport:        "soak-report.txt",
+		Scroller2Speed:    2,
+		Scroller2Mode:     ScrollPath,
+		Scroller2Form:     -1,
+		Scroller2Y:        canvasHeight - 24,
+		Gamma:             1,
+		Contrast:          1,
+		Saturation:        1,
+		RingRadius:        120,
+		RingTilt:          20,
+		RingSpeed:         0.025,
+		StdinQueue:        4096,
+		FeedInterval:      10 * time.Minute,
+		CountdownText:     "COMPO STARTS IN",
+		CountdownNext:     "demo",
+		ChatServer:        "irc.chat.twitch.tv:6697",
+		ChatTLS:           true,
+		ChatRate:          20,
+		Filter:            DefaultTextFilter(),
+		FilterOverrides:   make(map[string]string),
+		RemoteToken:       os.Getenv("TCB_REMOTE_TOKEN"),
+		Textlint:          "textlint",
+		Subsong:           1,
+		AudioRate:         44100,
+		AudioChunk:        4096,
+		FadeOut:           5 * time.Second,
+		SFXVolume:         0.15,
+		Settings:          defaultSettingsPath(),
+		Seed:              1989,
+	}
+}
+
+// RegisterFlags binds the config fields to command line flags
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Music, "music", c.Music, "play this YM, ProTracker MOD, WAV, Ogg Vorbis or MP3 file instead of the built-in tune")
+	fs.StringVar(&c.AudioInput, "audio-input", c.AudioInput, "visualize this 16-bit WAV file or pipe (- for standard input) instead of playing music")
+	fs.StringVar(&c.Lyrics, "lyrics", c.Lyrics, "LRC file of lyrics following the music")
+	fs.Var(&c.LyricsMode, "lyrics-mode", "how lyrics are shown: karaoke (line at the bottom) or scroll (fed to the scroller)")
+	fs.IntVar(&c.MusicLoops, "music-loops", c.MusicLoops, "loops of the music before it fades out and the demo ends; 0 loops forever")
+	fs.DurationVar(&c.FadeOut, "fade-out", c.FadeOut, "how long the music fades out after -music-loops")
+	fs.Float64Var(&c.SFXVolume, "sfx", c.SFXVolume, "volume of the sound effects on key presses, 0 to disable them")
+	fs.IntVar(&c.TickRate, "tick-rate", c.TickRate, "rate of the demo animation in Hz, e.g. 50 as on the ST; 0 moves on every update")
+	fs.BoolVar(&c.FixedPoint, "fixed-point", c.FixedPoint, "snap the scroller letters and the logo distortion to whole pixels like the ST's fixed-point maths")
+	fs.BoolVar(&c.Compare, "compare", c.Compare, "show the demo at 50 Hz in fixed point next to the demo with the settings given, to check the port")
+	fs.Float64Var(&c.BPM, "bpm", c.BPM, "tempo of WAV, Ogg Vorbis, MP3 or audio input music for the beat effects; 0 detects it")
+	fs.IntVar(&c.Subsong, "subsong", c.Subsong, "subsong of the music played first, from 1 (select with Shift+1 to 9)")
+	fs.IntVar(&c.AudioRate, "audio-rate", c.AudioRate, "audio sample rate in Hz")
+	fs.DurationVar(&c.AudioBuffer, "audio-buffer", c.AudioBuffer, "audio player buffer, e.g. 100ms; 0 for the default")
+	fs.StringVar(&c.AudioDevice, "audio-device", c.AudioDevice, "audio output device (Linux), \"list\" to show them")
+	fs.IntVar(&c.AudioChunk, "audio-chunk", c.AudioChunk, "samples computed at once by the YM replayer")
+	fs.Var(&c.YMStereo, "ym-stereo", "stereo placement of the YM voices: mono, abc or acb")
+	fs.BoolVar(&c.LowPass, "st-filter", c.LowPass, "soften the YM music with a low-pass filter like the ST output (toggle with L)")
+	fs.BoolVar(&c.HeatHaze, "haze", c.HeatHaze, "enable the heat haze above the horizon (toggle with H)")
+	fs.Float64Var(&c.HeatHazeIntensity, "haze-intensity", c.HeatHazeIntensity, "maximum heat haze displacement in pixels")
+	fs.BoolVar(&c.WaterRipple, "ripple", c.WaterRipple, "enable water ripples over the landscape foreground (toggle with R)")
+	fs.BoolVar(&c.Glow, "glow", c.Glow, "enable the scroller glow pulsing with the music (toggle with G)")
+	fs.Var((*hexColor)(&c.GlowColor), "glow-color", "scroller glow color as #rrggbb")
+	fs.Float64Var(&c.GlowRadius, "glow-radius", c.GlowRadius, "scroller glow radius in pixels")
+	fs.IntVar(&c.GlowChannel, "glow-channel", c.GlowChannel, "music channel driving the glow (YM 0-2 for A-C, MOD 0-3)")
+	fs.BoolVar(&c.Bloom, "bloom", c.Bloom, "enable bloom over the final frame (toggle with B)")
+	fs.StringVar(&c.Demo, "demo", c.Demo, "play the show of this .tcbdemo bundle exported with Ctrl+X; flags given win over it")
+	fs.StringVar(&c.Profile, "profile", c.Profile, "performance profile setting the flags not given: "+profileNames())
+	fs.Var(&c.PowerSave, "power-save", "use the powersave profile: auto (on battery, without -profile), on or off")
+	fs.BoolVar(&c.HalfMeg, "halfmeg", c.HalfMeg, "512K mode: make the demo fit in half a meg, as the scroll text says, with the halfmeg profile")
+	fs.BoolVar(&c.PostEffects, "post", c.PostEffects, "enable the shader effects (haze, ripples, glow, bloom, grain, transitions)")
+	fs.IntVar(&c.DrawFPS, "draw-fps", c.DrawFPS, "frames drawn per second, 0 to draw every update")
+	fs.Var(&c.BlurQuality, "blur-quality", "blur quality preset: low, medium or high")
+	fs.Float64Var(&c.Grain, "grain", c.Grain, "film grain strength (0 disables)")
+	fs.BoolVar(&c.Quantize, "quantize", c.Quantize, "reduce the final frame to 16 colors of the 512-color ST palette")
+	fs.BoolVar(&c.Dither, "dither", c.Dither, "use ordered dithering between the 16 colors when reducing the frame")
+	fs.Float64Var(&c.Gamma, "gamma", c.Gamma, "display gamma, above 1 to brighten the midtones")
+	fs.Float64Var(&c.Brightness, "brightness", c.Brightness, "display brightness added to every channel, -0.5 to 0.5")
+	fs.Float64Var(&c.Contrast, "contrast", c.Contrast, "display contrast around mid-gray")
+	fs.Float64Var(&c.Saturation, "saturation", c.Saturation, "display color saturation, 0 for black and white")
+	fs.Var(&c.Palette, "palette", "colors of the rasters, background and color banks: normal, deuteranopia or protanopia")
+	fs.Var(&c.ScrollMode, "scroll-mode", "scroller layout: horizontal, vertical, ring or path (along the logo)")
+	fs.Float64Var(&c.RingRadius, "ring-radius", c.RingRadius, "ring scroller radius")
+	fs.Float64Var(&c.RingTilt, "ring-tilt", c.RingTilt, "ring scroller tilt towards the camera in degrees")
+	fs.Float64Var(&c.RingSpeed, "ring-speed", c.RingSpeed, "ring scroller spin in radians per frame")
+	fs.Var(&c.TextEnd, "text-end", "end of scroll text behavior: loop, pingpong, stop or next")
+	fs.BoolVar(&c.RightToLeft, "rtl", c.RightToLeft, "scroll the text right to left")
+	fs.Float64Var(&c.ScrollSpeed, "scroll-speed", c.ScrollSpeed, "speed of the scroller in pixels per update")
+	fs.Float64Var(&c.FormMorph, "form-morph", c.FormMorph, "seconds the scrollers take to morph into a new waveform, 0 to switch at once")
+	fs.Var(&c.FormMorphCurve, "form-morph-curve", "easing curve of the waveform morphs: linear, smooth, in-sine, out-sine, in-out-sine, in-expo, out-expo, in-out-expo, in-bounce, out-bounce, in-out-bounce or bezier(x1,y1,x2,y2)")
+	fs.Float64Var(&c.Entrance, "letter-entrance", c.Entrance, "width in letters over which the letters grow in as they enter the line, 0 to show them whole")
+	fs.Var(&c.EntranceCurve, "letter-entrance-curve", "easing curve the letters grow in along, as -form-morph-curve")
+	fs.StringVar(&c.Font, "font", c.Font, "JSON descriptor of a font image, or a PNG sheet laid out as bgfont.png, replacing the built-in font, see README")
+	fs.StringVar(&c.TTF, "ttf", c.TTF, "TrueType or OpenType font rasterized in place of the bitmap font")
+	fs.Float64Var(&c.TTFSize, "ttf-size", c.TTFSize, "size of the -ttf and extra TrueType fonts in pixels, at most the 33 of a tile line")
+	fs.BoolVar(&c.Perspective, "perspective", c.Perspective, "draw the letters as quads turning with steep slopes of the waveforms rather than flat")
+	fs.BoolVar(&c.Proportional, "proportional", c.Proportional, "space the scroller letters by the widths of their glyphs rather than a tile each")
+	fs.IntVar(&c.MaxLetters, "max-letters", c.MaxLetters, "draw only this many letters in the middle of each scroller line (0 for all)")
+	fs.Float64Var(&c.LetterScale, "letter-scale", c.LetterScale, "size of the scroller letters, 1 for their tiles")
+	fs.Var((*pathList)(&c.ExtraFonts), "extra-font", "JSON descriptor or TrueType font of one more font for the ^F codes, repeat for several")
+	fs.StringVar(&c.Stamps, "stamps", c.Stamps, "JSON file naming the PNG images the ^[img:name] codes show in the scroll text, see README")
+	fs.StringVar(&c.Forms, "forms", c.Forms, "JSON file of up to 10 waveforms for the ^0-^9 codes, replacing the built-in ones, see README")
+	fs.StringVar(&c.ScrollText, "scrolltext", c.ScrollText, "file holding the scroll text (default assets/scrolltext.txt when present, else the built-in text)")
+	fs.StringVar(&c.ScrollTextURL, "scrolltext-url", c.ScrollTextURL, "download the scroll text from this HTTP address at startup, falling back to -scrolltext or the built-in text")
+	fs.StringVar(&c.Scroller2, "scroller2", c.Scroller2, "file holding the text of a second scroller plane, none when empty")
+	fs.Float64Var(&c.Scroller2Speed, "scroller2-speed", c.Scroller2Speed, "speed of the second scroller in pixels per update")
+	fs.Var(&c.Scroller2Mode, "scroller2-mode", "layout of the second scroller: horizontal, vertical, ring or path (flat, along -scroller2-y)")
+	fs.IntVar(&c.Scroller2Form, "scroller2-form", c.Scroller2Form, "waveform of the second scroller, from 0, or -1 to follow its ^0-^9 codes")
+	fs.IntVar(&c.Scroller2Y, "scroller2-y", c.Scroller2Y, "canvas row the second scroller runs on in path layout, 0 to 199")
+	fs.BoolVar(&c.Stdin, "stdin", c.Stdin, "scroll the lines read from standard input as they arrive")
+	fs.IntVar(&c.StdinQueue, "stdin-queue", c.StdinQueue, "bytes of standard input text waiting, reading stops while they are queued")
+	fs.StringVar(&c.FeedURL, "feed-url", c.FeedURL, "RSS, Atom or JSON endpoint whose headlines are spliced into the scroll text")
+	fs.DurationVar(&c.FeedInterval, "feed-interval", c.FeedInterval, "how often the headline feed is fetched")
+	fs.BoolVar(&c.Clock, "clock", c.Clock, "show a clock in the corner of the scroller")
+	fs.BoolVar(&c.ClockSeconds, "clock-seconds", c.ClockSeconds, "show seconds in the clock and in %TIME%")
+	fs.StringVar(&c.Countdown, "countdown", c.Countdown, "count down to this deadline first (15:04, 2006-01-02 15:04 or RFC 3339)")
+	fs.StringVar(&c.CountdownText, "countdown-text", c.CountdownText, "message shown before the remaining time")
+	fs.StringVar(&c.CountdownNext, "countdown-next", c.CountdownNext, "scene shown once the countdown is over")
+	fs.StringVar(&c.ChatChannel, "chat-channel", c.ChatChannel, "IRC or Twitch channel whose messages run in a second scroller")
+	fs.StringVar(&c.ChatServer, "chat-server", c.ChatServer, "chat server as host:port")
+	fs.BoolVar(&c.ChatTLS, "chat-tls", c.ChatTLS, "connect to the chat server over TLS")
+	fs.StringVar(&c.ChatNick, "chat-nick", c.ChatNick, "chat login, anonymous when empty (password in TCB_CHAT_PASS)")
+	fs.IntVar(&c.ChatRate, "chat-rate", c.ChatRate, "chat messages shown per minute at most")
+	fs.Var(&c.Filter, "filter", "filter for external text as words=a,b;chars=ABC;max=N")
+	for _, src := range textSources {
+		fs.Var(filterOverride{c.FilterOverrides, src}, "filter-"+src, "filter settings overriding -filter for "+src+" text")
+	}
+	fs.StringVar(&c.RemoteAddr, "remote", c.RemoteAddr, "serve the HTTP remote control API on this address, e.g. :8080")
+	fs.StringVar(&c.RemoteToken, "remote-token", c.RemoteToken, "bearer token of the authenticated remote endpoints (default $TCB_REMOTE_TOKEN)")
+	fs.StringVar(&c.Textlint, "textlint", c.Textlint, "textlint command checking the remote text, such as \"npx textlint\"")
+	fs.BoolVar(&c.CameraPan, "camera-pan", c.CameraPan, "pan the camera across all planes (toggle with C)")
+	fs.Var(&c.HideLayers, "hide-layers", "layers hidden from the start, as with the keys 1 to 6: mountains, logo, tcb, scroller, rasters and effects, separated by commas")
+	fs.BoolVar(&c.Pacing, "pacing", c.Pacing, "log frame pacing, audio underruns and page visibility, and time the demo by the clock")
+	fs.Var(&c.DrawPath, "draw-path", "how letters are drawn: auto (timed at startup), tiles (a DrawImage each) or batched (one DrawTriangles call)")
+	fs.BoolVar(&c.STPointer, "st-pointer", c.STPointer, "draw the ST mouse pointer, the busy bee while loading, in place of the system one")
+	fs.Int64Var(&c.Seed, "seed", c.Seed, "seed of every random number of the demo (noise, grain, haze), making runs reproducible")
+	fs.StringVar(&c.Moment, "moment", c.Moment, "start from a moment saved with M: scroll position, waveform, palette and music time")
+	fs.DurationVar(&c.Soak, "soak", c.Soak, "run minimized for this long, e.g. 8h, checking the demo's invariants, then write a report and exit")
+	fs.StringVar(&c.SoakReport, "soak-report", c.SoakReport, "file receiving the -soak report")
+	fs.StringVar(&c.StatsFile, "stats-json", c.StatsFile, "write the demo statistics to this JSON file at exit")
+	fs.IntVar(&c.MemoryBudget, "memory-budget", c.MemoryBudget, "report the scenes using more than this many MB of heap and VRAM (0 for no budget)")
+	fs.StringVar(&c.Settings, "settings", c.Settings, "JSON file remembering the achievements between runs; empty to forget them")
+	fs.StringVar(&c.Subtitles, "subtitles", c.Subtitles, "write the scroll text sentences with their times to this SRT or WebVTT (.vtt) file at exit")
+	fs.StringVar(&c.DumpAudio, "dump-audio", c.DumpAudio, "render the music once through into this WAV file and exit")
+	fs.Var(&c.DumpAudioFormat, "dump-audio-format", "sample format of the -dump-audio file: int16, float32 or uint8")
+	fs.StringVar(&c.Gallery, "gallery", c.Gallery, "render a labeled PNG of every waveform into this directory and exit")
+	fs.StringVar(&c.Mountains, "mountains", c.Mountains, "PNG replacing the built-in mountains background")
+	fs.StringVar(&c.MountainLayers, "mountain-layers", c.MountainLayers, "descriptor of the background strips, one \"top-bottom speed [y]\" line per layer (default 32 strips of 10 rows)")
+	fs.StringVar(&c.Scanlines, "scanlines", c.Scanlines, "table of tint and shift changes at chosen scanlines, emulating raster interrupts, one \"line [tint=#rrggbb] [shift=n]\" line per change")
+	fs.StringVar(&c.MountainSheet, "mountain-sheet", c.MountainSheet, "render the mountain layers with their speeds into this PNG contact sheet and exit")
+}
+
+// pathList is a flag.Value collecting the values of a repeated flag
+type pathList []string
+
+func (p *pathList) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *pathList) Set(s string) error {
+	*p = append(*p, s)
+	return nil
+}
+
+// hexColor is a flag.Value parsing #rrggbb colors
+type hexColor color.RGBA
+
+func (h *hexColor) String() string {
+	return fmt.Sprintf("#%02x%02x%02x", h.R, h.G, h.B)
+}
+
+func (h *hexColor) Set(s string) error {
+	var r, g, b uint8
+	if _, err := fmt.Sscanf(strings.TrimPrefix(s, "#"), "%02x%02x%02x", &r, &g, &b); err != nil {
+		return fmt.Errorf("invalid color %q, expected #rrggbb", s)
+	}
+	*h = hexColor{r, g, b, 0xff}
+	return nil
+}
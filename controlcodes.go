@@ -0,0 +1,120 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// The control codes of the scroll text, '^' followed by:
+//
+//	0-9  switch to waveform n, the codes past the last form being text
+//	R, L scroll right to left or left to right
+//	V    reverse the scroll, the text running back towards its start
+//	Sn   scroll at n pixels a frame, 0 for the scroller's speed
+//	Pn   stop scrolling for n seconds
+//	Cn   color the following letters with bank n, 0 for the rasters
+//	Fn   draw the following letters in font face n
+//	Wn   flash (1) or pulse (2) the word after the code, 0 for neither
+//	[img:name]  show the image stamp name in the text, as a letter
+//
+// The codes take no letter slots: the scroller reads the text as a
+// stream of letters and codes, see TokenizeScrollText.
+
+// isControlCode reports whether c may follow '^' on its own: a waveform
+// digit, R/L switching the scroll direction or V reversing the scroll
+func isControlCode(c byte) bool {
+	return isFormCode(c) || c == 'R' || c == 'L' || c == 'V'
+}
+
+// hasControlArg reports whether c may follow '^' with a digit after it
+func hasControlArg(c byte) bool {
+	return c == 'S' || c == 'P' || c == 'C' || c == 'F' || c == 'W'
+}
+
+// controlCodeLen returns the length of the control code starting at
+// text[i], 0 when there is none
+func controlCodeLen(text string, i int) int {
+	if i+1 >= len(text) || text[i] != '^' {
+		return 0
+	}
+	c := upper(text[i+1])
+	switch {
+	case c == '[':
+		return stampCodeLen(text, i)
+	case isControlCode(c):
+		return 2
+	case hasControlArg(c) && i+2 < len(text) && text[i+2] >= '0' && text[i+2] <= '9':
+		return 3
+	}
+	return 0
+}
+
+func upper(c byte) byte {
+	if c >= 'a' && c <= 'z' {
+		return c - 'a' + 'A'
+	}
+	return c
+}
+
+// colorBanks are the colors of the ^C codes, bank 0 leaving the letters
+// to the rasters
+var colorBanks = []color.RGBA{
+	{},
+	{0xe0, 0x20, 0x20, 0xff},
+	{0x20, 0xe0, 0x20, 0xff},
+	{0x40, 0x60, 0xff, 0xff},
+	{0xff, 0xe0, 0x20, 0xff},
+	{0x20, 0xe0, 0xe0, 0xff},
+	{0xe0, 0x40, 0xe0, 0xff},
+	{0xff, 0xff, 0xff, 0xff},
+}
+
+// fontStyle reshapes the tiles of a font
+type fontStyle struct {
+	slant float64 // horizontal shear, letters leaning right
+	width float64 // horizontal scale
+}
+
+// fontStyles are the styles of the first faces: plain, italic, wide and
+// narrow
+var fontStyles = []fontStyle{
+	{width: 1},
+	{slant: -0.3, width: 1},
+	{width: 1.4},
+	{width: 0.7},
+}
+
+// apply reshapes a letter centered on the origin
+func (f fontStyle) apply(m *ebiten.GeoM) {
+	m.Skew(f.slant, 0)
+	m.Scale(f.width, 1)
+}
+
+// fontFace is what a ^F code switches to: one of the scroller's fonts
+// drawn in a style. The first faces are the styles of the main font,
+// each font added after it is one more plain face.
+type fontFace struct {
+	font  int
+	style fontStyle
+}
+
+// enterCode applies the speed, pause and reverse codes entering the
+// screen with the letter after them
+func (s *Scroller) enterCode() {
+	tokens := s.tokens()
+	for i, k := 0, s.edge; i < len(tokens); i, k = i+1, k+s.textDir() {
+		t := tokens[wrapToken(k, len(tokens))]
+		if !t.IsCode() {
+			return
+		}
+		switch t.Code.Kind {
+		case 'S':
+			s.speed = float64(t.Code.Arg)
+		case 'P':
+			s.pause = t.Code.Arg * s.Rate
+		case 'V':
+			s.reverse()
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// countdownScene scrolls a message counting down to a deadline over the
+// demo screen, then moves on to the follow-up scene
+type countdownScene struct {
+	demoScene
+	target time.Time
+	next   string
+	long   bool // the deadline was more than an hour away
+}
+
+// newCountdownScene creates the countdown to target, showing message
+// before the remaining time
+func (g *Game) newCountdownScene(target time.Time, message, next string) *countdownScene {
+	s := &countdownScene{
+		target: target,
+		next:   next,
+		long:   time.Until(target) >= time.Hour,
+	}
+	width := len("00:00")
+	if s.long {
+		width = len("00:00:00")
+	}
+
+	spc := strings.Repeat(" ", scrollLetters)
+	sc := g.newScroller()
+	sc.Text = " ^4" + spc + expandTimeField(sanitizeText(message)+" "+timeField, width) + spc
+	sc.TimeText = s.remaining
+	s.demoScene = demoScene{g: g, scroller: sc}
+	return s
+}
+
+func (s *countdownScene) Name() string { return "countdown" }
+
+func (s *countdownScene) Update() error {
+	if !time.Now().Before(s.target) {
+		if s.next == "" || !s.g.timeline.Goto(s.next) {
+			s.g.timeline.Next()
+		}
+		return nil
+	}
+	return s.demoScene.Update()
+}
+
+// remaining formats the time left, rounded up to the second
+func (s *countdownScene) remaining() string {
+	d := max(0, (time.Until(s.target) + time.Second - 1).Truncate(time.Second))
+	secs := int(d / time.Second)
+	if s.long {
+		return fmt.Sprintf("%02d:%02d:%02d", secs/3600, secs/60%60, secs%60)
+	}
+	return fmt.Sprintf("%02d:%02d", secs/60, secs%60)
+}
+
+// parseDeadline reads a countdown target as RFC 3339, as a local date
+// and time, or as a time of day today (tomorrow when already passed)
+func parseDeadline(s string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04", "2006-01-02T15:04"} {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	t, err := time.ParseInLocation("15:04", s, now.Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid countdown target %q", s)
+	}
+	t = time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+	if !t.After(now) {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
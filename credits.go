@@ -0,0 +1,95 @@
+package main
+
+import (
+	"image/color"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// SongInfo is the metadata stored in a music file
+type SongInfo struct {
+	Name    string
+	Author  string
+	Comment string
+}
+
+// CreditsOverlay shows the name, author and comment of the tune playing
+// in the big font
+type CreditsOverlay struct {
+	visible bool
+
+	font   *BigFont
+	canvas *ebiten.Image
+	drawn  *SongInfo // info drawn into canvas, nil for none
+	height float64   // height of the text drawn
+}
+
+// NewCreditsOverlay creates the hidden overlay
+func NewCreditsOverlay(font *BigFont) *CreditsOverlay {
+	return &CreditsOverlay{font: font}
+}
+
+// Toggle shows or hides the credits
+func (c *CreditsOverlay) Toggle() {
+	c.visible = !c.visible
+}
+
+// Draw renders the credits of info centered on the screen
+func (c *CreditsOverlay) Draw(screen *ebiten.Image, info SongInfo) {
+	if !c.visible {
+		return
+	}
+	if c.canvas == nil {
+		c.canvas = ebiten.NewImage(screenWidth, screenHeight)
+	}
+	if c.drawn == nil || *c.drawn != info {
+		c.drawInfo(info)
+		c.drawn = &info
+	}
+
+	y := (screenHeight - c.height) / 2
+	vector.DrawFilledRect(screen, 0, float32(y-aboutMargin/2), screenWidth, float32(c.height+aboutMargin), color.RGBA{0, 0, 0, 0xd0}, false)
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(0, y)
+	screen.DrawImage(c.canvas, op)
+}
+
+// drawInfo lays the lines of info into the canvas, title first, each
+// line centered
+func (c *CreditsOverlay) drawInfo(info SongInfo) {
+	c.canvas.Clear()
+	page := "# " + info.Name
+	if info.Name == "" {
+		page = "# UNKNOWN TUNE"
+	}
+	if info.Author != "" {
+		page += "\nBY " + info.Author
+	}
+	if comment := strings.TrimSpace(info.Comment); comment != "" {
+		page += "\n\n" + comment
+	}
+
+	y := 0.0
+	for _, line := range layoutPage(c.font, page) {
+		text := strings.TrimSpace(line.text)
+		c.font.DrawString(c.canvas, text, (screenWidth-c.font.Measure(text, line.scale))/2, y, line.scale, nil)
+		y += c.font.LineHeight(line.scale) + aboutLineGap
+	}
+	c.height = y - aboutLineGap
+}
+
+// songInfo returns the metadata of the tune playing, the track name
+// standing in for music files without any
+func (g *Game) songInfo() SongInfo {
+	if i, ok := g.music.(interface{ Info() SongInfo }); ok {
+		if info := i.Info(); info.Name != "" {
+			return info
+		}
+	}
+	if len(g.tracks) > 0 {
+		return SongInfo{Name: g.tracks[g.track].Name}
+	}
+	return SongInfo{}
+}
@@ -0,0 +1,447 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A .tcbdemo bundle is a zip holding a manifest of the flags of a show
+// and the files they name, so the show plays the same on another machine
+const (
+	demoVersion  = 1
+	demoManifest = "manifest.json"
+	demoFiles    = "files"
+)
+
+// DemoFlag is one flag of a bundled show. File flags hold a path inside
+// the bundle.
+type DemoFlag struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// DemoManifest describes a bundled show. Repeated flags appear once per
+// value, in order.
+type DemoManifest struct {
+	Version int        `json:"version"`
+	Created time.Time  `json:"created"`
+	Flags   []DemoFlag `json:"flags"`
+}
+
+// bundledFlags name files, which are copied into the bundles
+var bundledFlags = map[string]bool{
+	"music":           true,
+	"lyrics":          true,
+	"font":            true,
+	"ttf":             true,
+	"extra-font":      true,
+	"stamps":          true,
+	"forms":           true,
+	"scrolltext":      true,
+	"scroller2":       true,
+	"mountains":       true,
+	"mountain-layers": true,
+	"scanlines":       true,
+}
+
+// machineFlags belong to the machine or the run rather than the show,
+// and are left out of the bundles
+var machineFlags = map[string]bool{
+	"demo":              true,
+	"profile":           true,
+	"power-save":        true,
+	"audio-device":      true,
+	"audio-input":       true,
+	"stdin":             true,
+	"stdin-queue":       true,
+	"remote":            true,
+	"remote-token":      true,
+	"compare":           true,
+	"pacing":            true,
+	"memory-budget":     true,
+	"soak":              true,
+	"soak-report":       true,
+	"stats-json":        true,
+	"settings":          true,
+	"subtitles":         true,
+	"dump-audio":        true,
+	"dump-audio-format": true,
+	"gallery":           true,
+	"mountain-sheet":    true,
+}
+
+// ApplyDemo sets the flags of the -demo bundle that were not given on the
+// command line, then remembers the flags making up the show for Ctrl+X.
+// Call it after parsing fs and before ApplyProfile, so the show wins
+// over the profile.
+func (c *Config) ApplyDemo(fs *flag.FlagSet) error {
+	if c.Demo != "" {
+		m, dir, err := OpenDemo(c.Demo)
+		if err != nil {
+			return err
+		}
+		c.demoDir = dir
+		given := make(map[string]bool)
+		fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+		for _, f := range m.Flags {
+			if given[f.Name] || machineFlags[f.Name] {
+				continue
+			}
+			if fs.Lookup(f.Name) == nil {
+				log.Printf("%s: skipping unknown flag -%s", c.Demo, f.Name)
+				continue
+			}
+			value := f.Value
+			if bundledFlags[f.Name] && value != "" {
+				// A path leaving the bundle would show any file here
+				if !filepath.IsLocal(filepath.FromSlash(value)) {
+					c.CloseDemo()
+					return fmt.Errorf("%s: -%s file %q is outside the bundle", c.Demo, f.Name, value)
+				}
+				value = filepath.Join(dir, filepath.FromSlash(value))
+			}
+			if err := fs.Set(f.Name, value); err != nil {
+				c.CloseDemo()
+				return fmt.Errorf("failed to apply demo %s: -%s: %w", c.Demo, f.Name, err)
+			}
+		}
+	}
+
+	c.showFlags = nil
+	fs.Visit(func(f *flag.Flag) {
+		switch {
+		case machineFlags[f.Name]:
+		case f.Name == "extra-font":
+			for _, font := range c.ExtraFonts {
+				c.showFlags = append(c.showFlags, DemoFlag{f.Name, font})
+			}
+		default:
+			c.showFlags = append(c.showFlags, DemoFlag{f.Name, f.Value.String()})
+		}
+	})
+	return nil
+}
+
+// CloseDemo removes the files extracted from the -demo bundle, once the
+// demo no longer plays them
+func (c *Config) CloseDemo() {
+	if c.demoDir == "" {
+		return
+	}
+	if err := os.RemoveAll(c.demoDir); err != nil {
+		log.Printf("Failed to remove the demo files: %v", err)
+	}
+	c.demoDir = ""
+}
+
+// OpenDemo extracts a .tcbdemo bundle into a new temporary directory and
+// returns its manifest and the directory. The files stay there while the
+// demo runs, until CloseDemo.
+func OpenDemo(name string) (*DemoManifest, string, error) {
+	r, err := zip.OpenReader(name)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open demo: %w", err)
+	}
+	defer r.Close()
+
+	var m *DemoManifest
+	for _, f := range r.File {
+		if f.Name != demoManifest {
+			continue
+		}
+		data, err := readZipFile(f)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s: failed to read manifest: %w", name, err)
+		}
+		m = new(DemoManifest)
+		if err := json.Unmarshal(data, m); err != nil {
+			return nil, "", fmt.Errorf("%s: failed to parse manifest: %w", name, err)
+		}
+	}
+	if m == nil {
+		return nil, "", fmt.Errorf("%s: no %s, not a demo bundle", name, demoManifest)
+	}
+	if m.Version > demoVersion {
+		return nil, "", fmt.Errorf("%s: demo version %d is newer than this demo's %d", name, m.Version, demoVersion)
+	}
+
+	dir, err := os.MkdirTemp("", "tcbdemo-")
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to extract demo: %w", err)
+	}
+	if err := extractDemo(name, r, dir); err != nil {
+		os.RemoveAll(dir)
+		return nil, "", err
+	}
+	return m, dir, nil
+}
+
+// extractDemo writes the files of bundle r into dir
+func extractDemo(name string, r *zip.ReadCloser, dir string) error {
+	for _, f := range r.File {
+		if f.Name == demoManifest || f.FileInfo().IsDir() {
+			continue
+		}
+		// Paths leaving the directory would write anywhere
+		if !filepath.IsLocal(f.Name) {
+			return fmt.Errorf("%s: file %q is outside the bundle", name, f.Name)
+		}
+		data, err := readZipFile(f)
+		if err != nil {
+			return fmt.Errorf("%s: failed to read %s: %w", name, f.Name, err)
+		}
+		file := filepath.Join(dir, filepath.FromSlash(f.Name))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			return fmt.Errorf("failed to extract demo: %w", err)
+		}
+		if err := os.WriteFile(file, data, 0o644); err != nil {
+			return fmt.Errorf("failed to extract demo: %w", err)
+		}
+	}
+	return nil
+}
+
+// maxDemoFile bounds the size of a file extracted from a bundle, so a
+// hostile one can't fill the memory
+const maxDemoFile = 256 << 20
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, maxDemoFile+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxDemoFile {
+		return nil, fmt.Errorf("file larger than %d MB", maxDemoFile>>20)
+	}
+	return data, nil
+}
+
+// exportDemo writes the show to a .tcbdemo bundle named after the time
+func (g *Game) exportDemo() {
+	name := "demo-" + time.Now().Format("20060102-150405") + ".tcbdemo"
+	if err := g.writeDemo(name); err != nil {
+		log.Printf("Failed to export the demo: %v", err)
+		g.notice.Show("Failed to export the demo")
+		return
+	}
+	g.notice.Show("Demo saved to " + name)
+}
+
+// writeDemo bundles the flags of the show with the files they name, and
+// the scroll text, waveforms and scroll speed as they are now
+func (g *Game) writeDemo(name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to create demo: %w", err)
+	}
+	w := &demoWriter{zw: zip.NewWriter(f), names: make(map[string]bool)}
+	err = g.bundleShow(w)
+	if cerr := w.zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(name)
+	}
+	return err
+}
+
+func (g *Game) bundleShow(w *demoWriter) error {
+	m := DemoManifest{Version: demoVersion, Created: time.Now().UTC()}
+
+	// The text, the waveforms and the settings changed with the keys and
+	// the options menu may differ from the flags, so they are bundled as
+	// they are now
+	state := g.runtimeFlags()
+	overridden := map[string]bool{"forms": true}
+	for _, fl := range state {
+		overridden[fl.Name] = true
+	}
+	if g.scroller.Feed == nil {
+		overridden["scrolltext"] = true
+		overridden["scrolltext-url"] = true
+	}
+
+	fonts := 0
+	for _, fl := range g.cfg.showFlags {
+		if overridden[fl.Name] {
+			continue
+		}
+		if bundledFlags[fl.Name] && fl.Value != "" {
+			dir := path.Join(demoFiles, fl.Name)
+			if fl.Name == "extra-font" {
+				fonts++
+				dir += "-" + strconv.Itoa(fonts)
+			}
+			bundled, err := w.addAsset(dir, fl.Name, fl.Value)
+			if err != nil {
+				return fmt.Errorf("failed to bundle -%s: %w", fl.Name, err)
+			}
+			fl.Value = bundled
+		}
+		m.Flags = append(m.Flags, fl)
+	}
+
+	if g.scroller.Feed == nil {
+		text := strings.TrimSpace(g.baseText) + "\n"
+		text = timeRun.ReplaceAllString(text, timeField)
+		bundled, err := w.add(path.Join(demoFiles, "scrolltext"), "scrolltext.txt", []byte(text))
+		if err != nil {
+			return err
+		}
+		m.Flags = append(m.Flags, DemoFlag{"scrolltext", bundled})
+	}
+	forms, err := formsJSON()
+	if err != nil {
+		return err
+	}
+	bundled, err := w.add(path.Join(demoFiles, "forms"), "forms.json", forms)
+	if err != nil {
+		return err
+	}
+	m.Flags = append(m.Flags, DemoFlag{"forms", bundled})
+	m.Flags = append(m.Flags, state...)
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.add("", demoManifest, append(data, '\n'))
+	return err
+}
+
+// runtimeFlags returns the flags of the settings the keys and the
+// options menu change while the demo runs, as they are now. The frozen
+// layers and the display settings of the menu (blur, grain, palette and
+// calibration) belong to the screen it runs on and are left out.
+func (g *Game) runtimeFlags() []DemoFlag {
+	flags := []DemoFlag{
+		{"scroll-speed", strconv.FormatFloat(g.scroller.Speed, 'g', -1, 64)},
+		{"scroll-mode", g.scroller.Mode.String()},
+		{"proportional", strconv.FormatBool(g.scroller.Proportional)},
+		{"camera-pan", strconv.FormatBool(g.camera.Enabled)},
+		{"st-filter", strconv.FormatBool(g.cfg.LowPass)},
+		{"hide-layers", (*LayerSet)(&g.hiddenLayers).String()},
+	}
+	// Effects that could not be created keep their flags
+	for _, name := range []string{"haze", "ripple", "glow", "bloom"} {
+		if g.effects.Lookup(name) != nil {
+			flags = append(flags, DemoFlag{name, strconv.FormatBool(g.effects.Enabled(name))})
+		}
+	}
+	return flags
+}
+
+// demoWriter stores files in a bundle under names not taken yet
+type demoWriter struct {
+	zw    *zip.Writer
+	names map[string]bool
+}
+
+// add stores data in dir, named after file unless another file took the
+// name, and returns its path in the bundle
+func (w *demoWriter) add(dir, file string, data []byte) (string, error) {
+	base := filepath.Base(file)
+	name := path.Join(dir, base)
+	for n := 2; w.names[name]; n++ {
+		name = path.Join(dir, strconv.Itoa(n)+"-"+base)
+	}
+	w.names[name] = true
+
+	zf, err := w.zw.Create(name)
+	if err != nil {
+		return "", err
+	}
+	_, err = zf.Write(data)
+	return name, err
+}
+
+// addAsset stores the file of a flag in dir, with the images named by
+// font and stamp descriptors next to it
+func (w *demoWriter) addAsset(dir, flagName, file string) (string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case flagName == "stamps":
+		data, err = w.addStampImages(dir, file, data)
+	case (flagName == "font" || flagName == "extra-font") && strings.EqualFold(filepath.Ext(file), ".json"):
+		data, err = w.addFontImage(dir, file, data)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", file, err)
+	}
+	return w.add(dir, file, data)
+}
+
+// addFontImage stores the image of a font descriptor and points the
+// descriptor at it
+func (w *demoWriter) addFontImage(dir, desc string, data []byte) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	var img string
+	if err := json.Unmarshal(fields["image"], &img); err != nil || img == "" {
+		return nil, errors.New("font descriptor names no image")
+	}
+	bundled, err := w.addReferenced(dir, desc, img)
+	if err != nil {
+		return nil, err
+	}
+	fields["image"], _ = json.Marshal(bundled)
+	return json.MarshalIndent(fields, "", "  ")
+}
+
+// addStampImages stores the images of a stamps file and points the file
+// at them
+func (w *demoWriter) addStampImages(dir, desc string, data []byte) ([]byte, error) {
+	var files map[string]string
+	if err := json.Unmarshal(data, &files); err != nil {
+		return nil, err
+	}
+	for name, img := range files {
+		bundled, err := w.addReferenced(dir, desc, img)
+		if err != nil {
+			return nil, err
+		}
+		files[name] = bundled
+	}
+	return json.MarshalIndent(files, "", "  ")
+}
+
+// addReferenced stores file, relative to the descriptor desc, in dir and
+// returns its name relative to the bundled descriptor
+func (w *demoWriter) addReferenced(dir, desc, file string) (string, error) {
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(filepath.Dir(desc), file)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	bundled, err := w.add(dir, file, data)
+	if err != nil {
+		return "", err
+	}
+	return path.Base(bundled), nil
+}
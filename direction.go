@@ -0,0 +1,54 @@
+package main
+
+// setRightToLeft switches the scroll direction. Right to left the text
+// is consumed from its end: the first slot holds the letter at the right
+// end of the line, the letters before it in the text follow to its left
+// and enter from the left, and a letter scrolls out when scrollX gets to
+// minus its width. On the switch the letter at the far end of the line,
+// far, ending farEnd along it, takes the first slot where it stands, and
+// every letter keeps the wave phase of its place in the text, so the line
+// turns round without a jump. A live feed only grows at its end, so it
+// always scrolls left to right.
+func (s *Scroller) setRightToLeft(rtl bool, far int, farEnd float64) {
+	if s.rtl == rtl || s.Feed != nil || far < 0 {
+		return
+	}
+	s.rtl = rtl
+	s.addi = far
+	s.scrollX -= (lineWidth - farEnd) * s.lineDir()
+}
+
+// textDir is the step from a token to the one consumed after it, -1
+// right to left
+func (s *Scroller) textDir() int {
+	if s.rtl {
+		return -1
+	}
+	return 1
+}
+
+// lineDir is the sign scrollX moves in as the text moves on, -1 right to
+// left
+func (s *Scroller) lineDir() float64 {
+	return float64(s.textDir())
+}
+
+// reverse turns the scroll around: the text runs back the way it came,
+// the letters moving the other way, and on from its other end. Reversing
+// again runs it forward.
+func (s *Scroller) reverse() {
+	s.dir = -s.dir
+}
+
+// enterCodeBack applies the reverse codes entering the screen on the
+// side the text runs back from, the tokens between the first letter and
+// the one at from
+func (s *Scroller) enterCodeBack(from int) {
+	tokens := s.tokens()
+	lo, hi := s.tokenAt(min(s.addi, from)), s.tokenAt(max(s.addi, from))
+	for k := lo + 1; k < min(hi, len(tokens)); k++ {
+		if t := tokens[k]; t.IsCode() && t.Code.Kind == 'V' {
+			s.reverse()
+		}
+	}
+}
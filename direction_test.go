@@ -0,0 +1,119 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+func newTestScroller(text string) *Scroller {
+	s := NewScroller(ebiten.NewImage(screenWidth, screenHeight), map[rune]*ebiten.Image{}, nil, nil, NewCamera(0, 0, false))
+	s.Forms = scrollForms
+	s.Text = text
+	return s
+}
+
+// letterXs returns where the letters laid out on the last update are,
+// by their index in the text
+func letterXs(s *Scroller) map[int]float64 {
+	xs := make(map[int]float64)
+	for _, p := range s.printPos {
+		if p.letter != "" {
+			xs[p.n] = p.x
+		}
+	}
+	return xs
+}
+
+func TestRightToLeftConsumesFromEnd(t *testing.T) {
+	s := newTestScroller("HELLO WORLD")
+	s.RightToLeft = true
+	s.Restart()
+	if want := len(s.Text) - 1; s.addi != want {
+		t.Fatalf("starts at %d, want the last letter %d", s.addi, want)
+	}
+	var got []int
+	for len(got) < 12 {
+		addi := s.addi
+		s.Update()
+		if s.scrollX > 0 || s.scrollX <= -fontTileWidth {
+			t.Fatalf("scrollX %g outside (-%d, 0]", s.scrollX, fontTileWidth)
+		}
+		if s.addi != addi {
+			got = append(got, s.addi)
+		}
+	}
+	want := []int{9, 8, 7, 6, 5, 4, 3, 2, 1, 0, 10, 9}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("letters taken %v, want %v", got, want)
+		}
+	}
+}
+
+func TestRightToLeftSwitchInPlace(t *testing.T) {
+	s := newTestScroller(strings.Repeat("ABCDEFGHIJ", 6) + "^R" + strings.Repeat("KLMNOPQRST", 6))
+	s.Restart()
+	prev := map[int]float64{}
+	for u := 0; u < 1000; u++ {
+		s.Update()
+		xs := letterXs(s)
+		// Letters move a few pixels an update, the wave and the depth
+		// included, never across the line
+		for n, x := range xs {
+			if px, ok := prev[n]; ok && math.Abs(x-px) > 3*s.Speed {
+				t.Fatalf("update %d, right to left %v: letter %d jumped from %g to %g", u, s.rtl, n, px, x)
+			}
+		}
+		prev = xs
+	}
+	if !s.rtl {
+		t.Fatal("^R did not switch to right to left")
+	}
+}
+
+func TestEntranceGrowsAtEnteringSide(t *testing.T) {
+	for _, rtl := range []bool{false, true} {
+		s := newTestScroller(strings.Repeat("ABCDEFGHIJ", 10))
+		s.Entrance = 2
+		s.RightToLeft = rtl
+		s.Restart()
+		grown := map[int]float64{}
+		for u := 0; u < 200; u++ {
+			s.Update()
+			for _, p := range s.printPos {
+				if p.letter == "" {
+					continue
+				}
+				if p.grow < grown[p.n] {
+					t.Fatalf("rtl %v: letter %d shrank from %g to %g", rtl, p.n, grown[p.n], p.grow)
+				}
+				grown[p.n] = p.grow
+			}
+		}
+		// The letters growing in are all beyond the whole ones, on the
+		// side the line enters from
+		var entering, whole []PrintPos
+		for _, p := range s.printPos {
+			switch {
+			case p.letter == "":
+			case p.grow < 1:
+				entering = append(entering, p)
+			default:
+				whole = append(whole, p)
+			}
+		}
+		for _, e := range entering {
+			for _, p := range whole {
+				if (e.x < p.x) != rtl {
+					t.Fatalf("rtl %v: letter %d entering at x %g behind whole letter %d at %g", rtl, e.n, e.x, p.n, p.x)
+				}
+			}
+		}
+		if len(entering) == 0 || len(entering) > 3 || len(whole) == 0 {
+			t.Fatalf("rtl %v: %d letters entering and %d whole", rtl, len(entering), len(whole))
+		}
+	}
+}
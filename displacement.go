@@ -0,0 +1,216 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"image"
+	"math"
+	"math/rand"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"tcb-multi-plane-3d-scroller/easings"
+)
+
+// Displacement shader source
+//
+//go:embed shaders/displacement.kage
+var displacementShaderSrc []byte
+
+// Displacer warps images through a displacement map on the GPU
+type Displacer struct {
+	shader *ebiten.Shader
+}
+
+// NewDisplacer compiles the displacement shader
+func NewDisplacer() (*Displacer, error) {
+	shader, err := ebiten.NewShader(displacementShaderSrc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile displacement shader: %w", err)
+	}
+	return &Displacer{shader: shader}, nil
+}
+
+// Apply draws src into dst at (x, y), moving each pixel by up to
+// amountX/amountY pixels according to dmap. src and dmap must have the
+// same size.
+func (d *Displacer) Apply(dst, src, dmap *ebiten.Image, x, y, amountX, amountY float64) {
+	b := src.Bounds()
+	op := &ebiten.DrawRectShaderOptions{}
+	op.GeoM.Translate(x, y)
+	op.Images[0] = src
+	op.Images[1] = dmap
+	op.Uniforms = map[string]any{
+		"Amount": []float32{float32(amountX), float32(amountY)},
+	}
+	op.Blend = ebiten.BlendCopy
+	dst.DrawRectShader(b.Dx(), b.Dy(), d.shader, op)
+}
+
+// DisplacementEffect warps a region of a canvas with a tileable
+// displacement texture scrolling over it
+type DisplacementEffect struct {
+	displacer *Displacer
+	region    image.Rectangle
+	texture   *ebiten.Image
+	mask      *ebiten.Image
+	dmap      *ebiten.Image
+	scratch   *ebiten.Image
+
+	// Texture scroll in pixels per frame
+	ScrollX, ScrollY float64
+	offX, offY       float64
+
+	// Maximum displacement in pixels
+	AmountX, AmountY float64
+}
+
+// NewDisplacementEffect creates an effect warping region with texture.
+// mask is optional and is drawn over the map to fade the effect out.
+func NewDisplacementEffect(displacer *Displacer, region image.Rectangle, texture, mask *ebiten.Image) *DisplacementEffect {
+	return &DisplacementEffect{
+		displacer: displacer,
+		region:    region,
+		texture:   texture,
+		mask:      mask,
+		dmap:      ebiten.NewImage(region.Dx(), region.Dy()),
+		scratch:   ebiten.NewImage(region.Dx(), region.Dy()),
+	}
+}
+
+// Update scrolls the texture and rebuilds the displacement map
+func (d *DisplacementEffect) Update() {
+	tw := float64(d.texture.Bounds().Dx())
+	th := float64(d.texture.Bounds().Dy())
+	d.offX = math.Mod(d.offX+d.ScrollX, tw)
+	d.offY = math.Mod(d.offY+d.ScrollY, th)
+	if d.offX > 0 {
+		d.offX -= tw
+	}
+	if d.offY > 0 {
+		d.offY -= th
+	}
+
+	// Tile the texture over the map, starting just off the top-left edge
+	w := float64(d.region.Dx())
+	h := float64(d.region.Dy())
+	for y := d.offY; y < h; y += th {
+		for x := d.offX; x < w; x += tw {
+			op := &ebiten.DrawImageOptions{}
+			op.GeoM.Translate(x, y)
+			op.Blend = ebiten.BlendCopy
+			d.dmap.DrawImage(d.texture, op)
+		}
+	}
+
+	if d.mask != nil {
+		d.dmap.DrawImage(d.mask, nil)
+	}
+}
+
+// Apply warps the effect region of the canvas in place
+func (d *DisplacementEffect) Apply(canvas *ebiten.Image) {
+	if d.AmountX == 0 && d.AmountY == 0 {
+		return
+	}
+
+	src := canvas.SubImage(d.region).(*ebiten.Image)
+	d.displacer.Apply(d.scratch, src, d.dmap, 0, 0, d.AmountX, d.AmountY)
+
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(float64(d.region.Min.X), float64(d.region.Min.Y))
+	op.Blend = ebiten.BlendCopy
+	canvas.DrawImage(d.scratch, op)
+}
+
+// encodeDisplacement packs offsets in [-1,1] into map channels
+func encodeDisplacement(pix []byte, i int, dx, dy float64) {
+	pix[i] = byte(127.5 + math.Max(-1, math.Min(1, dx))*127.5)
+	pix[i+1] = byte(127.5 + math.Max(-1, math.Min(1, dy))*127.5)
+	pix[i+2] = 0
+	pix[i+3] = 0xff
+}
+
+// NewNoiseDisplacementTexture builds a tileable value-noise map of the
+// given size from rnd. cell is the noise feature size in pixels.
+func NewNoiseDisplacementTexture(w, h int, cell float64, rnd *rand.Rand) *ebiten.Image {
+	gw := int(math.Ceil(float64(w) / cell))
+	gh := int(math.Ceil(float64(h) / cell))
+	gridX := make([]float64, gw*gh)
+	gridY := make([]float64, gw*gh)
+	for i := range gridX {
+		gridX[i] = rnd.Float64()*2 - 1
+		gridY[i] = rnd.Float64()*2 - 1
+	}
+
+	lerp := func(grid []float64, x, y float64) float64 {
+		x0, y0 := int(x), int(y)
+		fx, fy := easings.Smooth(x-float64(x0)), easings.Smooth(y-float64(y0))
+		x0 %= gw
+		y0 %= gh
+		x1, y1 := (x0+1)%gw, (y0+1)%gh
+		top := grid[y0*gw+x0] + (grid[y0*gw+x1]-grid[y0*gw+x0])*fx
+		bottom := grid[y1*gw+x0] + (grid[y1*gw+x1]-grid[y1*gw+x0])*fx
+		return top + (bottom-top)*fy
+	}
+
+	pix := make([]byte, w*h*4)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			nx := float64(x) / cell
+			ny := float64(y) / cell
+			encodeDisplacement(pix, (y*w+x)*4, lerp(gridX, nx, ny), lerp(gridY, nx, ny))
+		}
+	}
+
+	img := ebiten.NewImage(w, h)
+	img.WritePixels(pix)
+	return img
+}
+
+// NewSineDisplacementTexture builds a map whose horizontal offset follows
+// a sine of the row and whose vertical offset follows a sine of the
+// column. A zero period disables that axis.
+func NewSineDisplacementTexture(w, h int, periodY, periodX float64) *ebiten.Image {
+	pix := make([]byte, w*h*4)
+	for y := 0; y < h; y++ {
+		dx := 0.0
+		if periodY != 0 {
+			dx = math.Sin(2 * math.Pi * float64(y) / periodY)
+		}
+		for x := 0; x < w; x++ {
+			dy := 0.0
+			if periodX != 0 {
+				dy = math.Sin(2 * math.Pi * float64(x) / periodX)
+			}
+			encodeDisplacement(pix, (y*w+x)*4, dx, dy)
+		}
+	}
+
+	img := ebiten.NewImage(w, h)
+	img.WritePixels(pix)
+	return img
+}
+
+// NewDisplacementFadeMask builds an overlay pulling the map back to "no
+// offset" where strength(y) is 0 and leaving it untouched where it is 1
+func NewDisplacementFadeMask(w, h int, strength func(y int) float64) *ebiten.Image {
+	pix := make([]byte, w*h*4)
+	for y := 0; y < h; y++ {
+		a := 1 - math.Max(0, math.Min(1, strength(y)))
+		// Premultiplied neutral grey
+		v := byte(127.5 * a)
+		alpha := byte(255 * a)
+		for x := 0; x < w; x++ {
+			i := (y*w + x) * 4
+			pix[i] = v
+			pix[i+1] = v
+			pix[i+2] = 0
+			pix[i+3] = alpha
+		}
+	}
+
+	img := ebiten.NewImage(w, h)
+	img.WritePixels(pix)
+	return img
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"log"
+	"os"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// DrawOp is one draw operation of a frame: which layer drew which part
+// of which image, where and how it was blended
+type DrawOp struct {
+	Layer string     `json:"layer"`
+	Image string     `json:"image"`
+	Src   [4]int     `json:"src"`  // x0, y0, x1, y1 in the source image
+	GeoM  [6]float64 `json:"geom"` // a, b, c, d, tx, ty
+	Blend string     `json:"blend"`
+	// Color scales the source, left out when it doesn't
+	Color *[4]float32 `json:"color,omitempty"`
+	// Quad gives the corners a letter drawn in perspective is stretched
+	// onto, top left, top right, bottom left and bottom right; GeoM is
+	// then its flat transform
+	Quad *quad `json:"quad,omitempty"`
+}
+
+// DrawList records the draw operations of one frame, so tools can
+// compare how frames are composed rather than only their pixels
+type DrawList struct {
+	Frame uint64   `json:"frame"`
+	Ops   []DrawOp `json:"ops"`
+}
+
+// add records drawing src of the image named img with op, nil for the
+// default options. Recording into a nil list does nothing.
+func (d *DrawList) add(layer, img string, src image.Rectangle, op *ebiten.DrawImageOptions) {
+	if d == nil {
+		return
+	}
+	if op == nil {
+		op = &ebiten.DrawImageOptions{}
+	}
+	d.addGeoM(layer, img, src, op.GeoM, blendName(op), op.ColorScale)
+}
+
+// addGeoM records drawing src of the image named img transformed by
+// geoM
+func (d *DrawList) addGeoM(layer, img string, src image.Rectangle, geoM ebiten.GeoM, blend string, scale ebiten.ColorScale) {
+	if d == nil {
+		return
+	}
+	o := DrawOp{
+		Layer: layer,
+		Image: img,
+		Src:   [4]int{src.Min.X, src.Min.Y, src.Max.X, src.Max.Y},
+		GeoM: [6]float64{
+			geoM.Element(0, 0), geoM.Element(1, 0), geoM.Element(0, 1), geoM.Element(1, 1),
+			geoM.Element(0, 2), geoM.Element(1, 2),
+		},
+		Blend: blend,
+	}
+	if scale != (ebiten.ColorScale{}) {
+		o.Color = &[4]float32{scale.R(), scale.G(), scale.B(), scale.A()}
+	}
+	d.Ops = append(d.Ops, o)
+}
+
+// addQuad records drawing src of the image named img stretched onto q,
+// geoM being its flat transform
+func (d *DrawList) addQuad(layer, img string, src image.Rectangle, geoM ebiten.GeoM, q quad, scale ebiten.ColorScale) {
+	if d == nil {
+		return
+	}
+	d.addGeoM(layer, img, src, geoM, "source-over", scale)
+	d.Ops[len(d.Ops)-1].Quad = &q
+}
+
+// addEffects records the effects of stage applied to the canvas of layer
+func (d *DrawList) addEffects(layer string, r *EffectRegistry, stage EffectStage) {
+	if d == nil {
+		return
+	}
+	for _, name := range r.Active(stage) {
+		d.Ops = append(d.Ops, DrawOp{Layer: layer, Image: name, Blend: "shader"})
+	}
+}
+
+// blendName names the blending of op
+func blendName(op *ebiten.DrawImageOptions) string {
+	switch {
+	case op.CompositeMode == ebiten.CompositeModeSourceAtop, op.Blend == ebiten.BlendSourceAtop:
+		return "source-atop"
+	case op.Blend == ebiten.BlendCopy:
+		return "copy"
+	case op.Blend == ebiten.BlendLighter:
+		return "lighter"
+	case op.Blend == shadeBlend:
+		return "multiply"
+	}
+	return "source-over"
+}
+
+// requestDrawList records the next frame drawn and writes it to a JSON
+// file
+func (g *Game) requestDrawList() {
+	g.drawListPending = true
+}
+
+// startDrawList starts recording the frame being drawn when asked to
+func (g *Game) startDrawList() {
+	if !g.drawListPending {
+		return
+	}
+	g.drawListPending = false
+	g.drawList = &DrawList{Frame: g.frame}
+	for _, s := range g.allScrollers() {
+		s.DrawList = g.drawList
+	}
+}
+
+// finishDrawList writes the frame recorded, if any
+func (g *Game) finishDrawList() {
+	if g.drawList == nil {
+		return
+	}
+	d := g.drawList
+	g.drawList = nil
+	for _, s := range g.allScrollers() {
+		s.DrawList = nil
+	}
+
+	path := fmt.Sprintf("drawlist-%06d.json", d.Frame)
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err == nil {
+		err = os.WriteFile(path, data, 0o644)
+	}
+	if err != nil {
+		log.Printf("Failed to write the draw list: %v", err)
+		g.notice.Show("Failed to write the draw list")
+		return
+	}
+	g.notice.Show(fmt.Sprintf("Draw list of %d operations saved to %s", len(d.Ops), path))
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"tcb-multi-plane-3d-scroller/hooks"
+)
+
+// DrawPath selects how the scrollers draw their letters
+type DrawPath int
+
+const (
+	// DrawPathAuto times both paths at startup and keeps the faster
+	DrawPathAuto DrawPath = iota
+	// DrawPathTiles draws every letter with its own DrawImage call
+	DrawPathTiles
+	// DrawPathBatched draws the letters of a pass with one
+	// DrawTriangles call from an atlas of the font
+	DrawPathBatched
+)
+
+var drawPathNames = []string{"auto", "tiles", "batched"}
+
+func (p DrawPath) String() string {
+	if p < DrawPathAuto || p > DrawPathBatched {
+		return "unknown"
+	}
+	return drawPathNames[p]
+}
+
+// Set implements flag.Value
+func (p *DrawPath) Set(s string) error {
+	for i, name := range drawPathNames {
+		if strings.EqualFold(s, name) {
+			*p = DrawPath(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown draw path %q", s)
+}
+
+// drawBenchFrames is how many frames of letters each path draws when
+// they are timed
+const drawBenchFrames = 30
+
+// DrawPathChoice is the draw path in use and how it was picked. Some
+// drivers favor many small draws, others a single large one.
+type DrawPathChoice struct {
+	Path    DrawPath      // never auto once chosen
+	Tiles   time.Duration // a frame of letters on each path, 0 when not timed
+	Batched time.Duration
+	chosen  bool
+}
+
+// updateDrawPath picks the draw path on the first update, when the
+// GPU can be waited for
+func (g *Game) updateDrawPath() {
+	c := &g.drawPath
+	if c.chosen {
+		return
+	}
+	c.chosen = true
+
+	c.Path = g.cfg.DrawPath
+	if c.Path == DrawPathAuto {
+		c.Tiles, c.Batched = benchmarkDrawPaths(g.scroller)
+		c.Path = DrawPathTiles
+		if c.Batched < c.Tiles {
+			c.Path = DrawPathBatched
+		}
+		log.Printf("Drawing letters with %s (tiles %v, batched %v a frame)", c.Path, c.Tiles, c.Batched)
+	}
+
+	g.scroller.Batched = c.Path == DrawPathBatched
+	if g.chat != nil {
+		g.chat.Batched = g.scroller.Batched
+	}
+	for _, s := range g.planes {
+		s.Batched = g.scroller.Batched
+	}
+}
+
+// benchmarkDrawPaths times a screen of letters drawn by s on each path
+func benchmarkDrawPaths(s *Scroller) (tiles, batched time.Duration) {
+	canvas := ebiten.NewImage(s.canvas.Bounds().Dx(), s.canvas.Bounds().Dy())
+	defer canvas.Deallocate()
+
+	letters := make([]hooks.Letter, scrollLetters)
+	for i := range letters {
+		letters[i] = hooks.Letter{
+			Char:  byte('A' + i%26),
+			X:     float64(16 + i*10),
+			Y:     100 + 40*math.Sin(float64(i)/3),
+			Scale: 0.5 + float64(i%4)/4,
+		}
+	}
+
+	run := func(batch bool) time.Duration {
+		saved, savedBatched := s.canvas, s.Batched
+		s.canvas, s.Batched = canvas, batch
+		defer func() { s.canvas, s.Batched = saved, savedBatched }()
+
+		frame := func() {
+			canvas.Clear()
+			for i := range letters {
+				s.drawLetter(&letters[i], 0)
+			}
+			s.flushLetters()
+		}
+		// Warm up, then wait for the GPU before and after timing
+		frame()
+		canvas.At(0, 0)
+		start := time.Now()
+		for range drawBenchFrames {
+			frame()
+		}
+		canvas.At(0, 0)
+		return time.Since(start) / drawBenchFrames
+	}
+	return run(false), run(true)
+}
+
+// DebugOverlay shows how the demo runs: rates and the draw path
+type DebugOverlay struct {
+	visible bool
+}
+
+// Toggle shows or hides the overlay
+func (d *DebugOverlay) Toggle() {
+	d.visible = !d.visible
+}
+
+// drawDebug draws the debug overlay in the top left corner
+func (g *Game) drawDebug(screen *ebiten.Image) {
+	if !g.debug.visible {
+		return
+	}
+
+	c := g.drawPath
+	path := fmt.Sprintf("LETTERS  %s (-draw-path %s)", c.Path, g.cfg.DrawPath)
+	lines := []string{
+		fmt.Sprintf("FPS %.1f  TPS %.1f", ebiten.ActualFPS(), ebiten.ActualTPS()),
+		path,
+		fmt.Sprintf("FONT     %s, %s", g.fontName, fontCapsOf(g.fontTiles)),
+	}
+	if c.Tiles > 0 {
+		lines = append(lines, fmt.Sprintf("BENCH    tiles %v, batched %v a frame", c.Tiles, c.Batched))
+	}
+	if g.pacing != nil {
+		lines = append(lines, g.pacing.Summary())
+	}
+
+	// Debug font glyphs are 6x16
+	w := 0
+	for _, l := range lines {
+		w = max(w, len(l)*6+16)
+	}
+	vector.DrawFilledRect(screen, 8, 8, float32(w), float32(len(lines)*16+8), color.RGBA{0, 0, 0, 0xc0}, false)
+	for i, l := range lines {
+		ebitenutil.DebugPrintAt(screen, l, 16, 12+i*16)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// maxDumpSeconds stops tunes that never end
+const maxDumpSeconds = 30 * 60
+
+// dumpAudio renders the music once through, without looping, into a
+// stereo WAV file in the -dump-audio-format
+func dumpAudio(cfg *Config, path string) error {
+	music, err := NewMusicSource(cfg.Music, cfg.AudioRate, false)
+	if err != nil {
+		return err
+	}
+	defer music.Close()
+	if y, ok := music.(*YMPlayer); ok {
+		y.SetStereo(cfg.YMStereo)
+		y.SetLowPass(cfg.LowPass)
+	}
+	if cfg.Subsong > 1 {
+		if err := music.SetSubsong(cfg.Subsong - 1); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create WAV file: %w", err)
+	}
+	defer f.Close()
+
+	// The sizes are written once the data is known
+	format := cfg.DumpAudioFormat
+	w := bufio.NewWriter(f)
+	if err := writeWAVHeader(w, cfg.AudioRate, format, 0); err != nil {
+		return fmt.Errorf("failed to write WAV file: %w", err)
+	}
+	var size int64
+	samples := NewPCMReader(music, format)
+	if y, ok := music.(*YMPlayer); ok {
+		size, err = y.RenderTo(w, maxDumpSeconds*time.Second)
+	} else {
+		limit := int64(maxDumpSeconds) * int64(cfg.AudioRate) * int64(format.FrameSize())
+		size, err = io.Copy(w, io.LimitReader(samples, limit))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to render music: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write WAV file: %w", err)
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to write WAV file: %w", err)
+	}
+	if err := writeWAVHeader(f, cfg.AudioRate, format, uint32(size)); err != nil {
+		return fmt.Errorf("failed to write WAV file: %w", err)
+	}
+	return nil
+}
+
+// writeWAVHeader writes the header of a stereo WAV file with dataSize
+// bytes of samples in format
+func writeWAVHeader(w io.Writer, sampleRate int, format PCMFormat, dataSize uint32) error {
+	const channels = 2
+	bits := format.SampleSize() * 8
+	tag := uint16(1) // integer PCM
+	if format == PCMFloat32 {
+		tag = 3 // IEEE float
+	}
+	header := []any{
+		[4]byte{'R', 'I', 'F', 'F'}, 36 + dataSize, [4]byte{'W', 'A', 'V', 'E'},
+		[4]byte{'f', 'm', 't', ' '}, uint32(16), tag, uint16(channels),
+		uint32(sampleRate), uint32(sampleRate * channels * bits / 8),
+		uint16(channels * bits / 8), uint16(bits),
+		[4]byte{'d', 'a', 't', 'a'}, dataSize,
+	}
+	for _, v := range header {
+		if err := binary.Write(w, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+// Package easings holds the curves the demo animates its parameters
+// along, so the morphs, transitions and fades share one definition of
+// each. A curve maps the time t of an animation, from 0 to 1, to how far
+// it got, 0 at the start and 1 at the end.
+package easings
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Func is an easing curve
+type Func func(t float64) float64
+
+// Linear moves at a constant rate
+func Linear(t float64) float64 {
+	return t
+}
+
+// Smooth eases in and out with the smoothstep polynomial
+func Smooth(t float64) float64 {
+	return t * t * (3 - 2*t)
+}
+
+// InSine starts slowly along a quarter sine
+func InSine(t float64) float64 {
+	return 1 - math.Cos(t*math.Pi/2)
+}
+
+// OutSine ends slowly along a quarter sine
+func OutSine(t float64) float64 {
+	return math.Sin(t * math.Pi / 2)
+}
+
+// InOutSine eases in and out along half a cosine
+func InOutSine(t float64) float64 {
+	return (1 - math.Cos(t*math.Pi)) / 2
+}
+
+// InExpo starts very slowly and shoots to the end
+func InExpo(t float64) float64 {
+	if t <= 0 {
+		return 0
+	}
+	return math.Pow(2, 10*t-10)
+}
+
+// OutExpo shoots from the start and settles slowly
+func OutExpo(t float64) float64 {
+	if t >= 1 {
+		return 1
+	}
+	return 1 - math.Pow(2, -10*t)
+}
+
+// InOutExpo is InExpo then OutExpo
+func InOutExpo(t float64) float64 {
+	if t < 0.5 {
+		return InExpo(2*t) / 2
+	}
+	return (1 + OutExpo(2*t-1)) / 2
+}
+
+// OutBounce falls to the end and bounces on it three times
+func OutBounce(t float64) float64 {
+	const n, d = 7.5625, 2.75
+	switch {
+	case t < 1/d:
+		return n * t * t
+	case t < 2/d:
+		t -= 1.5 / d
+		return n*t*t + 0.75
+	case t < 2.5/d:
+		t -= 2.25 / d
+		return n*t*t + 0.9375
+	}
+	t -= 2.625 / d
+	return n*t*t + 0.984375
+}
+
+// InBounce bounces off the start before leaving it
+func InBounce(t float64) float64 {
+	return 1 - OutBounce(1-t)
+}
+
+// InOutBounce is InBounce then OutBounce
+func InOutBounce(t float64) float64 {
+	if t < 0.5 {
+		return InBounce(2*t) / 2
+	}
+	return (1 + OutBounce(2*t-1)) / 2
+}
+
+// Bezier returns the cubic Bézier curve from (0, 0) to (1, 1) with the
+// control points (x1, y1) and (x2, y2), as CSS cubic-bezier(). x1 and x2
+// must be within [0, 1] for the curve to be a function of t.
+func Bezier(x1, y1, x2, y2 float64) Func {
+	// Coordinate of the curve at parameter s, and its slope
+	at := func(a, b, s float64) float64 {
+		return ((1-3*b+3*a)*s+3*b-6*a)*s*s + 3*a*s
+	}
+	slope := func(a, b, s float64) float64 {
+		return 3*(1-3*b+3*a)*s*s + 2*(3*b-6*a)*s + 3*a
+	}
+	return func(t float64) float64 {
+		// Newton's method finds the parameter of x = t, bisection
+		// takes over where the curve is too flat for it
+		s := t
+		for range 8 {
+			d := slope(x1, x2, s)
+			if math.Abs(d) < 1e-6 {
+				break
+			}
+			s -= (at(x1, x2, s) - t) / d
+		}
+		if math.Abs(at(x1, x2, s)-t) > 1e-6 || s < 0 || s > 1 {
+			lo, hi := 0.0, 1.0
+			for range 40 {
+				s = (lo + hi) / 2
+				if at(x1, x2, s) < t {
+					lo = s
+				} else {
+					hi = s
+				}
+			}
+		}
+		return at(y1, y2, s)
+	}
+}
+
+// Mirror runs f forward over the first half of the time and back over
+// the second, for envelopes that swell and die
+func Mirror(f Func) Func {
+	return func(t float64) float64 {
+		return f(1 - math.Abs(2*t-1))
+	}
+}
+
+// Lerp returns the value t of the way from a to b
+func Lerp(a, b, t float64) float64 {
+	return a + (b-a)*t
+}
+
+// Names are the curves a Curve can be set to by name, besides
+// bezier(x1,y1,x2,y2)
+var Names = []string{
+	"linear", "smooth",
+	"in-sine", "out-sine", "in-out-sine",
+	"in-expo", "out-expo", "in-out-expo",
+	"in-bounce", "out-bounce", "in-out-bounce",
+}
+
+var byName = map[string]Func{
+	"linear":        Linear,
+	"smooth":        Smooth,
+	"in-sine":       InSine,
+	"out-sine":      OutSine,
+	"in-out-sine":   InOutSine,
+	"in-expo":       InExpo,
+	"out-expo":      OutExpo,
+	"in-out-expo":   InOutExpo,
+	"in-bounce":     InBounce,
+	"out-bounce":    OutBounce,
+	"in-out-bounce": InOutBounce,
+}
+
+// Curve is an easing curve chosen by name, settable from the command
+// line. The zero Curve is linear.
+type Curve struct {
+	name string
+	fn   Func
+}
+
+// Parse returns the curve named s: one of Names, or
+// bezier(x1,y1,x2,y2)
+func Parse(s string) (Curve, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if fn, ok := byName[s]; ok {
+		return Curve{s, fn}, nil
+	}
+	var x1, y1, x2, y2 float64
+	if _, err := fmt.Sscanf(strings.ReplaceAll(s, " ", ""), "bezier(%g,%g,%g,%g)", &x1, &y1, &x2, &y2); err == nil {
+		if x1 < 0 || x1 > 1 || x2 < 0 || x2 > 1 {
+			return Curve{}, fmt.Errorf("bezier x coordinates must be within 0 and 1 in %q", s)
+		}
+		return Curve{fmt.Sprintf("bezier(%g,%g,%g,%g)", x1, y1, x2, y2), Bezier(x1, y1, x2, y2)}, nil
+	}
+	return Curve{}, fmt.Errorf("unknown easing curve %q (want %s or bezier(x1,y1,x2,y2))", s, strings.Join(Names, ", "))
+}
+
+// MustParse is Parse for the curves known to be valid
+func MustParse(s string) Curve {
+	c, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// At returns how far the curve got at time t, clamped to [0, 1]
+func (c Curve) At(t float64) float64 {
+	t = min(max(t, 0), 1)
+	if c.fn == nil {
+		return t
+	}
+	return c.fn(t)
+}
+
+func (c Curve) String() string {
+	if c.name == "" {
+		return "linear"
+	}
+	return c.name
+}
+
+// Set implements flag.Value
+func (c *Curve) Set(s string) error {
+	parsed, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
+
+// MarshalText writes the curve by its name, for JSON files
+func (c Curve) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText reads a curve written by MarshalText
+func (c *Curve) UnmarshalText(text []byte) error {
+	return c.Set(string(text))
+}
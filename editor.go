@@ -0,0 +1,128 @@
+package main
+
+import (
+	"image/color"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// Key repeat of backspace in the editor, in updates
+const (
+	editorRepeatDelay = 30
+	editorRepeatEvery = 4
+)
+
+// editorShown is how many characters of the text end the editor shows
+const editorShown = 100
+
+// timeRun is a %TIME% field expanded in the text
+var timeRun = regexp.MustCompile(string(timeMarker) + "+")
+
+// TextEditor types into the end of the scroll text while the demo runs,
+// ahead of its trailing blank screen, for preparing party versions
+// without a rebuild
+type TextEditor struct {
+	active  bool
+	body    string // the text up to its trailing blank screen
+	padding string
+}
+
+// openEditor starts typing into the scroll text
+func (g *Game) openEditor() {
+	if g.scroller.Feed != nil {
+		g.notice.Show("The scroll text comes from a feed")
+		return
+	}
+	e := &g.editor
+	e.body = strings.TrimRight(g.baseText, " ")
+	e.padding = g.baseText[len(e.body):]
+	e.active = true
+}
+
+// updateEditor types the characters pressed into the text, removes the
+// last one with backspace, saves with Ctrl+S and closes with Esc
+func (g *Game) updateEditor() {
+	e := &g.editor
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		e.active = false
+		return
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyControl) {
+		if inpututil.IsKeyJustPressed(ebiten.KeyS) {
+			g.saveScrollText()
+		}
+		return
+	}
+
+	body := e.body
+	for _, r := range ebiten.AppendInputChars(nil) {
+		for _, c := range fontCase(transliterate(string(r))) {
+			if strings.ContainsRune(codeChars, c) || (c < utf8.RuneSelf && strings.ContainsRune(fontChars, c)) {
+				body += string(c)
+			}
+		}
+	}
+	if d := inpututil.KeyPressDuration(ebiten.KeyBackspace); d == 1 ||
+		(d > editorRepeatDelay && d%editorRepeatEvery == 0) {
+		body = body[:max(len(body)-1, 0)]
+	}
+	if body == e.body {
+		return
+	}
+
+	// The scroller follows at once
+	e.body = body
+	g.baseText = e.body + e.padding
+	g.rebuildText()
+	g.scroller.addi = min(g.scroller.addi, len(g.scroller.Text))
+}
+
+// saveScrollText writes the text typed to the -scrolltext file, or the
+// default one
+func (g *Game) saveScrollText() {
+	path := g.cfg.ScrollText
+	if path == "" {
+		path = defaultScrollTextFile
+	}
+	// Times shown are saved as the field they came from
+	text := strings.TrimSpace(g.editor.body) + "\n"
+	text = timeRun.ReplaceAllString(text, timeField)
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		log.Printf("Failed to save the scroll text: %v", err)
+		g.notice.Show("Failed to save the scroll text")
+		return
+	}
+	g.notice.Show("Scroll text saved to " + path)
+}
+
+// drawEditor shows the end of the text being typed at the bottom of the
+// screen
+func (g *Game) drawEditor(screen *ebiten.Image) {
+	e := &g.editor
+	if !e.active {
+		return
+	}
+
+	shown := e.body
+	if len(shown) > editorShown {
+		shown = "..." + shown[len(shown)-editorShown:]
+	}
+	cursor := " "
+	if g.frame/30%2 == 0 {
+		cursor = "_"
+	}
+
+	// Debug font glyphs are 6x16
+	y := screenHeight - 56
+	vector.DrawFilledRect(screen, 16, float32(y), screenWidth-32, 48, color.RGBA{0, 0, 0, 0xc0}, false)
+	ebitenutil.DebugPrintAt(screen, "EDIT SCROLL TEXT  (Backspace to erase, Ctrl+S to save, Esc to close)", 24, y+4)
+	ebitenutil.DebugPrintAt(screen, shown+cursor, 24, y+24)
+}
@@ -0,0 +1,148 @@
+package main
+
+import "github.com/hajimehoshi/ebiten/v2"
+
+// EffectStage selects the canvas an effect works on
+type EffectStage int
+
+const (
+	// StageLandscape runs on the parallax landscape before compositing
+	StageLandscape EffectStage = iota
+	// StageScroller runs on the logo plane before the scroller is drawn
+	// over it
+	StageScroller
+	// StageScreen runs on the final composited frame
+	StageScreen
+)
+
+// Effect is a pass that modifies a canvas in place
+type Effect interface {
+	Update()
+	Apply(canvas *ebiten.Image)
+}
+
+type registeredEffect struct {
+	name    string
+	stage   EffectStage
+	effect  Effect
+	enabled bool
+}
+
+// EffectRegistry holds the named effects in application order
+type EffectRegistry struct {
+	// Bypass skips every effect while set, keeping which are enabled
+	Bypass bool
+
+	effects  []*registeredEffect
+	triggers map[string]int
+}
+
+// NewEffectRegistry creates an empty registry
+func NewEffectRegistry() *EffectRegistry {
+	return &EffectRegistry{triggers: make(map[string]int)}
+}
+
+// Register adds an effect running at the given stage
+func (r *EffectRegistry) Register(name string, stage EffectStage, effect Effect, enabled bool) {
+	r.effects = append(r.effects, &registeredEffect{
+		name:    name,
+		stage:   stage,
+		effect:  effect,
+		enabled: enabled,
+	})
+}
+
+func (r *EffectRegistry) find(name string) *registeredEffect {
+	for _, e := range r.effects {
+		if e.name == name {
+			return e
+		}
+	}
+	return nil
+}
+
+// Lookup returns the effect registered under name, or nil
+func (r *EffectRegistry) Lookup(name string) Effect {
+	if e := r.find(name); e != nil {
+		return e.effect
+	}
+	return nil
+}
+
+// Enabled reports whether the named effect is active
+func (r *EffectRegistry) Enabled(name string) bool {
+	e := r.find(name)
+	return e != nil && e.enabled
+}
+
+// SetEnabled switches the named effect on or off
+func (r *EffectRegistry) SetEnabled(name string, enabled bool) {
+	if e := r.find(name); e != nil {
+		if enabled && !e.enabled {
+			r.triggers[name]++
+		}
+		e.enabled = enabled
+	}
+}
+
+// Toggle flips the named effect and returns its new state
+func (r *EffectRegistry) Toggle(name string) bool {
+	e := r.find(name)
+	if e == nil {
+		return false
+	}
+	e.enabled = !e.enabled
+	if e.enabled {
+		r.triggers[name]++
+	}
+	return e.enabled
+}
+
+// Trigger records that a one-shot effect (transition, ...) was started
+func (r *EffectRegistry) Trigger(name string) {
+	r.triggers[name]++
+}
+
+// Triggers returns how many times each effect was switched on or started
+func (r *EffectRegistry) Triggers() map[string]int {
+	out := make(map[string]int, len(r.triggers))
+	for name, n := range r.triggers {
+		out[name] = n
+	}
+	return out
+}
+
+// Update advances every enabled effect by one frame
+func (r *EffectRegistry) Update() {
+	for _, e := range r.effects {
+		if e.enabled {
+			e.effect.Update()
+		}
+	}
+}
+
+// Active returns the names of the effects Apply runs at stage
+func (r *EffectRegistry) Active(stage EffectStage) []string {
+	if r.Bypass {
+		return nil
+	}
+	var names []string
+	for _, e := range r.effects {
+		if e.enabled && e.stage == stage {
+			names = append(names, e.name)
+		}
+	}
+	return names
+}
+
+// Apply runs the enabled effects of a stage on the canvas
+func (r *EffectRegistry) Apply(stage EffectStage, canvas *ebiten.Image) {
+	if r.Bypass {
+		return
+	}
+	for _, e := range r.effects {
+		if e.enabled && e.stage == stage {
+			e.effect.Apply(canvas)
+		}
+	}
+}
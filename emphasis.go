@@ -0,0 +1,60 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"tcb-multi-plane-3d-scroller/hooks"
+)
+
+// The effects of the ^W codes on the word after them
+const (
+	emphasisNone = iota
+	// emphasisFlash cycles the letters through the raster colors
+	emphasisFlash
+	// emphasisPulse swells and shrinks the letters in a wave
+	emphasisPulse
+)
+
+const (
+	// flashSpeed is how many raster rows the flash moves per update,
+	// flashSpread how many apart two letters of the word are
+	flashSpeed  = 3
+	flashSpread = 12
+
+	// pulseSize is how much a pulsing letter grows at most, pulseSpeed
+	// and pulseSpread the wave in radians per update and per letter
+	pulseSize   = 0.3
+	pulseSpeed  = 0.25
+	pulseSpread = 0.7
+)
+
+// rasterColors returns the colors of the raster rows, down the left
+// column of img, which the flashing words cycle through
+func rasterColors(img image.Image) []color.RGBA {
+	b := img.Bounds()
+	colors := make([]color.RGBA, 0, b.Dy())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		colors = append(colors, color.RGBAModel.Convert(img.At(b.Min.X, y)).(color.RGBA))
+	}
+	return colors
+}
+
+// emphasize applies the ^W effect of the letter at p to l
+func (s *Scroller) emphasize(l *hooks.Letter, p *PrintPos) {
+	switch p.emphasis {
+	case emphasisFlash:
+		if len(s.FlashColors) == 0 {
+			return
+		}
+		i := (int(s.ticks)*flashSpeed + p.n*flashSpread) % len(s.FlashColors)
+		l.Color = ebiten.ColorScale{}
+		l.Color.ScaleWithColor(s.FlashColors[i])
+		l.NoRaster = true
+	case emphasisPulse:
+		l.Scale *= 1 + pulseSize*(0.5+0.5*math.Sin(float64(s.ticks)*pulseSpeed-float64(p.n)*pulseSpread))
+	}
+}
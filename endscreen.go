@@ -0,0 +1,66 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"tcb-multi-plane-3d-scroller/easings"
+)
+
+// End screen lettering
+const (
+	endTitle      = "THE END"
+	endTitleScale = 2
+	endHint       = "PRESS ESC TO QUIT"
+	endHintScale  = 0.375
+)
+
+// endFadeCurve darkens the screen slowly at first and quickly at the
+// end, as the music fading out sounds
+var endFadeCurve = easings.InSine
+
+// updateEnding fades the music out once it looped as often as asked, then
+// ends the demo. It reports whether the demo has ended and stands still.
+func (g *Game) updateEnding() bool {
+	switch {
+	case g.ended:
+		return true
+	case g.fadeFrames > 0:
+		g.fadeFrames--
+		if g.fadeFrames == 0 {
+			g.ended = true
+			if g.audioPlayer != nil {
+				g.audioPlayer.Pause()
+			}
+			g.startTransition()
+		}
+	case g.cfg.MusicLoops > 0 && g.music != nil && g.music.Loops() >= g.cfg.MusicLoops:
+		g.music.FadeOut(g.cfg.FadeOut)
+		g.fadeTotal = max(1, int(g.cfg.FadeOut.Seconds()*float64(ebiten.TPS())))
+		g.fadeFrames = g.fadeTotal
+	}
+	return false
+}
+
+// fadeLevel returns how bright the demo is while the music fades out
+func (g *Game) fadeLevel() float32 {
+	if g.fadeFrames == 0 {
+		return 1
+	}
+	return float32(1 - endFadeCurve(1-float64(g.fadeFrames)/float64(g.fadeTotal)))
+}
+
+// drawEndScreen renders the screen shown once the demo ended
+func (g *Game) drawEndScreen(canvas *ebiten.Image) {
+	if g.endCanvas == nil {
+		g.endCanvas = ebiten.NewImage(screenWidth, screenHeight)
+		f := g.bigfont
+		f.DrawString(g.endCanvas, endTitle, (screenWidth-f.Measure(endTitle, endTitleScale))/2,
+			screenHeight/2-f.LineHeight(endTitleScale), endTitleScale, nil)
+		f.DrawString(g.endCanvas, endHint, (screenWidth-f.Measure(endHint, endHintScale))/2,
+			screenHeight/2+48, endHintScale, nil)
+	}
+	canvas.Fill(color.Black)
+	canvas.DrawImage(g.endCanvas, nil)
+}
@@ -0,0 +1,15 @@
+package main
+
+// defaultEntranceCurve bounces the letters in, as dropped onto the line
+const defaultEntranceCurve = "out-bounce"
+
+// entrance returns the size a letter laid out at cursor, adv wide, has
+// grown to at the side the letters enter from: 0 at the edge of the
+// line, 1 once it is Entrance letters in, along EntranceCurve
+func (s *Scroller) entrance(cursor float64, adv letterAdvance) float64 {
+	if s.Entrance <= 0 {
+		return 1
+	}
+	in := lineWidth - cursor - adv.width + s.scrollX*s.lineDir()
+	return s.EntranceCurve.At(in / (s.Entrance * fontTileWidth))
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// DemoStatus is the event sent every second on the event stream
+type DemoStatus struct {
+	Scene      string  `json:"scene"`
+	MusicTime  float64 `json:"music_time"` // seconds played
+	TextPos    int     `json:"text_position"`
+	TextLength int     `json:"text_length"`
+	FPS        float64 `json:"fps"`
+}
+
+// EventStream broadcasts JSON events to WebSocket clients. Slow clients
+// miss events instead of holding up the demo.
+type EventStream struct {
+	mu      sync.Mutex
+	clients map[chan []byte]struct{}
+}
+
+// NewEventStream creates a stream without clients
+func NewEventStream() *EventStream {
+	return &EventStream{clients: make(map[chan []byte]struct{})}
+}
+
+// Publish sends v as JSON to every client
+func (e *EventStream) Publish(v any) {
+	msg, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("Failed to encode event: %v", err)
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for c := range e.clients {
+		select {
+		case c <- msg:
+		default:
+		}
+	}
+}
+
+// websocketGUID is the handshake constant of RFC 6455
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket opcodes
+const (
+	wsText  = 0x1
+	wsClose = 0x8
+	wsPing  = 0x9
+	wsPong  = 0xa
+)
+
+// ServeHTTP upgrades the request to a WebSocket and streams the events
+// until the client leaves
+func (e *EventStream) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	key := req.Header.Get("Sec-WebSocket-Key")
+	if !strings.EqualFold(req.Header.Get("Upgrade"), "websocket") || key == "" {
+		http.Error(w, "websocket upgrade required", http.StatusUpgradeRequired)
+		return
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket not supported", http.StatusInternalServerError)
+		return
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		log.Printf("Failed to upgrade event stream: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		return
+	}
+
+	c := make(chan []byte, 4)
+	e.mu.Lock()
+	e.clients[c] = struct{}{}
+	e.mu.Unlock()
+	defer func() {
+		e.mu.Lock()
+		delete(e.clients, c)
+		e.mu.Unlock()
+	}()
+
+	var wmu sync.Mutex
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		readFrames(rw.Reader, func(op byte, payload []byte) {
+			if op == wsPing {
+				wmu.Lock()
+				writeFrame(conn, wsPong, payload)
+				wmu.Unlock()
+			}
+		})
+	}()
+
+	for {
+		select {
+		case msg := <-c:
+			wmu.Lock()
+			err := writeFrame(conn, wsText, msg)
+			wmu.Unlock()
+			if err != nil {
+				return
+			}
+		case <-done:
+			wmu.Lock()
+			writeFrame(conn, wsClose, nil)
+			wmu.Unlock()
+			return
+		}
+	}
+}
+
+// readFrames reads client frames, passing control frames to fn, until
+// the client closes the connection or sends a close frame
+func readFrames(r *bufio.Reader, fn func(op byte, payload []byte)) {
+	var head [14]byte
+	for {
+		if _, err := io.ReadFull(r, head[:2]); err != nil {
+			return
+		}
+		op := head[0] & 0x0f
+		masked := head[1]&0x80 != 0
+		n := uint64(head[1] & 0x7f)
+		switch n {
+		case 126:
+			if _, err := io.ReadFull(r, head[:2]); err != nil {
+				return
+			}
+			n = uint64(binary.BigEndian.Uint16(head[:2]))
+		case 127:
+			if _, err := io.ReadFull(r, head[:8]); err != nil {
+				return
+			}
+			n = binary.BigEndian.Uint64(head[:8])
+		}
+		// Clients only send control frames and short messages here
+		if n > 4096 {
+			return
+		}
+		var mask [4]byte
+		if masked {
+			if _, err := io.ReadFull(r, mask[:]); err != nil {
+				return
+			}
+		}
+		payload := make([]byte, n)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return
+		}
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+		if op == wsClose {
+			return
+		}
+		fn(op, payload)
+	}
+}
+
+// writeFrame sends one unmasked frame, as servers do
+func writeFrame(conn net.Conn, op byte, payload []byte) error {
+	head := []byte{0x80 | op, 0}
+	switch n := len(payload); {
+	case n < 126:
+		head[1] = byte(n)
+	case n <= 0xffff:
+		head[1] = 126
+		head = binary.BigEndian.AppendUint16(head, uint16(n))
+	default:
+		head[1] = 127
+		head = binary.BigEndian.AppendUint64(head, uint64(n))
+	}
+	conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	_, err := conn.Write(append(head, payload...))
+	return err
+}
+
+// publishStatus sends the demo status on the event stream once a second
+func (g *Game) publishStatus() {
+	if g.events == nil || g.frame%uint64(ebiten.TPS()) != 0 {
+		return
+	}
+	st := DemoStatus{
+		Scene:      g.timeline.Current().Name(),
+		TextPos:    g.scroller.addi,
+		TextLength: len(g.scroller.Text),
+		FPS:        ebiten.ActualFPS(),
+	}
+	if g.music != nil {
+		st.MusicTime = g.music.Elapsed().Seconds()
+	}
+	g.events.Publish(st)
+}
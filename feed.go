@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// TextFeed collects lines arriving on a reader for the live scroller.
+// At most maxBytes of text wait in the queue. A reader is held back while
+// the queue is full, so a fast writer blocks on its pipe rather than
+// losing lines. Push drops the oldest lines to make room instead, so a
+// chat or the remote API never waits on the demo.
+type TextFeed struct {
+	// Filter cleans the lines read from the reader, nil for none
+	Filter *TextFilter
+
+	mu       sync.Mutex
+	room     *sync.Cond // signalled when a line leaves the queue
+	lines    []string
+	queued   int
+	maxBytes int
+	dropped  int
+}
+
+// NewTextFeed starts reading lines from r in the background, passing
+// them through filter
+func NewTextFeed(r io.Reader, maxBytes int, filter *TextFilter) *TextFeed {
+	f := NewTextQueue(maxBytes)
+	f.Filter = filter
+	go f.read(r)
+	return f
+}
+
+// NewTextQueue creates a feed filled with Push
+func NewTextQueue(maxBytes int) *TextFeed {
+	f := &TextFeed{maxBytes: max(1, maxBytes)}
+	f.room = sync.NewCond(&f.mu)
+	return f
+}
+
+func (f *TextFeed) read(r io.Reader) {
+	rd := bufio.NewReader(r)
+	for {
+		line, err := rd.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+		if f.Filter != nil {
+			line, _ = f.Filter.Apply(line)
+		}
+		if line != "" {
+			f.pushWait(line)
+		}
+		if err != nil {
+			if err != io.EOF {
+				log.Printf("Failed to read scroll text: %v", err)
+			}
+			return
+		}
+	}
+}
+
+// pushWait queues a line once there is room for it
+func (f *TextFeed) pushWait(line string) {
+	line = f.clip(line)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for f.queued > 0 && f.queued+len(line) > f.maxBytes {
+		f.room.Wait()
+	}
+	f.lines = append(f.lines, line)
+	f.queued += len(line)
+}
+
+// Push queues a line, dropping the oldest ones when the queue is full
+func (f *TextFeed) Push(line string) {
+	line = f.clip(line)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.lines = append(f.lines, line)
+	f.queued += len(line)
+	for f.queued > f.maxBytes {
+		f.queued -= len(f.lines[0])
+		f.lines = f.lines[1:]
+		if f.dropped == 0 {
+			log.Printf("Scroll text queue full, dropping old lines")
+		}
+		f.dropped++
+		f.room.Signal()
+	}
+}
+
+// Next returns the oldest queued line, if any
+func (f *TextFeed) Next() (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.lines) == 0 {
+		return "", false
+	}
+	line := f.lines[0]
+	f.lines = f.lines[1:]
+	f.queued -= len(line)
+	f.room.Signal()
+	return line, true
+}
+
+// clip cuts a line to the size of the queue, between two characters
+func (f *TextFeed) clip(line string) string {
+	if len(line) > f.maxBytes {
+		cut := f.maxBytes
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		line = line[:cut]
+	}
+	return line
+}
+
+// Dropped returns the number of lines dropped so far
+func (f *TextFeed) Dropped() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.dropped
+}
+
+// pullFeed keeps the text running ahead of the window with the lines
+// of the feed, or spaces while it is idle, and drops what has scrolled
+// past. The letters dropped are counted in trimmed, so the letters left
+// keep their wave phases.
+func (s *Scroller) pullFeed() {
+	if s.addi > 256 {
+		s.trimmed += s.tokens()[s.tokenAt(s.addi)].N
+		s.Text = s.Text[s.addi:]
+		s.addi = 0
+	}
+
+	for s.lettersLeft() <= s.onScreen()+1 {
+		line, ok := s.Feed.Next()
+		if !ok {
+			s.Text += " "
+			return
+		}
+		s.Text += " " + line + "   "
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFeedTrimKeepsWave(t *testing.T) {
+	s := newTestScroller(strings.Repeat(" ", scrollLetters))
+	s.Feed = NewTextQueue(4096)
+	s.Restart()
+	// A waveform whose phase runs along the letters
+	s.lockedForm = 2
+	s.snapForm()
+	var prev []PrintPos
+	trims := 0
+	for u := 0; u < 20000; u++ {
+		if u%50 == 0 {
+			s.Feed.Push("THE QUICK BROWN FOX JUMPS OVER THE LAZY DOG")
+		}
+		before := len(s.Text)
+		s.Update()
+		if len(s.Text) < before {
+			trims++
+		}
+		// The trim renumbers the letters, so they are followed by where
+		// they are: each letter is the one nearest along the line on the
+		// last update, and the wave only moves it a little
+		for _, p := range s.printPos {
+			if p.letter == "" {
+				continue
+			}
+			var near *PrintPos
+			for i := range prev {
+				q := &prev[i]
+				if q.letter != "" && math.Abs(p.x-q.x) < s.Speed+2 && (near == nil || math.Abs(p.x-q.x) < math.Abs(p.x-near.x)) {
+					near = q
+				}
+			}
+			if near != nil && math.Abs(p.y-near.y) > 8 {
+				t.Fatalf("update %d: letter at %g jumped from row %g to %g", u, p.x, near.y, p.y)
+			}
+		}
+		prev = append(prev[:0], s.printPos...)
+	}
+	if trims == 0 {
+		t.Fatal("the text was never trimmed")
+	}
+}
+
+func TestFeedReaderWaitsForRoom(t *testing.T) {
+	r, w := io.Pipe()
+	f := NewTextFeed(r, 8, nil)
+	wrote := make(chan int, 4)
+	go func() {
+		n := 0
+		for _, line := range []string{"ONE", "TWO", "SIX", "TEN"} {
+			io.WriteString(w, line+"\n")
+			n++
+			wrote <- n
+		}
+		w.Close()
+		close(wrote)
+	}()
+
+	// Two lines fit, the reader holds the third back and the writer
+	// waits on the pipe for the fourth
+	for n := range wrote {
+		if n == 3 {
+			break
+		}
+	}
+	select {
+	case n := <-wrote:
+		t.Fatalf("line %d written while the queue was full", n)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	var got []string
+	deadline := time.Now().Add(5 * time.Second)
+	for len(got) < 4 && time.Now().Before(deadline) {
+		if line, ok := f.Next(); ok {
+			got = append(got, line)
+		} else {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	if strings.Join(got, " ") != "ONE TWO SIX TEN" {
+		t.Errorf("read %q, want every line in order", got)
+	}
+	if f.Dropped() != 0 {
+		t.Errorf("dropped %d lines", f.Dropped())
+	}
+}
+
+func TestFeedPushMakesRoomForReader(t *testing.T) {
+	f := NewTextQueue(8)
+	f.Push("ABCDEFG")
+	done := make(chan struct{})
+	go func() {
+		f.pushWait("SIX")
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// Dropping the long line leaves room for the waiting one
+	f.Push("XY")
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the reader kept waiting once Push dropped the old lines")
+	}
+}
+
+func TestFeedClipKeepsCharacters(t *testing.T) {
+	f := NewTextQueue(4)
+	f.Push("ABCÉ")
+	if line, _ := f.Next(); line != "ABC" {
+		t.Errorf("clipped to %q, want \"ABC\"", line)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// TextFilter cleans text coming from outside the demo (standard input,
+// headline feed, chat, remote API) before it reaches a scroller
+type TextFilter struct {
+	// Words drop the whole text when one of them appears in it
+	Words []string
+	// Chars are the characters kept, the others become spaces
+	Chars string
+	// MaxLength truncates longer texts, 0 for no limit
+	MaxLength int
+}
+
+// fontChars are the characters the demo font can draw, the glyphs of
+// the -font descriptor once it is loaded
+var fontChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 !(),.:;"
+
+// fontCase upper-cases the letters the font has no lowercase glyph for
+func fontCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(fontChars, r) {
+			return r
+		}
+		return unicode.ToUpper(r)
+	}, s)
+}
+
+// sanitizeText upper-cases the letters of s the font has no lowercase
+// for and replaces everything the font cannot
+// draw, control code markers included, with spaces
+func sanitizeText(s string) string {
+	f := DefaultTextFilter()
+	out, _ := f.Apply(s)
+	return out
+}
+
+// DefaultTextFilter keeps what the font can draw
+func DefaultTextFilter() TextFilter {
+	return TextFilter{Chars: fontChars}
+}
+
+// Apply returns the filtered text, or false when it must be dropped.
+// Letters keep their case when Chars has it, with a font drawing
+// lowercase, and are upper-cased otherwise.
+func (f *TextFilter) Apply(s string) (string, bool) {
+	s = transliterate(s)
+	for _, w := range f.Words {
+		if w != "" && strings.Contains(strings.ToUpper(s), strings.ToUpper(w)) {
+			return "", false
+		}
+	}
+
+	out := strings.TrimSpace(strings.Map(func(r rune) rune {
+		if strings.ContainsRune(f.Chars, r) {
+			return r
+		}
+		if u := unicode.ToUpper(r); strings.ContainsRune(f.Chars, u) {
+			return u
+		}
+		return ' '
+	}, s))
+	if f.MaxLength > 0 && len(out) > f.MaxLength {
+		out = strings.TrimSpace(out[:f.MaxLength])
+	}
+	return out, out != ""
+}
+
+// String implements flag.Value
+func (f *TextFilter) String() string {
+	if f == nil {
+		return ""
+	}
+	return fmt.Sprintf("words=%s;chars=%s;max=%d", strings.Join(f.Words, ","), f.Chars, f.MaxLength)
+}
+
+// Set implements flag.Value, changing the fields named in a spec like
+// "words=foo,bar;chars=ABC ;max=120". Fields left out keep their value.
+func (f *TextFilter) Set(spec string) error {
+	for _, part := range strings.Split(spec, ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return fmt.Errorf("invalid filter setting %q, expected key=value", part)
+		}
+		switch key {
+		case "words":
+			f.Words = nil
+			for _, w := range strings.Split(value, ",") {
+				if w = strings.TrimSpace(transliterate(w)); w != "" {
+					f.Words = append(f.Words, w)
+				}
+			}
+		case "chars":
+			f.Chars = strings.ToUpper(value)
+		case "max":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return fmt.Errorf("invalid filter max length %q", value)
+			}
+			f.MaxLength = n
+		default:
+			return fmt.Errorf("unknown filter setting %q", key)
+		}
+	}
+	return nil
+}
+
+// textSources are the external text sources with their own filter
+var textSources = []string{"stdin", "feed", "chat", "remote"}
+
+// filterOverride is a flag.Value holding the filter changes of one
+// source, checked when the flag is parsed
+type filterOverride struct {
+	overrides map[string]string
+	source    string
+}
+
+func (o filterOverride) String() string {
+	if o.overrides == nil {
+		return ""
+	}
+	return o.overrides[o.source]
+}
+
+func (o filterOverride) Set(spec string) error {
+	var f TextFilter
+	if err := f.Set(spec); err != nil {
+		return err
+	}
+	o.overrides[o.source] = spec
+	return nil
+}
+
+// FilterFor returns the text filter of an external source: the common
+// filter with the source's overrides applied
+func (c *Config) FilterFor(source string) *TextFilter {
+	f := c.Filter
+	f.Words = append([]string(nil), c.Filter.Words...)
+	if spec := c.FilterOverrides[source]; spec != "" {
+		// Checked when the flag was parsed
+		_ = f.Set(spec)
+	}
+	return &f
+}
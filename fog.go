@@ -0,0 +1,104 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"tcb-multi-plane-3d-scroller/hooks"
+)
+
+// waveMiddle is the depth the waves swing around, where the fog starts
+const waveMiddle = 150
+
+// shadeBlend multiplies the colors of the destination by the source's
+var shadeBlend = ebiten.Blend{
+	BlendFactorSourceRGB:        ebiten.BlendFactorZero,
+	BlendFactorSourceAlpha:      ebiten.BlendFactorZero,
+	BlendFactorDestinationRGB:   ebiten.BlendFactorSourceColor,
+	BlendFactorDestinationAlpha: ebiten.BlendFactorOne,
+	BlendOperationRGB:           ebiten.BlendOperationAdd,
+	BlendOperationAlpha:         ebiten.BlendOperationAdd,
+}
+
+// fog returns how much a letter drawn at projection scale darkens and
+// fades in form sf: nothing up to the middle of the wave, the full fogDark
+// and fogFade of the form at its back, along its fog curve between
+func (s *Scroller) fog(scale float64, sf ScrollForm) (dark, fade float64) {
+	if (sf.fogDark <= 0 && sf.fogFade <= 0) || scale <= 0 {
+		return 0, 0
+	}
+	reach := sf.zSize
+	switch s.Mode {
+	case ScrollRing:
+		reach = s.RingRadius * math.Cos(s.RingTilt)
+	case ScrollPath:
+		// Flat letters are all at one depth
+		return 0, 0
+	}
+	if reach <= 0 {
+		return 0, 0
+	}
+	behind := fov/scale - fov - waveMiddle
+	thick := sf.fogCurve.At(behind / math.Abs(reach))
+	return min(max(sf.fogDark, 0), 1) * thick, min(max(sf.fogFade, 0), 1) * thick
+}
+
+// fadeLetter fades l into the background and darkens its own colors as
+// far as its fog reaches. The rasters keep only the alpha of the letters
+// they color, drawShade darkens those.
+func fadeLetter(l *hooks.Letter, p *PrintPos) {
+	if p.fade > 0 {
+		a := float32(1 - p.fade)
+		l.Color.Scale(a, a, a, a)
+	}
+	if p.dark > 0 {
+		k := float32(1 - p.dark)
+		l.Color.Scale(k, k, k, 1)
+	}
+}
+
+// shadedLetter is a letter colored by the rasters, with the darkening
+// of its fog
+type shadedLetter struct {
+	l     hooks.Letter
+	font  int
+	stamp string
+	dark  float64
+}
+
+// drawShade darkens the letters colored by the rasters by their fog: it
+// draws them back to front in the gray of their fog onto a white shade,
+// the nearer letters covering the farther ones, and multiplies the
+// canvas by the shade
+func (s *Scroller) drawShade(letters []shadedLetter) {
+	fogged := false
+	for i := range letters {
+		fogged = fogged || letters[i].dark > 0
+	}
+	if !fogged {
+		return
+	}
+
+	if s.shade == nil || s.shade.Bounds().Size() != s.canvas.Bounds().Size() {
+		s.shade = ebiten.NewImage(s.canvas.Bounds().Dx(), s.canvas.Bounds().Dy())
+	}
+	s.shade.Fill(color.White)
+	canvas, list := s.canvas, s.DrawList
+	s.canvas, s.DrawList = s.shade, nil
+	for i := range letters {
+		l := letters[i].l
+		gray := float32(1 - letters[i].dark)
+		l.Color = ebiten.ColorScale{}
+		l.Color.Scale(gray, gray, gray, 1)
+		s.drawGlyph(&l, letters[i].font, letters[i].stamp)
+	}
+	s.flushLetters()
+	s.canvas, s.DrawList = canvas, list
+
+	op := &ebiten.DrawImageOptions{}
+	op.Blend = shadeBlend
+	s.canvas.DrawImage(s.shade, op)
+	s.DrawList.add("scroller", "fog", s.shade.Bounds(), op)
+}
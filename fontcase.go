@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// FontCaps are what a font can draw besides the uppercase letters,
+// which the scrollers and the text sources adapt to
+type FontCaps struct {
+	// Lowercase is set when the font has lowercase glyphs: the text
+	// keeps its case rather than being upper-cased
+	Lowercase bool
+}
+
+// fontCapsOf returns the capabilities of a font from its tiles
+func fontCapsOf(tiles map[rune]*ebiten.Image) FontCaps {
+	var caps FontCaps
+	for r := 'a'; r <= 'z'; r++ {
+		if _, ok := tiles[r]; ok {
+			caps.Lowercase = true
+			break
+		}
+	}
+	return caps
+}
+
+func (c FontCaps) String() string {
+	if c.Lowercase {
+		return "upper and lowercase"
+	}
+	return "uppercase"
+}
+
+// fontRune returns the rune font draws for r: r itself when the font
+// has it, its uppercase for the fonts without lowercase and the
+// lowercase letters they lack
+func (s *Scroller) fontRune(font int, r rune) rune {
+	if r < 'a' || r > 'z' {
+		return r
+	}
+	if s.caps[font].Lowercase {
+		if _, ok := s.fonts[font][r]; ok {
+			return r
+		}
+	}
+	return r - 'a' + 'A'
+}
+
+// gridRows are the characters of a font sheet laid out as bgfont.png,
+// row by row of 10 tiles. The lowercase rows below the built-in six are
+// used when the sheet is tall enough to have them.
+var gridRows = [][]rune{
+	{0, '!', 0, 0, 0, 0, 0, 0, '(', ')'},
+	{0, 0, ',', 0, '.', 0, 0, 0, 0, 0},
+	{0, 0, 0, 0, 0, 0, ':', ';', 0, 0},
+	{0, 0, 0, 'A', 'B', 'C', 'D', 'E', 'F', 'G'},
+	{'H', 'I', 'J', 'K', 'L', 'M', 'N', 'O', 'P', 'Q'},
+	{'R', 'S', 'T', 'U', 'V', 'W', 'X', 'Y', 'Z', 0},
+	{'a', 'b', 'c', 'd', 'e', 'f', 'g', 'h', 'i', 'j'},
+	{'k', 'l', 'm', 'n', 'o', 'p', 'q', 'r', 's', 't'},
+	{'u', 'v', 'w', 'x', 'y', 'z', 0, 0, 0, 0},
+}
+
+// isGridFont reports whether path is a font sheet laid out as
+// bgfont.png rather than a descriptor
+func isGridFont(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".png")
+}
+
+// loadGridFont replaces the font with a sheet laid out as bgfont.png,
+// with lowercase rows or not
+func (g *Game) loadGridFont(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open font sheet: %w", err)
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return fmt.Errorf("failed to decode font sheet: %w", err)
+	}
+	if b := img.Bounds(); b.Dx() < 10*fontTileWidth || b.Dy() < 6*fontTileHeight {
+		return fmt.Errorf("%s: font sheet is %dx%d, want at least %dx%d", path, b.Dx(), b.Dy(), 10*fontTileWidth, 6*fontTileHeight)
+	}
+
+	g.font = ebiten.NewImageFromImage(img)
+	clear(g.fontTiles)
+	clear(g.fontSpans)
+	g.cacheFontTiles(img)
+	fontChars = tileChars(g.fontTiles, "")
+	g.fontName = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return nil
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/png"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Size of the tiles the scroller draws, every glyph is placed on one
+const (
+	fontTileWidth  = 32
+	fontTileHeight = 33
+)
+
+// FontDescriptor lays out the glyphs of a font image, for fonts beyond
+// the grid of bgfont.png: lowercase, digits, more punctuation
+type FontDescriptor struct {
+	Image    string               `json:"image"`    // PNG, relative to the descriptor
+	Baseline int                  `json:"baseline"` // tile row the glyphs sit on, 0 for the bottom one
+	Glyphs   map[string]FontGlyph `json:"glyphs"`
+}
+
+// FontGlyph is a glyph of the font image
+type FontGlyph struct {
+	Rect     [4]int `json:"rect"`     // x, y, width and height in the image
+	Baseline int    `json:"baseline"` // row of the rect on the baseline, 0 for its bottom
+	Width    int    `json:"width"`    // room the glyph takes, 0 for its drawn columns
+	// Frames animates the glyph: the rects of its frames follow each
+	// other to the right, shown at FPS frames a second
+	Frames int     `json:"frames"` // 0 or 1 for a still glyph
+	FPS    float64 `json:"fps"`    // 0 for 8
+}
+
+// LoadFontDescriptor reads a font descriptor and the image it names
+func LoadFontDescriptor(path string) (*FontDescriptor, image.Image, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read font descriptor: %w", err)
+	}
+	var d FontDescriptor
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse font descriptor: %w", err)
+	}
+
+	imgPath := d.Image
+	if !filepath.IsAbs(imgPath) {
+		imgPath = filepath.Join(filepath.Dir(path), imgPath)
+	}
+	f, err := os.Open(imgPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open font image: %w", err)
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode font image: %w", err)
+	}
+
+	if err := d.check(img.Bounds()); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &d, img, nil
+}
+
+// check reports glyphs that are not single characters the scroller can
+// show, or that don't fit the image or a tile
+func (d *FontDescriptor) check(bounds image.Rectangle) error {
+	if d.Baseline < 0 || d.Baseline > fontTileHeight {
+		return fmt.Errorf("baseline %d is outside the %d rows of a tile", d.Baseline, fontTileHeight)
+	}
+	if len(d.Glyphs) == 0 {
+		return fmt.Errorf("no glyphs")
+	}
+	for key, g := range d.Glyphs {
+		if len(key) != 1 || key[0] <= ' ' || key[0] > '~' || key[0] == '^' {
+			return fmt.Errorf("glyph %q: want a single printable ASCII character other than space and ^", key)
+		}
+		r := g.rect()
+		if r.Empty() || !r.In(bounds) {
+			return fmt.Errorf("glyph %q: rect %v is outside the image", key, g.Rect)
+		}
+		if g.Frames < 0 || g.FPS < 0 {
+			return fmt.Errorf("glyph %q: invalid frames or fps", key)
+		}
+		if strip := r.Add(image.Pt((max(g.Frames, 1)-1)*r.Dx(), 0)); !strip.In(bounds) {
+			return fmt.Errorf("glyph %q: the %d frames reach outside the image", key, g.Frames)
+		}
+		if r.Dx() > fontTileWidth || r.Dy() > fontTileHeight {
+			return fmt.Errorf("glyph %q: %dx%d is larger than a %dx%d tile", key, r.Dx(), r.Dy(), fontTileWidth, fontTileHeight)
+		}
+		if g.Baseline < 0 || g.Baseline > r.Dy() || g.Width < 0 {
+			return fmt.Errorf("glyph %q: invalid baseline or width", key)
+		}
+		if at := g.origin(d.Baseline); at.Y < 0 || at.Y+r.Dy() > fontTileHeight {
+			return fmt.Errorf("glyph %q: does not fit a tile on the baseline", key)
+		}
+	}
+	return nil
+}
+
+func (g FontGlyph) rect() image.Rectangle {
+	return image.Rect(g.Rect[0], g.Rect[1], g.Rect[0]+g.Rect[2], g.Rect[1]+g.Rect[3])
+}
+
+// origin returns where the glyph goes on its tile: centered, its
+// baseline on the one of the font
+func (g FontGlyph) origin(fontBaseline int) image.Point {
+	r := g.rect()
+	baseline := g.Baseline
+	if baseline == 0 {
+		baseline = r.Dy()
+	}
+	if fontBaseline == 0 {
+		fontBaseline = fontTileHeight
+	}
+	return image.Pt((fontTileWidth-r.Dx())/2, fontBaseline-baseline)
+}
+
+// newTiles places the glyphs on tiles cut from img, which holds src,
+// with the columns each covers. Digits the font lacks are drawn.
+func (d *FontDescriptor) newTiles(img *ebiten.Image, src image.Image) (map[rune]*ebiten.Image, map[rune][2]int) {
+	tiles := map[rune]*ebiten.Image{' ': ebiten.NewImage(fontTileWidth, fontTileHeight)}
+	spans := make(map[rune][2]int)
+	addDigitTiles(tiles)
+	for r := '0'; r <= '9'; r++ {
+		spans[r] = [2]int{digitLeft, digitRight}
+	}
+
+	for key, glyph := range d.Glyphs {
+		r := glyph.rect()
+		at := glyph.origin(d.Baseline)
+		frames := make([]*ebiten.Image, max(glyph.Frames, 1))
+		for i := range frames {
+			frames[i] = ebiten.NewImage(fontTileWidth, fontTileHeight)
+			op := &ebiten.DrawImageOptions{}
+			op.GeoM.Translate(float64(at.X), float64(at.Y))
+			frames[i].DrawImage(img.SubImage(r.Add(image.Pt(i*r.Dx(), 0))).(*ebiten.Image), op)
+		}
+		tile := frames[0]
+		if len(frames) > 1 {
+			fps := glyph.FPS
+			if fps == 0 {
+				fps = defaultGlyphFPS
+			}
+			glyphAnims[tile] = &glyphAnim{frames: frames, fps: fps}
+		}
+
+		span := glyphSpan(src, r)
+		span[0] += at.X
+		span[1] += at.X
+		if glyph.Width > 0 {
+			span[1] = span[0] + glyph.Width
+		}
+
+		tiles[rune(key[0])] = tile
+		spans[rune(key[0])] = span
+	}
+	return tiles, spans
+}
+
+// tileChars returns the characters of tiles missing from chars
+func tileChars(tiles map[rune]*ebiten.Image, chars string) string {
+	var added []rune
+	for r := range tiles {
+		if !strings.ContainsRune(chars, r) {
+			added = append(added, r)
+		}
+	}
+	slices.Sort(added)
+	return string(added)
+}
+
+// loadDescribedFont replaces the font with the one of a descriptor
+func (g *Game) loadDescribedFont(path string) error {
+	d, img, err := LoadFontDescriptor(path)
+	if err != nil {
+		return err
+	}
+
+	g.font = ebiten.NewImageFromImage(img)
+	tiles, spans := d.newTiles(g.font, img)
+	clear(g.fontTiles)
+	clear(g.fontSpans)
+	maps.Copy(g.fontTiles, tiles)
+	maps.Copy(g.fontSpans, spans)
+	fontChars = tileChars(tiles, "")
+	g.fontName = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return nil
+}
+
+// loadExtraFonts loads the fonts of -extra-font, descriptors or
+// TrueType fonts, which the ^F codes switch to after the styles of the
+// main font
+func (g *Game) loadExtraFonts() {
+	for _, path := range g.cfg.ExtraFonts {
+		tiles, spans, err := g.loadExtraFont(path)
+		if err != nil {
+			log.Printf("Failed to load extra font: %v", err)
+			continue
+		}
+		g.extraFonts = append(g.extraFonts, tiles)
+		g.extraSpans = append(g.extraSpans, spans)
+		g.extraFontNames = append(g.extraFontNames, strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+		fontChars += tileChars(tiles, fontChars)
+	}
+}
+
+func (g *Game) loadExtraFont(path string) (map[rune]*ebiten.Image, map[rune][2]int, error) {
+	if isTTF(path) {
+		sheet, cells, err := LoadTTFSheet(path, g.cfg.TTFSize)
+		if err != nil {
+			return nil, nil, err
+		}
+		tiles, spans := ttfTiles(ebiten.NewImageFromImage(sheet), sheet, cells)
+		return tiles, spans, nil
+	}
+
+	d, img, err := LoadFontDescriptor(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	tiles, spans := d.newTiles(ebiten.NewImageFromImage(img), img)
+	return tiles, spans, nil
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"log"
+	"os"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"tcb-multi-plane-3d-scroller/easings"
+)
+
+// defaultFormsFile receives the waveforms exported without -forms
+const defaultFormsFile = "forms.json"
+
+// formParam is a parameter of the waveforms: its name in the forms
+// files, the step of the arrow keys and the range of its slider
+type formParam struct {
+	name  string
+	step  float64
+	limit float64 // the slider runs from -limit to limit
+}
+
+var formParams = []formParam{
+	{"zSize", 10, 300},
+	{"zAmount", 1, 100},
+	{"zSpeed", 0.5, 10},
+	{"zAdd", 0.25, 10},
+	{"ySize", 5, 100},
+	{"yAmount", 1, 100},
+	{"ySpeed", 0.5, 10},
+	{"xSize", 1, 50},
+	{"xAmount", 1, 100},
+	{"xSpeed", 0.5, 10},
+	{"rSize", 0.1, 6.4},
+	{"rAmount", 1, 100},
+	{"rSpeed", 0.5, 10},
+	{"fogDark", 0.05, 1},
+	{"fogFade", 0.05, 1},
+}
+
+// param returns parameter i of f, in the order of formParams
+func (f *ScrollForm) param(i int) *float64 {
+	return [...]*float64{&f.zSize, &f.zAmount, &f.zSpeed, &f.zAdd, &f.ySize, &f.yAmount, &f.ySpeed, &f.xSize, &f.xAmount, &f.xSpeed, &f.rSize, &f.rAmount, &f.rSpeed, &f.fogDark, &f.fogFade}[i]
+}
+
+// FormEditor adjusts the waveforms while the demo runs, opened with W.
+// The main scroller holds the form being edited, and every scroller
+// shows the changes at once.
+type FormEditor struct {
+	active bool
+	form   int
+	param  int
+	locked int // lockedForm of the scroller before it opened
+}
+
+// toggleFormEditor opens the waveform editor on the form on screen, or
+// closes it
+func (g *Game) toggleFormEditor() {
+	e := &g.formEditor
+	s := g.scroller
+	if e.active {
+		e.active = false
+		s.lockedForm = e.locked
+		return
+	}
+	e.active = true
+	e.locked = s.lockedForm
+	e.form = s.form
+	if s.lockedForm >= 0 {
+		e.form = s.lockedForm
+	}
+	s.lockedForm = e.form
+	// The editor takes the arrow keys
+	g.options.visible = false
+}
+
+// updateFormEditor selects and changes the parameters with the arrow
+// keys, the form with Page Up and Page Down, the morph curve with Tab
+// and the fog curve of the form with Shift+Tab, and exports the forms
+// with Ctrl+S
+func (g *Game) updateFormEditor() {
+	e := &g.formEditor
+	if ebiten.IsKeyPressed(ebiten.KeyControl) {
+		if inpututil.IsKeyJustPressed(ebiten.KeyS) {
+			g.exportForms()
+		}
+		return
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyUp) {
+		e.param = cycle(e.param, -1, len(formParams))
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyDown) {
+		e.param = cycle(e.param, 1, len(formParams))
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyPageUp) {
+		e.form = cycle(e.form, -1, len(scrollForms))
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyPageDown) {
+		e.form = cycle(e.form, 1, len(scrollForms))
+	}
+	g.scroller.lockedForm = e.form
+	if inpututil.IsKeyJustPressed(ebiten.KeyTab) {
+		if ebiten.IsKeyPressed(ebiten.KeyShift) {
+			f := &scrollForms[e.form]
+			f.fogCurve = nextCurve(f.fogCurve)
+		} else {
+			g.cycleMorphCurve()
+		}
+	}
+
+	// Held arrows repeat as in the text editor, Shift for tenth steps
+	step := formParams[e.param].step
+	if ebiten.IsKeyPressed(ebiten.KeyShift) {
+		step /= 10
+	}
+	v := scrollForms[e.form].param(e.param)
+	if keyRepeated(ebiten.KeyLeft) {
+		*v -= step
+	}
+	if keyRepeated(ebiten.KeyRight) {
+		*v += step
+	}
+}
+
+// nextCurve returns the named curve after c, the first after a Bézier
+// curve
+func nextCurve(c easings.Curve) easings.Curve {
+	i := slices.Index(easings.Names, c.String())
+	return easings.MustParse(easings.Names[(i+1)%len(easings.Names)])
+}
+
+// cycleMorphCurve turns every scroller to the next named morph curve
+func (g *Game) cycleMorphCurve() {
+	c := nextCurve(g.cfg.FormMorphCurve)
+	g.cfg.FormMorphCurve = c
+	for _, s := range g.allScrollers() {
+		s.MorphCurve = c
+	}
+}
+
+// keyRepeated reports whether key was just pressed, or is held long
+// enough to repeat
+func keyRepeated(key ebiten.Key) bool {
+	d := inpututil.KeyPressDuration(key)
+	return d == 1 || (d > editorRepeatDelay && d%editorRepeatEvery == 0)
+}
+
+// exportForms writes the waveforms to the -forms file, or to forms.json
+func (g *Game) exportForms() {
+	path := g.cfg.Forms
+	if path == "" {
+		path = defaultFormsFile
+	}
+	data, err := formsJSON()
+	if err == nil {
+		err = os.WriteFile(path, data, 0o644)
+	}
+	if err != nil {
+		log.Printf("Failed to export the waveforms: %v", err)
+		g.notice.Show("Failed to export the waveforms")
+		return
+	}
+	g.notice.Show("Waveforms saved to " + path)
+}
+
+// formsJSON encodes the waveforms as a -forms file
+func formsJSON() ([]byte, error) {
+	file := make([]scrollFormFile, len(scrollForms))
+	for i, f := range scrollForms {
+		file[i] = scrollFormFile{f.zSize, f.zAmount, f.zSpeed, f.zAdd, f.ySize, f.yAmount, f.ySpeed, f.xSize, f.xAmount, f.xSpeed, f.rSize, f.rAmount, f.rSpeed, f.fogDark, f.fogFade, f.fogCurve}
+	}
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// drawFormEditor shows the parameters of the form being edited, with a
+// slider each
+func (g *Game) drawFormEditor(screen *ebiten.Image) {
+	e := &g.formEditor
+	if !e.active {
+		return
+	}
+
+	const (
+		x           = 24
+		y           = 24
+		lineHeight  = 16
+		sliderX     = x + 180
+		sliderWidth = 120
+		curveWidth  = 96
+		curveHeight = 48
+	)
+	h := float32(len(formParams)*lineHeight + 60 + 2*(curveHeight+24))
+	vector.DrawFilledRect(screen, x, y, 320, h, color.RGBA{0, 0, 0, 0xc0}, false)
+
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("WAVEFORM %d/%d  (W to close)", e.form, len(scrollForms)-1), x+8, y+8)
+	f := &scrollForms[e.form]
+	for i, p := range formParams {
+		cursor := " "
+		if i == e.param {
+			cursor = ">"
+		}
+		v := *f.param(i)
+		ly := y + 28 + i*lineHeight
+		ebitenutil.DebugPrintAt(screen, fmt.Sprintf("%s %-8s %7.2f", cursor, p.name, v), x+8, ly)
+
+		// Slider from -limit to limit, the zero in the middle
+		pos := (min(max(v, -p.limit), p.limit)/p.limit + 1) / 2
+		vector.DrawFilledRect(screen, sliderX, float32(ly+7), sliderWidth, 2, color.RGBA{0x60, 0x60, 0x60, 0xff}, false)
+		vector.DrawFilledRect(screen, sliderX+sliderWidth/2, float32(ly+4), 1, 8, color.RGBA{0x60, 0x60, 0x60, 0xff}, false)
+		vector.DrawFilledRect(screen, sliderX+float32(pos)*sliderWidth-2, float32(ly+3), 4, 10, color.RGBA{0xff, 0xe0, 0x20, 0xff}, false)
+	}
+	ebitenutil.DebugPrintAt(screen, "PGUP/PGDN form  CTRL+S export", x+8, y+32+len(formParams)*lineHeight)
+
+	// Preview of the morph curve, with the morph under way
+	cy := y + 56 + len(formParams)*lineHeight
+	s := g.scroller
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("MORPH %s  (TAB)", s.MorphCurve), x+curveWidth+24, cy+curveHeight/2-8)
+	drawCurve(screen, s.MorphCurve, s.morphAt, x+8, cy, curveWidth, curveHeight)
+
+	// and of the fog of the form, from the middle of the wave to its back
+	cy += curveHeight + 24
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("FOG %s  (SHIFT+TAB)", f.fogCurve), x+curveWidth+24, cy+curveHeight/2-8)
+	drawCurve(screen, f.fogCurve, 1, x+8, cy, curveWidth, curveHeight)
+}
+
+// drawCurve plots curve in the w x h box at (x, y), with a dot at time
+// t while it is below 1
+func drawCurve(screen *ebiten.Image, curve easings.Curve, t float64, x, y, w, h int) {
+	grey := color.RGBA{0x60, 0x60, 0x60, 0xff}
+	vector.StrokeRect(screen, float32(x), float32(y), float32(w), float32(h), 1, grey, false)
+	point := func(t float64) (float32, float32) {
+		return float32(x) + float32(t)*float32(w), float32(y+h) - float32(curve.At(t))*float32(h)
+	}
+
+	const segments = 48
+	x0, y0 := point(0)
+	for i := 1; i <= segments; i++ {
+		x1, y1 := point(float64(i) / segments)
+		vector.StrokeLine(screen, x0, y0, x1, y1, 1, color.White, false)
+		x0, y0 = x1, y1
+	}
+	if t < 1 {
+		px, py := point(t)
+		vector.DrawFilledCircle(screen, px, py, 3, color.RGBA{0xff, 0xe0, 0x20, 0xff}, false)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"tcb-multi-plane-3d-scroller/easings"
+)
+
+// maxScrollForms is the number of waveforms the ^0 to ^9 codes select,
+// one digit each
+const maxScrollForms = 10
+
+//go:embed assets/forms.json
+var defaultFormsData []byte
+
+// scrollForms are the waveforms selected with ^0 to ^9: the eight of
+// the original screen (exactly as in JS), or those of -forms
+var scrollForms = mustParseScrollForms(defaultFormsData)
+
+// ScrollForm defines parameters for scroll wave forms
+type ScrollForm struct {
+	zSize   float64
+	zAmount float64
+	zSpeed  float64
+	zAdd    float64
+	ySize   float64
+	yAmount float64
+	ySpeed  float64
+	// Wobble along the line, 0 for the forms of the original
+	xSize   float64
+	xAmount float64
+	xSpeed  float64
+	// Rotation of the letters around the view axis, 0 for upright
+	rSize   float64
+	rAmount float64
+	rSpeed  float64
+	// Depth fog: how much the farthest letters darken and fade, from 0
+	// to 1, and how the fog thickens with depth
+	fogDark  float64
+	fogFade  float64
+	fogCurve easings.Curve
+}
+
+// scrollFormFile is a waveform as written in a forms file
+type scrollFormFile struct {
+	ZSize   float64 `json:"zSize"`   // depth of the wave
+	ZAmount float64 `json:"zAmount"` // depth phase step per letter, in hundredths of a radian
+	ZSpeed  float64 `json:"zSpeed"`  // depth phase step per update
+	ZAdd    float64 `json:"zAdd"`    // depth phase offset in radians
+	YSize   float64 `json:"ySize"`   // height of the wave
+	YAmount float64 `json:"yAmount"` // height phase step per letter, in hundredths of a radian
+	YSpeed  float64 `json:"ySpeed"`  // height phase step per update
+	XSize   float64 `json:"xSize"`   // wobble along the line
+	XAmount float64 `json:"xAmount"` // wobble phase step per letter, in hundredths of a radian
+	XSpeed  float64 `json:"xSpeed"`  // wobble phase step per update
+	RSize   float64 `json:"rSize"`   // letter rotation in radians
+	RAmount float64 `json:"rAmount"` // rotation phase step per letter, in hundredths of a radian
+	RSpeed  float64 `json:"rSpeed"`  // rotation phase step per update
+	// Fog of the letters behind the middle of the wave, strongest at the
+	// back
+	FogDark  float64       `json:"fogDark"`  // darkening of the farthest letters, 0 to 1
+	FogFade  float64       `json:"fogFade"`  // fading of the farthest letters, 0 to 1
+	FogCurve easings.Curve `json:"fogCurve"` // thickening of the fog with depth
+}
+
+// parseScrollForms reads the waveforms of a forms file, a JSON array of
+// 1 to 10 of them
+func parseScrollForms(data []byte) ([]ScrollForm, error) {
+	var file []scrollFormFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse waveforms: %w", err)
+	}
+	if len(file) == 0 || len(file) > maxScrollForms {
+		return nil, fmt.Errorf("%d waveforms, want 1 to %d for the ^0-^9 codes", len(file), maxScrollForms)
+	}
+	forms := make([]ScrollForm, len(file))
+	for i, f := range file {
+		forms[i] = ScrollForm{f.ZSize, f.ZAmount, f.ZSpeed, f.ZAdd, f.YSize, f.YAmount, f.YSpeed, f.XSize, f.XAmount, f.XSpeed, f.RSize, f.RAmount, f.RSpeed, f.FogDark, f.FogFade, f.FogCurve}
+	}
+	return forms, nil
+}
+
+func mustParseScrollForms(data []byte) []ScrollForm {
+	forms, err := parseScrollForms(data)
+	if err != nil {
+		panic(err)
+	}
+	return forms
+}
+
+// LoadScrollForms reads a forms file
+func LoadScrollForms(path string) ([]ScrollForm, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read waveforms: %w", err)
+	}
+	forms, err := parseScrollForms(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return forms, nil
+}
+
+// loadScrollForms replaces the built-in waveforms with those of -forms.
+// It runs before any text is read, as the number of forms decides which
+// ^n codes there are.
+func (g *Game) loadScrollForms() {
+	if g.cfg.Forms == "" {
+		return
+	}
+	forms, err := LoadScrollForms(g.cfg.Forms)
+	if err != nil {
+		log.Printf("Failed to load waveforms, using the built-in ones: %v", err)
+		return
+	}
+	scrollForms = forms
+}
+
+// isFormCode reports whether ^c selects a waveform
+func isFormCode(c byte) bool {
+	return c >= '0' && int(c-'0') < len(scrollForms)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"tcb-multi-plane-3d-scroller/hooks"
+)
+
+// galleryWarmup is the number of frames a waveform runs before its
+// picture is taken, so the wave is fully established
+const galleryWarmup = 40
+
+// galleryPalette is one color treatment of the final frame
+type galleryPalette struct {
+	name     string
+	quantize bool
+	dither   bool
+}
+
+var galleryPalettes = []galleryPalette{
+	{"original", false, false},
+	{"st", true, false},
+	{"st-dither", true, true},
+}
+
+// galleryFont is a font of the gallery and the scroller face drawing it
+type galleryFont struct {
+	name string
+	face int
+}
+
+// galleryShot is one picture of the gallery
+type galleryShot struct {
+	form    int
+	font    galleryFont
+	colors  Palette
+	palette galleryPalette
+}
+
+// Gallery renders a labeled PNG of every waveform in every font and
+// palette, then stops the demo
+type Gallery struct {
+	game  *Game
+	dir   string
+	shots []galleryShot
+	index int
+	frame int
+	err   error // a picture that failed, ending the run
+}
+
+// NewGallery prepares the shots and hooks the gallery into the frame loop
+func NewGallery(g *Game, dir string) (*Gallery, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create gallery directory: %w", err)
+	}
+
+	// The main font, then the fonts of -extra-font, faces after the
+	// styles of the main one
+	fonts := []galleryFont{{g.fontName, 0}}
+	for i, name := range g.extraFontNames {
+		fonts = append(fonts, galleryFont{name, len(fontStyles) + i})
+	}
+	palettes := galleryPalettes
+	if g.grain == nil {
+		palettes = palettes[:1]
+	}
+
+	gl := &Gallery{game: g, dir: dir}
+	for form := range g.scroller.Forms {
+		for _, font := range fonts {
+			for colors := range paletteNames {
+				for _, palette := range palettes {
+					gl.shots = append(gl.shots, galleryShot{form, font, Palette(colors), palette})
+				}
+			}
+		}
+	}
+
+	g.hooks.Register(hooks.PreUpdate, "gallery", gl.update)
+	g.hooks.Register(hooks.PostDraw, "gallery", gl.capture)
+	gl.setup()
+	return gl, nil
+}
+
+// setup puts the demo in the state of the current shot
+func (gl *Gallery) setup() {
+	g := gl.game
+	shot := gl.shots[gl.index]
+
+	s := g.scroller
+	s.lockedForm = shot.form
+	s.lockedFace = shot.font.face
+	s.snapForm()
+	s.wave = 0
+	s.scrollX = 0
+	// Start on real text rather than the leading spaces
+	s.addi = max(0, strings.Index(s.Text, "WOW"))
+
+	g.setPalette(shot.colors)
+	if g.grain != nil {
+		g.grain.Quantize = shot.palette.quantize
+		g.grain.Dither = shot.palette.dither
+	}
+	gl.frame = 0
+}
+
+// update ends the run once every picture is taken, with the error of
+// the picture that failed if one did
+func (gl *Gallery) update(ctx *hooks.Context) error {
+	if gl.err != nil {
+		return gl.err
+	}
+	if gl.index >= len(gl.shots) {
+		log.Printf("Gallery written to %s (%d pictures)", gl.dir, len(gl.shots))
+		return ebiten.Termination
+	}
+	gl.frame++
+	return nil
+}
+
+// capture takes the picture of the current shot. The draw hooks only log
+// their errors, so a failure is kept for update to end the run with.
+func (gl *Gallery) capture(ctx *hooks.Context) error {
+	if gl.err != nil || gl.index >= len(gl.shots) || gl.frame < galleryWarmup {
+		return nil
+	}
+	if err := gl.write(ctx.Screen); err != nil {
+		gl.err = fmt.Errorf("failed to write gallery: %w", err)
+		return gl.err
+	}
+
+	gl.index++
+	if gl.index < len(gl.shots) {
+		gl.setup()
+	}
+	return nil
+}
+
+// write labels screen with the current shot and saves it
+func (gl *Gallery) write(screen *ebiten.Image) error {
+	shot := gl.shots[gl.index]
+	label := fmt.Sprintf("FORM %d  FONT %s  PALETTE %s %s", shot.form, strings.ToUpper(shot.font.name), strings.ToUpper(shot.colors.String()), strings.ToUpper(shot.palette.name))
+	b := screen.Bounds()
+	vector.DrawFilledRect(screen, 0, float32(b.Dy()-20), float32(b.Dx()), 20, color.Black, false)
+	ebitenutil.DebugPrintAt(screen, label, 8, b.Dy()-18)
+
+	img := image.NewRGBA(b)
+	screen.ReadPixels(img.Pix)
+
+	name := fmt.Sprintf("form%d-%s-%s-%s.png", shot.form, shot.font.name, shot.colors, shot.palette.name)
+	f, err := os.Create(filepath.Join(gl.dir, name))
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/colorm"
+)
+
+// TextGlow adds a soft halo around the scroller letters whose strength
+// follows the level of one music channel
+type TextGlow struct {
+	mask   *ebiten.Image
+	tinted *ebiten.Image
+	blur   *BlurTarget
+	color  color.Color
+	radius float64
+	source func() float64
+	level  float64
+}
+
+// NewTextGlow creates a glow around the opaque pixels of mask. level
+// returns the music level driving the glow, in the range [0,1].
+func NewTextGlow(blurrer *Blurrer, mask *ebiten.Image, clr color.Color, radius float64, level func() float64) *TextGlow {
+	w := mask.Bounds().Dx()
+	h := mask.Bounds().Dy()
+	return &TextGlow{
+		mask:   mask,
+		tinted: ebiten.NewImage(w, h),
+		blur:   blurrer.NewTarget(w, h),
+		color:  clr,
+		radius: radius,
+		source: level,
+	}
+}
+
+// Update follows the music level with a quick attack and slow release
+func (t *TextGlow) Update() {
+	target := t.source()
+	if target > t.level {
+		t.level = target
+	} else {
+		t.level *= 0.9
+	}
+}
+
+// Apply draws the glow additively onto canvas, behind the letters
+func (t *TextGlow) Apply(canvas *ebiten.Image) {
+	if t.level < 0.01 {
+		return
+	}
+
+	// Letter coverage tinted with the glow color
+	var cm colorm.ColorM
+	cm.Scale(0, 0, 0, 1)
+	r, g, b, _ := t.color.RGBA()
+	cm.Translate(float64(r)/0xffff, float64(g)/0xffff, float64(b)/0xffff, 0)
+	mop := &colorm.DrawImageOptions{}
+	mop.Blend = ebiten.BlendCopy
+	colorm.DrawImage(t.tinted, t.mask, cm, mop)
+
+	blurred, scale := t.blur.Blur(t.tinted, t.radius)
+
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(scale, scale)
+	op.Filter = ebiten.FilterLinear
+	op.ColorScale.Scale(float32(t.level), float32(t.level), float32(t.level), float32(t.level))
+	op.Blend = ebiten.BlendLighter
+	canvas.DrawImage(blurred, op)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// defaultGlyphFPS is the rate of the animated glyphs that don't set one
+const defaultGlyphFPS = 8
+
+// glyphAnim is the animation strip of a glyph, a blinking eye or a
+// spinning star, cycling at fps frames a second
+type glyphAnim struct {
+	frames []*ebiten.Image // the tile of the glyph first
+	fps    float64
+}
+
+// glyphAnims are the animations of the fonts, by the tile of their
+// first frame, so whatever draws the tiles of a font may animate them
+var glyphAnims = map[*ebiten.Image]*glyphAnim{}
+
+// animFrame returns the frame of tile shown after ticks updates, rate
+// updates a second, and its number: tile itself and 0 when it isn't
+// animated
+func animFrame(tile *ebiten.Image, ticks uint64, rate int) (*ebiten.Image, int) {
+	a, ok := glyphAnims[tile]
+	if !ok || rate <= 0 {
+		return tile, 0
+	}
+	i := int(float64(ticks)*a.fps/float64(rate)) % len(a.frames)
+	return a.frames[i], i
+}
+
+// animFrames returns the frames of tile, tile alone when it isn't
+// animated
+func animFrames(tile *ebiten.Image) []*ebiten.Image {
+	if a, ok := glyphAnims[tile]; ok {
+		return a.frames
+	}
+	return []*ebiten.Image{tile}
+}
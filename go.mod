@@ -5,6 +5,7 @@ go 1.24.4
 require (
 	github.com/hajimehoshi/ebiten/v2 v2.8.8
 	github.com/olivierh59500/ym-player v0.0.0-20250607015657-bb5818debd02
+	golang.org/x/image v0.24.0
 )
 
 require (
@@ -12,7 +13,11 @@ require (
 	github.com/ebitengine/hideconsole v1.0.0 // indirect
 	github.com/ebitengine/oto/v3 v3.3.3 // indirect
 	github.com/ebitengine/purego v0.8.0 // indirect
+	github.com/hajimehoshi/go-mp3 v0.3.4 // indirect
 	github.com/jezek/xgb v1.1.1 // indirect
-	golang.org/x/sync v0.8.0 // indirect
+	github.com/jfreymuth/oggvorbis v1.0.5 // indirect
+	github.com/jfreymuth/vorbis v1.0.2 // indirect
+	golang.org/x/sync v0.11.0 // indirect
 	golang.org/x/sys v0.30.0 // indirect
+	golang.org/x/text v0.22.0 // indirect
 )
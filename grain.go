@@ -0,0 +1,251 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"math/rand"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Grain and palette quantization shader source
+//
+//go:embed shaders/grain.kage
+var grainShaderSrc []byte
+
+// stLevels is the number of levels per channel of the Atari ST palette
+const stLevels = 8
+
+// stPaletteSize is the number of colors of an ST low resolution screen
+const stPaletteSize = 16
+
+// paletteShrink is how much smaller the copy of the frame the palette
+// is picked from is
+const paletteShrink = 4
+
+// paletteRefresh is the number of frames a palette is kept for
+const paletteRefresh = 10
+
+// GrainEffect adds film grain to the final frame and optionally reduces
+// it to 16 colors of the ST palette, picked from the frame, with ordered
+// dithering
+type GrainEffect struct {
+	shader  *ebiten.Shader
+	source  *ebiten.Image
+	small   *ebiten.Image
+	pixels  []byte
+	palette []float32
+	age     int
+	rnd     *rand.Rand
+	offX    float64
+	offY    float64
+
+	Grain    float64
+	Quantize bool
+	Dither   bool
+}
+
+// NewGrainEffect creates the pass for canvases of size w x h. The grain
+// pattern of every frame is drawn from rnd, so recordings are
+// reproducible.
+func NewGrainEffect(w, h int, rnd *rand.Rand) (*GrainEffect, error) {
+	shader, err := ebiten.NewShader(grainShaderSrc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile grain shader: %w", err)
+	}
+	small := ebiten.NewImage(max(1, w/paletteShrink), max(1, h/paletteShrink))
+	return &GrainEffect{
+		shader:  shader,
+		source:  ebiten.NewImage(w, h),
+		small:   small,
+		pixels:  make([]byte, 4*small.Bounds().Dx()*small.Bounds().Dy()),
+		palette: make([]float32, 3*stPaletteSize),
+		rnd:     rnd,
+	}, nil
+}
+
+// Update moves the grain pattern
+func (e *GrainEffect) Update() {
+	e.offX = float64(e.rnd.Intn(1024))
+	e.offY = float64(e.rnd.Intn(1024))
+}
+
+// Apply runs the pass on the canvas
+func (e *GrainEffect) Apply(canvas *ebiten.Image) {
+	if e.Grain <= 0 && !e.Quantize {
+		return
+	}
+
+	op := &ebiten.DrawImageOptions{}
+	op.Blend = ebiten.BlendCopy
+	e.source.DrawImage(canvas, op)
+
+	quantize := float32(0)
+	if e.Quantize {
+		quantize = 1
+		// A new palette as soon as the quantization is switched on, then
+		// every few frames as the picture changes
+		if e.age == 0 {
+			e.pickPalette()
+		}
+		e.age = (e.age + 1) % paletteRefresh
+	} else {
+		e.age = 0
+	}
+	dither := float32(0)
+	if e.Dither {
+		dither = 1
+	}
+
+	b := e.source.Bounds()
+	sop := &ebiten.DrawRectShaderOptions{}
+	sop.Images[0] = e.source
+	sop.Uniforms = map[string]any{
+		"Quantize":    quantize,
+		"Palette":     e.palette,
+		"Dither":      dither,
+		"Grain":       float32(e.Grain),
+		"NoiseOffset": []float32{float32(e.offX), float32(e.offY)},
+	}
+	sop.Blend = ebiten.BlendCopy
+	canvas.DrawRectShader(b.Dx(), b.Dy(), e.shader, sop)
+}
+
+// pickPalette picks the palette of the frame in the source image from a
+// shrunk copy of it
+func (e *GrainEffect) pickPalette() {
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(1.0/paletteShrink, 1.0/paletteShrink)
+	op.Blend = ebiten.BlendCopy
+	e.small.DrawImage(e.source, op)
+	e.small.ReadPixels(e.pixels)
+
+	for i, c := range stPalette(e.pixels) {
+		for k := range 3 {
+			e.palette[3*i+k] = float32(c[k]) / (stLevels - 1)
+		}
+	}
+}
+
+// stColor is a color of the ST palette, with levels 0 to 7 per channel
+type stColor [3]int
+
+// stBox is a box of the color cube, holding the colors of the frame in
+// it and their pixel counts
+type stBox struct {
+	colors []stColor
+	counts []int
+}
+
+// stPalette picks stPaletteSize colors of the ST palette for the RGBA
+// pixels by median cut: the box of colors spanning the widest channel is
+// split at its median pixel until there are enough boxes, and every box
+// gives its average color. Missing entries repeat the first color.
+func stPalette(pixels []byte) [stPaletteSize]stColor {
+	var counts [stLevels * stLevels * stLevels]int
+	for i := 0; i+3 < len(pixels); i += 4 {
+		var c stColor
+		for k := range 3 {
+			c[k] = (int(pixels[i+k])*(stLevels-1) + 127) / 255
+		}
+		counts[(c[0]*stLevels+c[1])*stLevels+c[2]]++
+	}
+	var all stBox
+	for key, n := range counts {
+		if n > 0 {
+			all.colors = append(all.colors, stColor{key / (stLevels * stLevels), key / stLevels % stLevels, key % stLevels})
+			all.counts = append(all.counts, n)
+		}
+	}
+
+	boxes := []stBox{all}
+	for len(boxes) < stPaletteSize {
+		widest, channel, span := -1, 0, 0
+		for i, b := range boxes {
+			if len(b.colors) < 2 {
+				continue
+			}
+			for k := range 3 {
+				if s := b.span(k); s > span {
+					widest, channel, span = i, k, s
+				}
+			}
+		}
+		if widest < 0 {
+			break
+		}
+		low, high := boxes[widest].split(channel)
+		boxes[widest] = low
+		boxes = append(boxes, high)
+	}
+
+	var palette [stPaletteSize]stColor
+	for i := range palette {
+		palette[i] = boxes[0].average()
+		if i < len(boxes) {
+			palette[i] = boxes[i].average()
+		}
+	}
+	return palette
+}
+
+// span returns how many levels the colors of b cover in channel k
+func (b stBox) span(k int) int {
+	lo, hi := stLevels, -1
+	for _, c := range b.colors {
+		lo, hi = min(lo, c[k]), max(hi, c[k])
+	}
+	return hi - lo
+}
+
+// split sorts the colors of b along channel k and cuts it in two at the
+// median pixel, each half keeping at least one color
+func (b stBox) split(k int) (low, high stBox) {
+	order := make([]int, len(b.colors))
+	total := 0
+	for i := range order {
+		order[i] = i
+		total += b.counts[i]
+	}
+	slices.SortFunc(order, func(i, j int) int { return b.colors[i][k] - b.colors[j][k] })
+
+	cut, seen := 1, 0
+	for i, o := range order[:len(order)-1] {
+		seen += b.counts[o]
+		cut = i + 1
+		if 2*seen >= total {
+			break
+		}
+	}
+	for i, o := range order {
+		half := &low
+		if i >= cut {
+			half = &high
+		}
+		half.colors = append(half.colors, b.colors[o])
+		half.counts = append(half.counts, b.counts[o])
+	}
+	return low, high
+}
+
+// average returns the color of the ST palette nearest to the mean of the
+// pixels of b
+func (b stBox) average() stColor {
+	var sum [3]int
+	total := 0
+	for i, c := range b.colors {
+		for k := range 3 {
+			sum[k] += c[k] * b.counts[i]
+		}
+		total += b.counts[i]
+	}
+	var c stColor
+	if total == 0 {
+		return c
+	}
+	for k := range 3 {
+		c[k] = (2*sum[k] + total) / (2 * total)
+	}
+	return c
+}
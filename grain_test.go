@@ -0,0 +1,59 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// pixelsOf returns RGBA pixels of the colors, count of each
+func pixelsOf(colors [][3]byte, count int) []byte {
+	var pixels []byte
+	for _, c := range colors {
+		for range count {
+			pixels = append(pixels, c[0], c[1], c[2], 0xff)
+		}
+	}
+	return pixels
+}
+
+func TestSTPaletteKeepsFewColors(t *testing.T) {
+	colors := [][3]byte{{0, 0, 0}, {0xff, 0xff, 0xff}, {0x24, 0x48, 0xdb}}
+	palette := stPalette(pixelsOf(colors, 50))
+	for _, c := range []stColor{{0, 0, 0}, {7, 7, 7}, {1, 2, 6}} {
+		found := false
+		for _, p := range palette {
+			found = found || p == c
+		}
+		if !found {
+			t.Errorf("palette %v misses %v", palette, c)
+		}
+	}
+}
+
+func TestSTPaletteSize(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	var colors [][3]byte
+	for range 300 {
+		colors = append(colors, [3]byte{byte(rnd.Intn(256)), byte(rnd.Intn(256)), byte(rnd.Intn(256))})
+	}
+	palette := stPalette(pixelsOf(colors, 1))
+	seen := map[stColor]bool{}
+	for _, c := range palette {
+		for k := range 3 {
+			if c[k] < 0 || c[k] >= stLevels {
+				t.Fatalf("color %v outside the ST palette", c)
+			}
+		}
+		seen[c] = true
+	}
+	// Hundreds of colors leave no entry unused
+	if len(seen) != stPaletteSize {
+		t.Errorf("%d distinct colors in %v, want %d", len(seen), palette, stPaletteSize)
+	}
+}
+
+func TestGrainShader(t *testing.T) {
+	if _, err := NewGrainEffect(32, 32, rand.New(rand.NewSource(1))); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// halfMegProfile is the profile of the 512K mode
+const halfMegProfile = "halfmeg"
+
+// halfMegBadge is shown in the corner in 512K mode
+const halfMegBadge = "512K MODE"
+
+// halfMegScale is the size of the badge in the demo font
+const halfMegScale = 0.5
+
+// chooseHalfMegProfile selects the 512K mode profile with -halfmeg. The
+// scroll text says the screen must work on half a meg, so the demo makes
+// do with fewer and smaller letters and mono sound; the profile sets the
+// flags, which the command line still wins over.
+func (c *Config) chooseHalfMegProfile() {
+	if !c.HalfMeg || c.Profile == halfMegProfile {
+		return
+	}
+	if c.Profile != "" {
+		log.Printf("512K mode replaces the %s profile", c.Profile)
+	}
+	c.Profile = halfMegProfile
+}
+
+// drawHalfMeg shows the 512K mode badge in the top right corner
+func (g *Game) drawHalfMeg(screen *ebiten.Image) {
+	if !g.cfg.HalfMeg {
+		return
+	}
+	x := float64(screenWidth) - g.bigfont.Measure(halfMegBadge, halfMegScale) - 12
+	g.bigfont.DrawString(screen, halfMegBadge, x, 12, halfMegScale, nil)
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxHeadlines is the number of headlines kept from a feed
+const maxHeadlines = 10
+
+// HeadlineFetcher periodically pulls headlines from an RSS, Atom or
+// JSON endpoint. The last good headlines are kept when a fetch fails.
+type HeadlineFetcher struct {
+	URL      string
+	Interval time.Duration
+	Filter   *TextFilter
+
+	client    *http.Client
+	mu        sync.Mutex
+	headlines []string
+}
+
+// NewHeadlineFetcher starts fetching url every interval, passing the
+// headlines through filter
+func NewHeadlineFetcher(url string, interval time.Duration, filter *TextFilter) *HeadlineFetcher {
+	f := &HeadlineFetcher{
+		URL:      url,
+		Interval: interval,
+		Filter:   filter,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+	go f.run()
+	return f
+}
+
+func (f *HeadlineFetcher) run() {
+	for {
+		headlines, err := f.fetch()
+		if err != nil {
+			log.Printf("Failed to fetch headlines: %v", err)
+		} else {
+			f.mu.Lock()
+			f.headlines = headlines
+			f.mu.Unlock()
+		}
+		time.Sleep(f.Interval)
+	}
+}
+
+// Headlines returns the latest filtered headlines
+func (f *HeadlineFetcher) Headlines() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.headlines
+}
+
+func (f *HeadlineFetcher) fetch() ([]string, error) {
+	resp, err := f.client.Get(f.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feed: %w", err)
+	}
+	titles, err := parseHeadlines(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var headlines []string
+	for _, t := range titles {
+		if t, ok := f.Filter.Apply(t); ok {
+			headlines = append(headlines, t)
+		}
+		if len(headlines) == maxHeadlines {
+			break
+		}
+	}
+	if len(headlines) == 0 {
+		return nil, fmt.Errorf("feed has no headlines")
+	}
+	return headlines, nil
+}
+
+// parseHeadlines extracts the titles of an RSS or Atom document, or of
+// a JSON list of strings or of objects with a "title", bare or under
+// "items"
+func parseHeadlines(body []byte) ([]string, error) {
+	body = bytes.TrimSpace(body)
+	if bytes.HasPrefix(body, []byte("<")) {
+		var doc struct {
+			Items []struct {
+				Title string `xml:"title"`
+			} `xml:"channel>item"`
+			Entries []struct {
+				Title string `xml:"title"`
+			} `xml:"entry"`
+		}
+		if err := xml.Unmarshal(body, &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse feed: %w", err)
+		}
+		var titles []string
+		for _, it := range doc.Items {
+			titles = append(titles, it.Title)
+		}
+		for _, e := range doc.Entries {
+			titles = append(titles, e.Title)
+		}
+		return titles, nil
+	}
+
+	type item struct {
+		Title string `json:"title"`
+	}
+	var strs []string
+	if err := json.Unmarshal(body, &strs); err == nil {
+		return strs, nil
+	}
+	var items []item
+	if err := json.Unmarshal(body, &items); err != nil {
+		var wrapped struct {
+			Items []item `json:"items"`
+		}
+		if err := json.Unmarshal(body, &wrapped); err != nil {
+			return nil, fmt.Errorf("failed to parse feed: %w", err)
+		}
+		items = wrapped.Items
+	}
+	titles := make([]string, len(items))
+	for i, it := range items {
+		titles[i] = it.Title
+	}
+	return titles, nil
+}
+
+// spliceHeadlines inserts the headlines into text before every
+// waveform change but the first, so they run between the greeting
+// blocks. Without headlines the text is returned unchanged.
+func spliceHeadlines(text string, headlines []string) string {
+	if len(headlines) == 0 {
+		return text
+	}
+
+	var b strings.Builder
+	codes := 0
+	for i := 0; i < len(text); i++ {
+		if controlCodeLen(text, i) == 2 && isFormCode(text[i+1]) {
+			if codes > 0 {
+				b.WriteString("     NEWS: " + headlines[(codes-1)%len(headlines)] + "     ")
+			}
+			codes++
+		}
+		b.WriteByte(text[i])
+	}
+	return b.String()
+}
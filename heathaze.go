@@ -0,0 +1,31 @@
+package main
+
+import (
+	"image"
+	"math/rand"
+)
+
+const (
+	// The haze covers the far rows of the lower landscape, just below
+	// the gap holding the logo (papercanvas2 coordinates)
+	hazeTop    = 236
+	hazeHeight = 48
+)
+
+// NewHeatHaze creates the shimmer over the horizon rows of a landscape
+// of the given width, rising slowly through a noise field drawn from rnd
+func NewHeatHaze(displacer *Displacer, width int, intensity float64, rnd *rand.Rand) *DisplacementEffect {
+	texture := NewNoiseDisplacementTexture(128, 64, 8, rnd)
+
+	// Strongest at the horizon, fading out towards the foreground
+	mask := NewDisplacementFadeMask(width, hazeHeight, func(y int) float64 {
+		return 1 - float64(y)/hazeHeight
+	})
+
+	haze := NewDisplacementEffect(displacer, image.Rect(0, hazeTop, width, hazeTop+hazeHeight), texture, mask)
+	haze.ScrollX = 0.1
+	haze.ScrollY = -0.4
+	haze.AmountX = intensity
+	haze.AmountY = intensity / 2
+	return haze
+}
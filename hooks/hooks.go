@@ -0,0 +1,143 @@
+// Package hooks lets extensions run code at fixed points of every demo
+// frame without touching the core loop. Extensions usually register
+// their callbacks from an init function:
+//
+//	func init() {
+//		hooks.Register(hooks.PostDraw, "recorder", func(ctx *hooks.Context) error {
+//			return capture(ctx.Screen)
+//		})
+//	}
+package hooks
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Point is a moment of the frame at which hooks run
+type Point int
+
+const (
+	// PreUpdate runs before the demo state advances
+	PreUpdate Point = iota
+	// PostUpdate runs after the demo state advanced
+	PostUpdate
+	// PreDraw runs before anything is drawn
+	PreDraw
+	// PostDraw runs once the frame is on the screen, before the overlays
+	// (options menu, ...) are drawn over it
+	PostDraw
+
+	numPoints
+)
+
+var pointNames = [...]string{"pre-update", "post-update", "pre-draw", "post-draw"}
+
+func (p Point) String() string {
+	if p < 0 || p >= numPoints {
+		return fmt.Sprintf("Point(%d)", int(p))
+	}
+	return pointNames[p]
+}
+
+// Effects is the part of the effect registry available to hooks
+type Effects interface {
+	Enabled(name string) bool
+	SetEnabled(name string, enabled bool)
+	Toggle(name string) bool
+}
+
+// Context describes the frame being processed
+type Context struct {
+	// Frame counts the updates since the demo started
+	Frame uint64
+
+	// Effects switches the registered visual effects
+	Effects Effects
+
+	// Canvases are the intermediate planes by name ("landscape",
+	// "logo", "scroller", "composite")
+	Canvases map[string]*ebiten.Image
+
+	// Screen is the final frame, only set for the draw points
+	Screen *ebiten.Image
+}
+
+// Func is a hook callback. Errors returned from the update points stop
+// the demo, errors from the draw points are only reported.
+type Func func(ctx *Context) error
+
+type entry struct {
+	name string
+	fn   Func
+}
+
+// Registry keeps the hooks of every point in registration order
+type Registry struct {
+	mu      sync.RWMutex
+	hooks   [numPoints][]entry
+	letters []letterEntry
+}
+
+// Register adds a named hook at the given point
+func (r *Registry) Register(p Point, name string, fn Func) {
+	if p < 0 || p >= numPoints {
+		panic(fmt.Sprintf("hooks: invalid point %d", int(p)))
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hooks[p] = append(r.hooks[p], entry{name: name, fn: fn})
+}
+
+// Unregister removes every hook registered under name, letter hooks
+// included
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for p := range r.hooks {
+		var kept []entry
+		for _, e := range r.hooks[p] {
+			if e.name != name {
+				kept = append(kept, e)
+			}
+		}
+		r.hooks[p] = kept
+	}
+
+	var kept []letterEntry
+	for _, e := range r.letters {
+		if e.name != name {
+			kept = append(kept, e)
+		}
+	}
+	r.letters = kept
+}
+
+// Run calls the hooks of a point, stopping at the first error
+func (r *Registry) Run(p Point, ctx *Context) error {
+	r.mu.RLock()
+	entries := r.hooks[p]
+	r.mu.RUnlock()
+
+	for _, e := range entries {
+		if err := e.fn(ctx); err != nil {
+			return fmt.Errorf("%s hook %q: %w", p, e.name, err)
+		}
+	}
+	return nil
+}
+
+// Default is the registry used by the demo
+var Default = &Registry{}
+
+// Register adds a hook to the default registry
+func Register(p Point, name string, fn Func) {
+	Default.Register(p, name, fn)
+}
+
+// Unregister removes a hook from the default registry
+func Unregister(name string) {
+	Default.Unregister(name)
+}
@@ -0,0 +1,67 @@
+package hooks
+
+import "github.com/hajimehoshi/ebiten/v2"
+
+// Letter is one scroller letter about to be drawn. Letter hooks may
+// move, rotate, rescale, recolor or skip it.
+type Letter struct {
+	// Frame counts the scroller updates
+	Frame uint64
+
+	// Slot is the position of the letter in the visible line, 0 being
+	// the oldest letter
+	Slot int
+	Char byte
+
+	// Center on the scroller canvas and projection scale
+	X, Y  float64
+	Scale float64
+
+	// Angle rotates the letter around its center, in radians clockwise
+	Angle float64
+
+	// Tilt grows the right and bottom edges of the letter by these
+	// fractions of Scale and shrinks the left and top ones as much,
+	// turning it in perspective. Zero draws it flat.
+	Tilt [2]float64
+
+	// Color scales the letter. The rasters recolor every letter
+	// afterwards, keeping only its alpha, unless NoRaster is set.
+	Color    ebiten.ColorScale
+	NoRaster bool
+
+	// Skip leaves the letter out of this frame
+	Skip bool
+}
+
+// LetterFunc is a letter hook
+type LetterFunc func(l *Letter)
+
+type letterEntry struct {
+	name string
+	fn   LetterFunc
+}
+
+// RegisterLetter adds a named hook called for every letter of every
+// frame, in registration order
+func (r *Registry) RegisterLetter(name string, fn LetterFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.letters = append(r.letters, letterEntry{name: name, fn: fn})
+}
+
+// RunLetter calls the letter hooks on l
+func (r *Registry) RunLetter(l *Letter) {
+	r.mu.RLock()
+	entries := r.letters
+	r.mu.RUnlock()
+
+	for _, e := range entries {
+		e.fn(l)
+	}
+}
+
+// RegisterLetter adds a letter hook to the default registry
+func RegisterLetter(name string, fn LetterFunc) {
+	Default.RegisterLetter(name, fn)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// MountainLayer is a strip of the background image scrolling at its own
+// speed: rows Top to Bottom (excluded) of the image, drawn at row Y of
+// the landscape canvas
+type MountainLayer struct {
+	Top, Bottom int
+	Y           int
+	Speed       float64 // ST pixels a frame
+}
+
+// Height returns the rows of the strip
+func (l MountainLayer) Height() int {
+	return l.Bottom - l.Top
+}
+
+// defaultMountainLayers is the layout of the built-in mountains.png: 32
+// strips of 10 rows, 16 above the logo and 16 below it, each half
+// slowing down from 8 to 0.5 pixels a frame
+func defaultMountainLayers() []MountainLayer {
+	layers := make([]MountainLayer, 32)
+	for i := range layers {
+		y := i * 10
+		if i >= 16 {
+			// The gap holding the logo
+			y += 84
+		}
+		layers[i] = MountainLayer{Top: i * 10, Bottom: i*10 + 10, Y: y, Speed: 8 - float64(i%16)*0.5}
+	}
+	return layers
+}
+
+// LoadMountainLayers reads a layer descriptor file
+func LoadMountainLayers(path string) ([]MountainLayer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mountain layers: %w", err)
+	}
+	defer f.Close()
+	layers, err := ParseMountainLayers(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return layers, nil
+}
+
+// ParseMountainLayers reads one layer a line, back to front:
+//
+//	top-bottom speed [y]
+//
+// top-bottom are the rows of the strip in the image, bottom excluded,
+// speed its scrolling in ST pixels a frame and y the canvas row it is
+// drawn at, right under the layer before when left out. Blank lines and
+// lines starting with # are skipped.
+func ParseMountainLayers(r io.Reader) ([]MountainLayer, error) {
+	var layers []MountainLayer
+	next := 0
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, fmt.Errorf("line %d: want top-bottom speed [y]", n)
+		}
+
+		var l MountainLayer
+		top, bottom, ok := strings.Cut(fields[0], "-")
+		var err1, err2 error
+		l.Top, err1 = strconv.Atoi(top)
+		l.Bottom, err2 = strconv.Atoi(bottom)
+		if !ok || err1 != nil || err2 != nil || l.Top < 0 || l.Bottom <= l.Top {
+			return nil, fmt.Errorf("line %d: invalid rows %q", n, fields[0])
+		}
+		speed, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil || speed < 0 {
+			return nil, fmt.Errorf("line %d: invalid speed %q", n, fields[1])
+		}
+		l.Speed = speed
+		l.Y = next
+		if len(fields) == 3 {
+			if l.Y, err = strconv.Atoi(fields[2]); err != nil {
+				return nil, fmt.Errorf("line %d: invalid canvas row %q", n, fields[2])
+			}
+		}
+		next = l.Y + l.Height()
+		layers = append(layers, l)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(layers) == 0 {
+		return nil, fmt.Errorf("no layers")
+	}
+	return layers, nil
+}
+
+// initLayers lays the mountains image out as set with -mountain-layers,
+// or as the built-in image
+func (g *Game) initLayers() {
+	g.layers = defaultMountainLayers()
+	if g.cfg.MountainLayers != "" {
+		layers, err := LoadMountainLayers(g.cfg.MountainLayers)
+		if err == nil {
+			err = checkMountainLayers(layers, g.mountains.Bounds().Dy())
+		}
+		if err != nil {
+			log.Printf("Failed to load mountain layers, using the built-in layout: %v", err)
+		} else {
+			g.layers = layers
+		}
+	}
+	g.bgPos = make([]float64, len(g.layers))
+}
+
+// checkMountainLayers reports layers reaching past the bottom of an
+// image of height rows
+func checkMountainLayers(layers []MountainLayer, height int) error {
+	for i, l := range layers {
+		if l.Bottom > height {
+			return fmt.Errorf("layer %d ends at row %d, past the %d rows of the image", i, l.Bottom, height)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// ScreenLayer is one of the planes of the screen the number keys hide
+// and show, to study each on its own, or freeze for composing screenshots
+type ScreenLayer int
+
+const (
+	// LayerMountains is the parallax landscape
+	LayerMountains ScreenLayer = iota
+	// LayerLogo is the distorted logo
+	LayerLogo
+	// LayerTCB is the rotating TCB text
+	LayerTCB
+	// LayerScroller is the 3D scroller and the other scroller planes
+	LayerScroller
+	// LayerRasters colors the letters, the font colors show without it
+	LayerRasters
+	// LayerEffects is the shader effects: haze, ripples, glow, bloom,
+	// grain and transitions
+	LayerEffects
+	layerCount
+)
+
+var screenLayerNames = []string{"Mountains", "Logo", "TCB text", "Scroller", "Rasters", "Post effects"}
+
+func (l ScreenLayer) String() string {
+	if l < LayerMountains || l >= layerCount {
+		return "unknown"
+	}
+	return screenLayerNames[l]
+}
+
+// layerFlagNames name the layers in -hide-layers
+var layerFlagNames = []string{"mountains", "logo", "tcb", "scroller", "rasters", "effects"}
+
+// LayerSet holds a flag per layer, settable from the command line as a
+// comma-separated list of layer names
+type LayerSet [layerCount]bool
+
+func (s *LayerSet) String() string {
+	var names []string
+	for l, in := range s {
+		if in {
+			names = append(names, layerFlagNames[l])
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// Set implements flag.Value
+func (s *LayerSet) Set(list string) error {
+	*s = LayerSet{}
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		l := 0
+		for l < len(layerFlagNames) && !strings.EqualFold(name, layerFlagNames[l]) {
+			l++
+		}
+		if l == len(layerFlagNames) {
+			return fmt.Errorf("unknown layer %q (want %s)", name, strings.Join(layerFlagNames, ", "))
+		}
+		s[l] = true
+	}
+	return nil
+}
+
+// layerKeys hide the layers, 1 to 6 without Shift, which selects the
+// subsongs, and freeze them with Ctrl
+var layerKeys = []ebiten.Key{
+	ebiten.Key1, ebiten.Key2, ebiten.Key3, ebiten.Key4, ebiten.Key5, ebiten.Key6,
+}
+
+// updateLayerKeys hides or freezes the layers of the number keys
+// pressed, and steps a frame with the period key while paused
+func (g *Game) updateLayerKeys() {
+	if g.paused && inpututil.IsKeyJustPressed(ebiten.KeyPeriod) {
+		g.stepFrame = true
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyShift) {
+		return
+	}
+	freeze := ebiten.IsKeyPressed(ebiten.KeyControl)
+	for i, key := range layerKeys {
+		switch {
+		case !inpututil.IsKeyJustPressed(key):
+		case freeze:
+			g.freezeLayer(ScreenLayer(i))
+		default:
+			g.toggleLayer(ScreenLayer(i))
+		}
+	}
+}
+
+// layerShown reports whether l is drawn
+func (g *Game) layerShown(l ScreenLayer) bool {
+	return !g.hiddenLayers[l]
+}
+
+// hideLayers hides the layers of -hide-layers
+func (g *Game) hideLayers() {
+	for l, hidden := range g.cfg.HideLayers {
+		if hidden {
+			g.setLayerHidden(ScreenLayer(l), true)
+		}
+	}
+}
+
+// toggleLayer hides l, or shows it again
+func (g *Game) toggleLayer(l ScreenLayer) {
+	hidden := !g.hiddenLayers[l]
+	g.setLayerHidden(l, hidden)
+
+	state := "shown"
+	if hidden {
+		state = "hidden"
+	}
+	g.notice.Show(l.String() + " " + state)
+}
+
+// setLayerHidden hides or shows l
+func (g *Game) setLayerHidden(l ScreenLayer, hidden bool) {
+	g.hiddenLayers[l] = hidden
+	switch l {
+	case LayerRasters:
+		// The letters keep the colors of their font
+		for _, s := range g.allScrollers() {
+			s.NoRasters = hidden
+		}
+	case LayerEffects:
+		g.effects.Bypass = hidden
+	}
+}
+
+// freezeLayer stops l from moving, or lets it move again. Frozen
+// layers stay still while the others play, or step with the period key
+// while paused.
+func (g *Game) freezeLayer(l ScreenLayer) {
+	g.frozenLayers[l] = !g.frozenLayers[l]
+	state := "moving"
+	if g.frozenLayers[l] {
+		state = "frozen"
+	}
+	g.notice.Show(l.String() + " " + state)
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// LyricsMode selects how the lyrics are shown
+type LyricsMode int
+
+const (
+	// LyricsKaraoke shows the current line at the bottom, sung words lit
+	LyricsKaraoke LyricsMode = iota
+	// LyricsScroll feeds the lines to the scroller as they are sung
+	LyricsScroll
+)
+
+var lyricsModeNames = []string{"karaoke", "scroll"}
+
+func (m LyricsMode) String() string {
+	if m < LyricsKaraoke || m > LyricsScroll {
+		return "unknown"
+	}
+	return lyricsModeNames[m]
+}
+
+// Set implements flag.Value
+func (m *LyricsMode) Set(s string) error {
+	for i, name := range lyricsModeNames {
+		if strings.EqualFold(s, name) {
+			*m = LyricsMode(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown lyrics mode %q (want %s)", s, strings.Join(lyricsModeNames, ", "))
+}
+
+// lyricsWordTime caps how long a word lasts when the file only times the
+// lines
+const lyricsWordTime = 600 * time.Millisecond
+
+// LyricWord is a word of a lyrics line and when it is sung
+type LyricWord struct {
+	At   time.Duration
+	Text string
+}
+
+// LyricLine is a line of lyrics and when it starts
+type LyricLine struct {
+	At    time.Duration
+	Text  string
+	Words []LyricWord
+}
+
+// Lyrics are the timed lines of an LRC file
+type Lyrics struct {
+	Title  string
+	Artist string
+	Lines  []LyricLine
+}
+
+// LoadLyrics reads an LRC file
+func LoadLyrics(path string) (*Lyrics, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lyrics: %w", err)
+	}
+	defer f.Close()
+	l, err := ParseLRC(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return l, nil
+}
+
+// ParseLRC reads lyrics in the LRC format: lines starting with one or
+// more [mm:ss.xx] times, the ti, ar and offset tags, and the <mm:ss.xx>
+// word times of enhanced LRC
+func ParseLRC(r io.Reader) (*Lyrics, error) {
+	l := &Lyrics{}
+	var offset time.Duration
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+
+		var times []time.Duration
+		for strings.HasPrefix(line, "[") {
+			end := strings.IndexByte(line, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unclosed tag", n)
+			}
+			tag := line[1:end]
+			line = strings.TrimSpace(line[end+1:])
+
+			if t, err := parseLRCTime(tag); err == nil {
+				times = append(times, t)
+				continue
+			}
+			key, value, _ := strings.Cut(tag, ":")
+			value = strings.TrimSpace(value)
+			switch strings.ToLower(key) {
+			case "ti":
+				l.Title = value
+			case "ar":
+				l.Artist = value
+			case "offset":
+				ms, err := strconv.Atoi(value)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: invalid offset %q", n, value)
+				}
+				// A positive offset shows the lyrics sooner
+				offset = -time.Duration(ms) * time.Millisecond
+			}
+		}
+
+		for _, t := range times {
+			l.Lines = append(l.Lines, parseLyricLine(t, line))
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(l.Lines) == 0 {
+		return nil, fmt.Errorf("no timed lines")
+	}
+
+	sort.SliceStable(l.Lines, func(i, j int) bool { return l.Lines[i].At < l.Lines[j].At })
+	for i := range l.Lines {
+		line := &l.Lines[i]
+		line.At = max(0, line.At+offset)
+		for w := range line.Words {
+			if line.Words[w].At >= 0 {
+				line.Words[w].At = max(0, line.Words[w].At+offset)
+			}
+		}
+		if i+1 < len(l.Lines) {
+			line.spread(l.Lines[i+1].At + offset)
+		} else {
+			line.spread(line.At + time.Duration(len(line.Words))*lyricsWordTime)
+		}
+	}
+	return l, nil
+}
+
+// parseLRCTime reads mm:ss, mm:ss.xx or mm:ss.xxx
+func parseLRCTime(s string) (time.Duration, error) {
+	m, rest, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, fmt.Errorf("no minutes in %q", s)
+	}
+	min, err := strconv.Atoi(m)
+	if err != nil || min < 0 {
+		return 0, fmt.Errorf("invalid minutes in %q", s)
+	}
+	sec, err := strconv.ParseFloat(rest, 64)
+	if err != nil || sec < 0 || sec >= 60 {
+		return 0, fmt.Errorf("invalid seconds in %q", s)
+	}
+	return time.Duration(min)*time.Minute + time.Duration(sec*float64(time.Second)), nil
+}
+
+// parseLyricLine splits the text of a line into words, reading the
+// <mm:ss.xx> word times when present. A time inside a word splits it into
+// syllables; the space following a word stays in its text. Untimed words
+// get their time from spread.
+func parseLyricLine(at time.Duration, text string) LyricLine {
+	line := LyricLine{At: at}
+	wordAt := time.Duration(-1)
+	text = strings.TrimSpace(text)
+	for text != "" {
+		if text[0] == '<' {
+			if end := strings.IndexByte(text, '>'); end > 0 {
+				if t, err := parseLRCTime(text[1:end]); err == nil {
+					wordAt = t
+					text = text[end+1:]
+					continue
+				}
+			}
+		}
+		if text[0] == ' ' || text[0] == '\t' {
+			// Collapse the spaces into the previous word
+			text = strings.TrimLeft(text, " \t")
+			if n := len(line.Words); n > 0 && text != "" {
+				line.Words[n-1].Text += " "
+			}
+			continue
+		}
+
+		end := len(text)
+		if i := strings.IndexAny(text[1:], " \t<"); i >= 0 {
+			end = i + 1
+		}
+		line.Words = append(line.Words, LyricWord{At: wordAt, Text: text[:end]})
+		text = text[end:]
+		wordAt = -1
+	}
+
+	var b strings.Builder
+	for _, w := range line.Words {
+		b.WriteString(w.Text)
+	}
+	line.Text = b.String()
+	return line
+}
+
+// spread times the words that have no time of their own, evenly between
+// the timed words around them or the end of the line, each lasting at
+// most lyricsWordTime
+func (l *LyricLine) spread(end time.Duration) {
+	for i := 0; i < len(l.Words); i++ {
+		if l.Words[i].At >= 0 {
+			continue
+		}
+		j := i
+		for j < len(l.Words) && l.Words[j].At < 0 {
+			j++
+		}
+		until := end
+		if j < len(l.Words) {
+			until = l.Words[j].At
+		}
+
+		// The first word starts with the line, the others after the
+		// word before them
+		from, gaps := l.At, j-i
+		if i > 0 {
+			from, gaps = l.Words[i-1].At, j-i+1
+		}
+		step := min(lyricsWordTime, max(0, until-from)/time.Duration(gaps))
+		for k := i; k < j; k++ {
+			if k == 0 {
+				l.Words[k].At = from
+			} else {
+				l.Words[k].At = l.Words[k-1].At + step
+			}
+		}
+		i = j
+	}
+}
+
+// Line returns the index of the line sung at t, -1 before the first one
+func (l *Lyrics) Line(t time.Duration) int {
+	return sort.Search(len(l.Lines), func(i int) bool { return l.Lines[i].At > t }) - 1
+}
+
+// lyricsPlayer follows the music through the lyrics
+type lyricsPlayer struct {
+	lyrics *Lyrics
+	mode   LyricsMode
+	line   int           // line sung, -1 for none
+	at     time.Duration // position in the tune
+	loops  int
+	start  time.Duration // music time at which the current loop began
+	canvas *ebiten.Image
+}
+
+// initLyrics loads the lyrics set in the config
+func (g *Game) initLyrics() error {
+	l, err := LoadLyrics(g.cfg.Lyrics)
+	if err != nil {
+		return err
+	}
+	g.lyrics = &lyricsPlayer{lyrics: l, mode: g.cfg.LyricsMode, line: -1}
+	if g.lyrics.mode == LyricsScroll && g.scroller.Feed == nil {
+		// The lyrics become the scroll text
+		g.scroller.Text = strings.Repeat(" ", scrollLetters+2)
+		g.scroller.Feed = NewTextQueue(g.cfg.StdinQueue)
+		g.scroller.OnWrap = nil
+	}
+	return nil
+}
+
+// updateLyrics moves to the line sung now, feeding it to the scroller in
+// scroll mode
+func (g *Game) updateLyrics() {
+	p := g.lyrics
+	if p == nil || g.music == nil {
+		return
+	}
+
+	elapsed := g.music.Elapsed()
+	if loops := g.music.Loops(); loops != p.loops || elapsed < p.start {
+		// The tune looped or another one started: sing again
+		p.loops = loops
+		p.start = elapsed
+		p.line = -1
+	}
+	p.at = elapsed - p.start
+	if l, ok := g.music.(interface{ Length() time.Duration }); ok && l.Length() > 0 {
+		// Follows the music when it is scrubbed through
+		p.at = elapsed % l.Length()
+	}
+
+	line := p.lyrics.Line(p.at)
+	if line == p.line {
+		return
+	}
+	p.line = line
+	if line >= 0 && p.mode == LyricsScroll && g.scroller.Feed != nil {
+		if text := p.lyrics.Lines[line].Text; text != "" {
+			g.scroller.Feed.Push(fontCase(text))
+		}
+	}
+}
+
+// drawLyrics renders the current line at the bottom of the screen in
+// karaoke mode, lighting the words already sung
+func (g *Game) drawLyrics(screen *ebiten.Image) {
+	p := g.lyrics
+	if p == nil || p.mode != LyricsKaraoke || p.line < 0 {
+		return
+	}
+	line := p.lyrics.Lines[p.line]
+	if len(line.Words) == 0 {
+		return
+	}
+	if p.canvas == nil {
+		p.canvas = ebiten.NewImage(screenWidth, 16)
+	}
+
+	// Debug font glyphs are 6x16
+	w := len(line.Text)*6 + 16
+	x := (screenWidth - w) / 2
+	y := screenHeight - 40
+	vector.DrawFilledRect(screen, float32(x), float32(y), float32(w), 24, color.RGBA{0, 0, 0, 0xc0}, false)
+
+	col := 0
+	for _, word := range line.Words {
+		p.canvas.Clear()
+		ebitenutil.DebugPrint(p.canvas, word.Text)
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Translate(float64(x+8+col*6), float64(y+4))
+		if word.At > p.at {
+			op.ColorScale.Scale(0.4, 0.4, 0.5, 1)
+		} else {
+			op.ColorScale.Scale(1, 0.85, 0.2, 1)
+		}
+		screen.DrawImage(p.canvas, op)
+		col += len(word.Text)
+	}
+}
@@ -3,6 +3,8 @@ package main
 import (
 	"bytes"
 	_ "embed"
+	"errors"
+	"flag"
 	"fmt"
 	"image"
 	"image/color"
@@ -10,13 +12,18 @@ import (
 	"io"
 	"log"
 	"math"
-	"sort"
+	"os"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/audio"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"github.com/olivierh59500/ym-player/pkg/stsound"
+
+	"tcb-multi-plane-3d-scroller/hooks"
 )
 
 const (
@@ -37,25 +44,25 @@ var (
 	logoData []byte
 	//go:embed assets/bgfont.png
 	fontData []byte
-	//go:embed assets/Thundercats.ym
-	musicData []byte
 )
 
-// ScrollForm defines parameters for scroll wave forms
-type ScrollForm struct {
-	zSize   float64
-	zAmount float64
-	zSpeed  float64
-	zAdd    float64
-	ySize   float64
-	yAmount float64
-	ySpeed  float64
-}
-
 // PrintPos represents a character position for 3D rendering
 type PrintPos struct {
 	x, y, z float64
 	letter  string
+	slot    int
+	color   int // color bank, 0 for the rasters
+	font    int // font face, the last one when out of range
+	// Per letter state of the ^W effects: the effect and the letter's
+	// number in the text, phasing it along the word
+	emphasis int
+	n        int
+	stamp    string  // image of a stampMarker letter
+	angle    float64 // rotation of the waveform
+	tilt     [2]float64
+	// Depth fog: darkening and fading from 0 to 1
+	dark, fade float64
+	grow       float64 // size of the letter entering, from 0 to 1
 }
 
 // YMPlayer wraps the YM player for Ebiten audio
@@ -67,7 +74,17 @@ type YMPlayer struct {
 	position     int64
 	totalSamples int64
 	loop         bool
-	volume       float64
+	volume       volumeRamp
+	fade         volumeRamp
+	levels       [3]float64
+	envelope     [3]bool
+	regs         [14]int
+	voices       psgVoices
+	scope        *scopeRing
+	stereo       YMStereo
+	dc           [2]float64 // offset of the stereo voices
+	filter       [2]stFilter
+	format       PCMFormat
 }
 
 // NewYMPlayer creates a new YM player instance
@@ -90,64 +107,317 @@ func NewYMPlayer(data []byte, sampleRate int, loop bool) (*YMPlayer, error) {
 		buffer:       make([]int16, 4096),
 		totalSamples: totalSamples,
 		loop:         loop,
-		volume:       0.7,
+		volume:       newVolumeRamp(0.7, sampleRate),
+		fade:         newVolumeRamp(1, sampleRate),
+		scope:        newScopeRing(3),
+		filter:       [2]stFilter{newSTFilter(sampleRate), newSTFilter(sampleRate)},
 	}, nil
 }
 
+// SetChunkSize sets how many samples the replayer computes at once.
+// Smaller chunks follow the registers more closely, larger ones cost
+// less.
+func (y *YMPlayer) SetChunkSize(n int) {
+	y.mutex.Lock()
+	defer y.mutex.Unlock()
+	y.buffer = make([]int16, max(64, n))
+}
+
+// SetPCMFormat sets the sample format Read produces, 16-bit by default
+// as Ebiten plays it. Seek offsets are in bytes of that format.
+func (y *YMPlayer) SetPCMFormat(f PCMFormat) {
+	y.mutex.Lock()
+	defer y.mutex.Unlock()
+	y.format = f
+}
+
 // Read implements io.Reader for audio streaming
-func (y *YMPlayer) Read(p []byte) (n int, err error) {
+func (y *YMPlayer) Read(p []byte) (int, error) {
 	y.mutex.Lock()
 	defer y.mutex.Unlock()
+	if y.player == nil {
+		return 0, io.EOF
+	}
 
-	samplesNeeded := len(p) / 4
-	outBuffer := make([]int16, samplesNeeded*2)
+	size := y.format.FrameSize()
+	samplesNeeded := len(p) / size
 
 	processed := 0
 	for processed < samplesNeeded {
-		chunkSize := samplesNeeded - processed
-		if chunkSize > len(y.buffer) {
-			chunkSize = len(y.buffer)
+		chunkSize := min(samplesNeeded-processed, len(y.buffer))
+		if !y.loop && y.totalSamples > 0 {
+			// The replayer only notices the end on the chunk after it
+			chunkSize = min(chunkSize, int(max(0, y.totalSamples-y.position)))
+		}
+		if y.stereo != YMStereoMono {
+			// The voices follow the registers read after every chunk
+			chunkSize = min(chunkSize, max(1, y.sampleRate/ymFrameRate/ymStereoSteps))
 		}
 
-		if !y.player.Compute(y.buffer[:chunkSize], chunkSize) {
+		if chunkSize == 0 || !y.player.Compute(y.buffer[:chunkSize], chunkSize) {
 			if !y.loop {
-				for i := processed * 2; i < len(outBuffer); i++ {
-					outBuffer[i] = 0
-				}
-				err = io.EOF
-				break
+				// The tune ended, only what it played counts
+				return processed * size, io.EOF
 			}
 		}
 
+		y.updateLevels()
+		gains := y.stereo.gains()
+
+		out := p[processed*size:]
 		for i := 0; i < chunkSize; i++ {
-			sample := int16(float64(y.buffer[i]) * y.volume)
-			outBuffer[(processed+i)*2] = sample
-			outBuffer[(processed+i)*2+1] = sample
+			y.scope.push(y.voices.next(&y.regs, &y.levels, y.sampleRate))
+			left, right := float64(y.buffer[i]), float64(y.buffer[i])
+			if y.stereo != YMStereoMono {
+				left, right = y.stereoFrame(&gains)
+			}
+			volume := y.volume.next() * y.fade.next()
+			y.format.putFrame(out[i*size:], y.filter[0].process(left)*volume, y.filter[1].process(right)*volume)
 		}
 
 		processed += chunkSize
 		y.position += int64(chunkSize)
 	}
+	return samplesNeeded * size, nil
+}
+
+// renderChunk is how many samples RenderTo computes at once
+const renderChunk = 4096
+
+// RenderTo renders d of the music into w as interleaved stereo PCM in
+// the format set with SetPCMFormat, as fast as it computes, without an
+// audio context. It stops early at the end of a tune that doesn't loop,
+// and returns the bytes written. The same state and duration always give
+// the same samples.
+func (y *YMPlayer) RenderTo(w io.Writer, d time.Duration) (int64, error) {
+	y.mutex.Lock()
+	size := int64(y.format.FrameSize())
+	y.mutex.Unlock()
+
+	buf := make([]byte, renderChunk*size)
+	left := int64(d) * int64(y.sampleRate) / int64(time.Second)
+	var written int64
+	for left > 0 {
+		p := buf[:min(left, renderChunk)*size]
+		n, err := y.Read(p)
+		if n > 0 {
+			m, werr := w.Write(p[:n])
+			written += int64(m)
+			if werr != nil {
+				return written, werr
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return written, err
+		}
+		left -= int64(n) / size
+	}
+	return written, nil
+}
+
+// updateLevels samples the PSG registers and the volume of the three
+// channels
+func (y *YMPlayer) updateLevels() {
+	for r := range y.regs {
+		y.regs[r] = y.player.GetRegister(r)
+	}
+	mixer := y.regs[7]
+	for c := 0; c < 3; c++ {
+		// Channel muted when both tone and noise are disabled
+		if mixer&(1<<c) != 0 && mixer&(8<<c) != 0 {
+			y.levels[c] = 0
+			continue
+		}
+
+		vol := y.regs[8+c]
+		y.envelope[c] = vol&0x10 != 0
+		if vol&0x10 != 0 {
+			// Envelope mode, treat as full volume
+			y.levels[c] = 1
+		} else {
+			y.levels[c] = float64(vol&0x0f) / 15
+		}
+	}
+}
+
+// ChannelLevels returns the current volume of the three PSG channels
+// (A, B, C) in the range [0,1]
+func (y *YMPlayer) ChannelLevels() []float64 {
+	y.mutex.Lock()
+	defer y.mutex.Unlock()
+	return append([]float64(nil), y.levels[:]...)
+}
+
+// Scope returns the last samples of the three voices
+func (y *YMPlayer) Scope() [][]float32 {
+	y.mutex.Lock()
+	defer y.mutex.Unlock()
+	return y.scope.copy()
+}
+
+// ymFrameRate is the replay rate of YM files made on the ST
+const ymFrameRate = 50
+
+// Sync returns the frame and voice state at the last audio callback
+func (y *YMPlayer) Sync() MusicSync {
+	y.mutex.Lock()
+	defer y.mutex.Unlock()
+	return MusicSync{
+		Frame:    y.position * ymFrameRate / int64(y.sampleRate),
+		Levels:   append([]float64(nil), y.levels[:]...),
+		Envelope: append([]bool(nil), y.envelope[:]...),
+	}
+}
 
-	buf := make([]byte, 0, len(outBuffer)*2)
-	for _, sample := range outBuffer {
-		buf = append(buf, byte(sample), byte(sample>>8))
+// Loops returns how many times the tune has been played through
+func (y *YMPlayer) Loops() int {
+	y.mutex.Lock()
+	defer y.mutex.Unlock()
+	if y.totalSamples <= 0 {
+		return 0
 	}
+	return int(y.position / y.totalSamples)
+}
+
+// Elapsed returns how long the tune has played, loops included
+func (y *YMPlayer) Elapsed() time.Duration {
+	y.mutex.Lock()
+	defer y.mutex.Unlock()
+	return time.Duration(y.position) * time.Second / time.Duration(y.sampleRate)
+}
+
+// Volume returns the volume set last
+func (y *YMPlayer) Volume() float64 {
+	y.mutex.Lock()
+	defer y.mutex.Unlock()
+	return y.volume.target
+}
 
-	copy(p, buf)
-	n = len(buf)
-	if n > len(p) {
-		n = len(p)
+// SetVolume ramps the volume to v
+func (y *YMPlayer) SetVolume(v float64) {
+	y.mutex.Lock()
+	defer y.mutex.Unlock()
+	y.volume.target = max(0, min(1, v))
+}
+
+// FadeOut fades the tune to silence over d
+func (y *YMPlayer) FadeOut(d time.Duration) {
+	y.mutex.Lock()
+	defer y.mutex.Unlock()
+	y.fade.fadeOut(d, y.sampleRate)
+}
+
+// Info returns the song name, author and comment of the YM header
+func (y *YMPlayer) Info() SongInfo {
+	y.mutex.Lock()
+	defer y.mutex.Unlock()
+	if y.player == nil {
+		return SongInfo{}
 	}
+	info := y.player.GetInfo()
+	return SongInfo{Name: info.SongName, Author: info.SongAuthor, Comment: info.SongComment}
+}
 
-	return n, err
+// Title returns the song name and author stored in the file
+func (y *YMPlayer) Title() string {
+	info := y.Info()
+	if info.Author != "" && info.Name != "" {
+		return info.Name + " by " + info.Author
+	}
+	return info.Name
 }
 
-// Seek implements io.Seeker
+// Length returns the duration of the tune, from the YM header
+func (y *YMPlayer) Length() time.Duration {
+	y.mutex.Lock()
+	defer y.mutex.Unlock()
+	return time.Duration(y.totalSamples) * time.Second / time.Duration(y.sampleRate)
+}
+
+// Subsongs returns 1, a YM file holds a single tune
+func (y *YMPlayer) Subsongs() int {
+	return 1
+}
+
+// SetSubsong restarts the tune, the only subsong of a YM file
+func (y *YMPlayer) SetSubsong(n int) error {
+	if n != 0 {
+		return fmt.Errorf("no subsong %d, YM files hold a single tune", n+1)
+	}
+	y.mutex.Lock()
+	defer y.mutex.Unlock()
+	if y.player == nil {
+		return errYMClosed
+	}
+	y.player.Restart()
+	y.position = 0
+	return nil
+}
+
+// Seek implements io.Seeker. Offsets are in bytes of the stereo stream
+// in its PCM format; the replayer jumps there directly when the file allows it and
+// is restarted and fast-forwarded otherwise.
 func (y *YMPlayer) Seek(offset int64, whence int) (int64, error) {
-	return y.position, nil
+	y.mutex.Lock()
+	defer y.mutex.Unlock()
+	if y.player == nil {
+		return 0, errYMClosed
+	}
+
+	frameSize := int64(y.format.FrameSize())
+	var target int64
+	switch whence {
+	case io.SeekStart:
+		target = offset
+	case io.SeekCurrent:
+		target = y.position*frameSize + offset
+	case io.SeekEnd:
+		target = y.totalSamples*frameSize + offset
+	default:
+		return 0, fmt.Errorf("invalid whence %d", whence)
+	}
+	if target < 0 {
+		return 0, fmt.Errorf("negative position %d", target)
+	}
+	frame := target / frameSize
+
+	// Position within the tune, later loops play the same samples
+	inTune := frame
+	if y.totalSamples > 0 {
+		inTune = frame % y.totalSamples
+	}
+
+	if y.player.IsSeekable() {
+		y.player.Seek(uint32(inTune * 1000 / int64(y.sampleRate)))
+	} else {
+		skip := inTune
+		if y.totalSamples > 0 {
+			current := y.position % y.totalSamples
+			if inTune >= current {
+				skip = inTune - current
+			} else {
+				y.player.Restart()
+			}
+		} else {
+			y.player.Restart()
+		}
+		for skip > 0 {
+			n := int(min(skip, int64(len(y.buffer))))
+			y.player.Compute(y.buffer[:n], n)
+			skip -= int64(n)
+		}
+	}
+
+	y.position = frame
+	return frame * frameSize, nil
 }
 
+// errYMClosed is returned by the calls that move a closed YMPlayer
+var errYMClosed = errors.New("YM player is closed")
+
 // Close releases resources
 func (y *YMPlayer) Close() error {
 	y.mutex.Lock()
@@ -162,6 +432,9 @@ func (y *YMPlayer) Close() error {
 
 // Game represents the TCB demo state
 type Game struct {
+	cfg *Config
+	rng *RNG // random streams of every module, from -seed
+
 	// Images
 	rasters   *ebiten.Image
 	mountains *ebiten.Image
@@ -177,21 +450,54 @@ type Game struct {
 	thecanvas    *ebiten.Image
 	thecanvas2   *ebiten.Image
 
-	// Font tiles
+	// Font tiles, the columns each glyph covers and the font drawing
+	// UI strings with them
 	fontTiles map[rune]*ebiten.Image
+	fontSpans map[rune][2]int
+	fontName  string
+	bigfont   *BigFont
+
+	// Fonts of -extra-font, more faces for the ^F codes
+	extraFonts     []map[rune]*ebiten.Image
+	extraSpans     []map[rune][2]int
+	extraFontNames []string
+
+
+	// Background parallax: the strips of the mountains image and where
+	// each has scrolled to
+	layers         []MountainLayer
+	bgPos          []float64
+	landscapeTicks Ticks
+
+	// Raster interrupt emulation, nil without -scanlines
+	scanlines *ScanlineTable
 
-	// Background parallax
-	bgSpeed []float64
-	bgPos   []float64
+	// 3D scroller
+	scroller *Scroller
+	baseText string // scroll text before any splicing
 
-	// Scroll parameters
-	scrollForms []ScrollForm
-	form        int
-	scrollX     float64
-	scrollText  string
-	addi        int
-	sinAdder    float64
-	printPos    []PrintPos
+	// Time for the corner clock and the scroll text
+	clock *Clock
+
+	// Chat messages scrolling along the bottom, nil when disabled
+	chat       *Scroller
+	chatcanvas *ebiten.Image
+
+	// Scroller planes of -scroller2 beside the main one
+	planes []*Scroller
+
+	// Remote control API, nil when disabled
+	remote         *RemoteServer
+	remoteText     remoteText
+	remoteCommands chan func() // run by Update on the game loop
+	events         *EventStream
+	metrics        *Metrics
+
+	// Headlines from the text feed, nil when disabled
+	headlines *HeadlineFetcher
+
+	// Top edge of the distorted logo, for letters following it
+	logoContour []float64
 
 	// Logo animation
 	logoSin     []float64
@@ -200,15 +506,120 @@ type Game struct {
 	rotAdd      float64
 	next        int
 
+	// Post effects
+	effects *EffectRegistry
+	blurrer *Blurrer
+	wobble  *WobbleTransition
+	grain   *GrainEffect
+	options *OptionsMenu
+	about   *AboutOverlay
+	memory  *MemoryMonitor
+	pointer *Pointer // nil for the system pointer
+	seekBar int      // frames the music progress bar stays on screen
+	credits *CreditsOverlay
+
+	// Display calibration of the final pass, nil when its shader failed
+	calibration *Calibration
+
+	// Colors of the rasters, mountains and color banks, and the
+	// original images recolored for it
+	palette          Palette
+	paletteRasters   recolored
+	paletteMountains recolored
+	rasterFlash      []color.RGBA // the raster rows in the palette, for ^W1
+	paletteFlash     []color.RGBA // the raster rows before recoloring
+
+	// Scroll text typed in live, opened with E
+	editor TextEditor
+
+	// Waveforms adjusted live, opened with W
+	formEditor FormEditor
+
+	// Planes of the screen hidden with the number keys, and the ones
+	// frozen with Ctrl
+	hiddenLayers [layerCount]bool
+	frozenLayers [layerCount]bool
+	stepFrame    bool // step the unfrozen planes once while paused
+	stepped      bool // stepped since paused, hiding the PAUSED label
+
+	// Draw operations of the frame being recorded, asked for with
+	// Ctrl+D
+	drawList        *DrawList
+	drawListPending bool
+
+	// Debug overlay, toggled with D, and the letter draw path it reports
+	debug    DebugOverlay
+	drawPath DrawPathChoice
+
+	// Badges earned while watching, remembered in the settings file
+	achievements *Achievements
+
+	// Sound effects mixed into the music, nil when disabled
+	sfx *SFXMixer
+
+	// Demo steps owed at the tick rate, below 1
+	ticks float64
+
+	// Frame pacing diagnostics of -pacing, nil when off
+	pacing *FramePacing
+
+	// Music state for the effects pulsing with it
+	musicSync  MusicSync
+	musicFrame float64 // smoothed replay frame
+	pulse      float64 // 1 on a new note, fading to 0
+	flash      float64 // 1 on a beat of sampled music, fading to 0
+
+	// Space freezes the demo and the music
+	paused bool
+
+	// Lyrics following the music, nil without -lyrics
+	lyrics *lyricsPlayer
+	// Sentence timing of the scroll text, nil without -subtitles
+	subtitles *SubtitleRecorder
+
+	// End of the demo after -music-loops: frames of music fade left out
+	// of fadeTotal, then the end screen
+	fadeFrames int
+	fadeTotal  int
+	ended      bool
+	endCanvas  *ebiten.Image
+
+	// Oscilloscope of every music voice
+	scopes bool
+
+	// Short message after a key press
+	notice Notice
+
+	// Virtual camera pan across all planes
+	camera *Camera
+
+	// Statistics shown when quitting
+	stats      *Stats
+	quitting   bool
+	quitFrames int
+
+	// Scenes of the show
+	timeline *Timeline
+
+	// Extension hooks
+	hooks   *hooks.Registry
+	hookCtx *hooks.Context
+	frame   uint64
+
 	// Audio
 	audioContext *audio.Context
 	audioPlayer  *audio.Player
-	ymPlayer     *YMPlayer
+	tracks       []Track // playlist
+	track        int
+	music        MusicSource
 }
 
 // NewGame creates and initializes the demo
-func NewGame() *Game {
+func NewGame(cfg *Config) *Game {
 	g := &Game{
+		cfg: cfg,
+		rng: NewRNG(cfg.Seed),
+
 		mycanvas:     ebiten.NewImage(screenWidth, screenHeight),
 		papercanvas:  ebiten.NewImage(canvasWidth, canvasHeight),
 		papercanvas2: ebiten.NewImage(canvasWidth*2, canvasHeight*2),
@@ -216,43 +627,77 @@ func NewGame() *Game {
 		lettercanvas: ebiten.NewImage(32, 32),
 
 		fontTiles: make(map[rune]*ebiten.Image),
-		printPos:  make([]PrintPos, 30),
-
-		form:    0,
-		addi:    0,
-		rotAdd:  1,
-		scrollX: 0,
+		fontSpans: make(map[rune][2]int),
+		fontName:  "bgfont",
+		effects:   NewEffectRegistry(),
+		camera:    NewCamera(24, 0.01, cfg.CameraPan),
+		hooks:     hooks.Default,
+		stats:     NewStats(),
+		clock:     &Clock{Seconds: cfg.ClockSeconds},
+
+		rotAdd: 1,
 	}
 
-	// Initialize scroll forms (exactly as in JS)
-	g.scrollForms = []ScrollForm{
-		{0, 0, 0, 0, 55, 0, 0},
-		{0, 0, 0, 0, 55, 0, 2},
-		{0, 0, 0, 0, 55, 20, 2},
-		{200, 0, 0, 5, 55, 20, 2},
-		{200, 0, 4, 5, 55, 20, 2},
-		{200, -30, 4, 0, 55, 30, 2},
-		{200, 40, -4, 5, -70, 40, -4},
-		{150, 20, -3, 5, 55, 20, 2},
-	}
+	// Waveforms of -forms, before any text is tokenized
+	g.loadScrollForms()
+
+	// Load assets
+	g.loadAssets()
 
-	// Initialize background speeds (exactly as in JS)
-	speeds := []float64{8, 7.5, 7, 6.5, 6, 5.5, 5, 4.5, 4, 3.5, 3, 2.5, 2, 1.5, 1, 0.5}
-	g.bgSpeed = make([]float64, 32)
-	g.bgPos = make([]float64, 32)
+	// Initialize the scroller
+	g.scroller = g.newScroller()
+	g.scroller.TextEnd = cfg.TextEnd
+	g.scroller.RightToLeft = cfg.RightToLeft
+	g.scroller.Speed = cfg.ScrollSpeed
+	g.scroller.OnTextEnd = func() { g.timeline.Next() }
 
-	// Copy speeds forward and backward
-	copy(g.bgSpeed[:16], speeds)
-	copy(g.bgSpeed[16:], speeds)
+	// Background layers, with the speeds of the JS version by default
+	g.initLayers()
+	g.initScanlines()
 
 	// Initialize logo sine table
 	g.initLogoSin()
 
-	// Load assets
-	g.loadAssets()
-
 	// Initialize scroll text
-	g.initScrollText()
+	if cfg.Stdin {
+		g.scroller.Text = strings.Repeat(" ", scrollLetters+2)
+		g.scroller.Feed = NewTextFeed(os.Stdin, cfg.StdinQueue, cfg.FilterFor("stdin"))
+	} else {
+		g.initScrollText()
+		g.scroller.Text = g.clock.Expand(g.scroller.Text)
+		g.baseText = g.scroller.Text
+	}
+
+	// Headlines and remote edits are spliced in every time the text
+	// wraps
+	if cfg.FeedURL != "" && !cfg.Stdin {
+		g.headlines = NewHeadlineFetcher(cfg.FeedURL, cfg.FeedInterval, cfg.FilterFor("feed"))
+	}
+	if !cfg.Stdin {
+		g.scroller.OnWrap = g.rebuildText
+	}
+
+	// Lyrics, before the remote API picks the scroller feed
+	if cfg.Lyrics != "" {
+		if err := g.initLyrics(); err != nil {
+			log.Printf("Failed to load lyrics: %v", err)
+		}
+	}
+
+	if cfg.Subtitles != "" {
+		g.subtitles = NewSubtitleRecorder(g.scroller)
+	}
+
+	// Remote control API
+	if cfg.RemoteAddr != "" {
+		g.initRemote()
+	}
+
+	// Chat relayed into a second scroller
+	if cfg.ChatChannel != "" {
+		g.initChat()
+	}
+	g.initPlanes()
 
 	// Extract logo parts
 	if g.logo != nil {
@@ -273,12 +718,88 @@ func NewGame() *Game {
 		g.thecanvas2.DrawImage(tcbPart, op2)
 	}
 
-	// Initialize audio
-	g.initAudio()
+	// Initialize shader effects and the display calibration, which
+	// doesn't depend on -post
+	g.initEffects()
+	if c, err := NewCalibration(cfg); err != nil {
+		log.Printf("Failed to create display calibration: %v", err)
+	} else {
+		g.calibration = c
+	}
+	g.setPalette(cfg.Palette)
+
+	// Initialize audio (the gallery, the mountain sheet and the second
+	// demo of the comparison render silently)
+	if cfg.Gallery == "" && cfg.MountainSheet == "" && !cfg.Silent {
+		g.initAudio()
+	}
+
+	// Build the scenes
+	g.initTimeline()
+	g.memory = NewMemoryMonitor(cfg.MemoryBudget, g.sharedVRAM())
+	if cfg.Pacing {
+		g.pacing = NewFramePacing()
+	}
+	if cfg.STPointer {
+		g.pointer = NewPointer()
+	}
+
+	g.hideLayers()
+
+	// Build the options menu, the about pages and the credits
+	g.initOptions()
+	g.about = NewAboutOverlay(g.bigfont)
+	g.credits = NewCreditsOverlay(g.bigfont)
+
+	g.achievements = NewAchievements(cfg.Settings)
+	g.achievements.OnUnlock = func(Achievement) { g.sfx.Play(SFXAchievement) }
+	g.about.OnHidden = func() {
+		g.achievements.Unlock("hidden")
+		g.sfx.Play(SFXSecret)
+	}
+
+	// Start from a shared moment
+	g.initMoment()
 
 	return g
 }
 
+// newScroller creates a scroller drawing into the scroll canvas with
+// the settings shared by every scroller of the show
+func (g *Game) newScroller() *Scroller {
+	s := NewScroller(g.scrollcanvas, g.fontTiles, g.fontSpans, g.rasters, g.camera)
+	s.Forms = scrollForms
+	s.Mode = g.cfg.ScrollMode
+	s.RingRadius = g.cfg.RingRadius
+	s.RingTilt = g.cfg.RingTilt * math.Pi / 180
+	s.RingSpeed = g.cfg.RingSpeed
+	s.Path = g.logoPath
+	s.Hooks = g.hooks
+	s.FormMorph = g.cfg.FormMorph
+	s.MorphCurve = g.cfg.FormMorphCurve
+	s.Entrance = g.cfg.Entrance
+	s.EntranceCurve = g.cfg.EntranceCurve
+	for i, tiles := range g.extraFonts {
+		s.AddFont(tiles, g.extraSpans[i])
+	}
+	s.Proportional = g.cfg.Proportional
+	s.FlashColors = g.rasterFlash
+	s.MaxLetters = g.cfg.MaxLetters
+	s.LetterScale = g.cfg.LetterScale
+	s.TimeText = g.clock.Text
+	if g.cfg.Clock {
+		s.Overlay = g.drawClock
+	}
+	s.OnAdvance = func() { g.stats.CharsScrolled++ }
+	s.Snap = g.cfg.FixedPoint
+	s.Perspective = g.cfg.Perspective
+	s.Rate = ebiten.TPS()
+	if g.cfg.TickRate > 0 {
+		s.Rate = g.cfg.TickRate
+	}
+	return s
+}
+
 func (g *Game) initLogoSin() {
 	g.logoSin = make([]float64, 0)
 
@@ -304,8 +825,13 @@ func (g *Game) initLogoSin() {
 }
 
 func (g *Game) initScrollText() {
+	if text, ok := g.loadScrollText(); ok {
+		g.scroller.Text = text
+		return
+	}
+
 	spc := "                             "
-	g.scrollText = " ^0" + spc +
+	g.scroller.Text = " ^0" + spc +
 		"WOW, THIS DEMO SURE DOES LOOK GREAT..  BUT PERHAPS THE SCROLLINE LOOKS A BIT   TOO ORDINARY. " +
 		"WELL, OKEY, LET US SWING IT UP AND DOWN. " +
 		"^1 THIS IS THE LITTLE BIT OF EVERYTHING DEMO BY THE CAREBEARS. THERE ARE STAR RAY TYPE OF " +
@@ -347,18 +873,23 @@ func (g *Game) loadAssets() {
 		g.rasters.Fill(color.RGBA{255, 0, 255, 255})
 	} else {
 		g.rasters = ebiten.NewImageFromImage(img)
+		g.rasterFlash = rasterColors(img)
 	}
 
-	// Load mountains
-	img, _, err = image.Decode(bytes.NewReader(mountainsData))
+	// Load mountains, their strips are laid out by initLayers
+	data := mountainsData
+	if g.cfg.Mountains != "" {
+		if data, err = os.ReadFile(g.cfg.Mountains); err != nil {
+			log.Printf("Error loading mountains: %v", err)
+			data = mountainsData
+		}
+	}
+	img, _, err = image.Decode(bytes.NewReader(data))
 	if err != nil {
 		log.Printf("Error loading mountains: %v", err)
 		g.mountains = ebiten.NewImage(1024, 320)
 	} else {
 		g.mountains = ebiten.NewImageFromImage(img)
-		// Mountains are 1024x320 with 32 layers of 10 pixels height each
-		// Set up mountain tiles properly
-		g.mountains.SubImage(image.Rect(0, 0, 1024, 320))
 	}
 
 	// Load logo
@@ -368,182 +899,587 @@ func (g *Game) loadAssets() {
 		g.logo = ebiten.NewImage(320, 48)
 	} else {
 		g.logo = ebiten.NewImageFromImage(img)
+		g.logoContour = topContour(img, image.Rect(0, 16, 303, 16+logoRows))
 	}
 
-	// Load font
-	img, _, err = image.Decode(bytes.NewReader(fontData))
+	// Load font, the one of -ttf or -font or the built-in one
+	err = nil
+	switch {
+	case g.cfg.TTF != "":
+		err = g.loadTTFFont(g.cfg.TTF)
+	case g.cfg.Font != "" && isGridFont(g.cfg.Font):
+		err = g.loadGridFont(g.cfg.Font)
+	case g.cfg.Font != "":
+		err = g.loadDescribedFont(g.cfg.Font)
+	}
 	if err != nil {
-		log.Printf("Error loading font: %v", err)
-		g.font = ebiten.NewImage(320, 198)
-	} else {
-		g.font = ebiten.NewImageFromImage(img)
-		g.cacheFontTiles()
+		log.Printf("Failed to load font, using the built-in one: %v", err)
 	}
-}
-
-func (g *Game) cacheFontTiles() {
-	// Font layout based on your description
-	charMap := [][]rune{
-		{0, '!', 0, 0, 0, 0, 0, 0, '(', ')'},
-		{0, 0, ',', 0, '.', 0, 0, 0, 0, 0},
-		{0, 0, 0, 0, 0, 0, ':', ';', 0, 0},
-		{0, 0, 0, 'A', 'B', 'C', 'D', 'E', 'F', 'G'},
-		{'H', 'I', 'J', 'K', 'L', 'M', 'N', 'O', 'P', 'Q'},
-		{'R', 'S', 'T', 'U', 'V', 'W', 'X', 'Y', 'Z', 0},
+	if g.font == nil {
+		img, _, err = image.Decode(bytes.NewReader(fontData))
+		if err != nil {
+			log.Printf("Error loading font: %v", err)
+			g.font = ebiten.NewImage(320, 198)
+		} else {
+			g.font = ebiten.NewImageFromImage(img)
+			g.cacheFontTiles(img)
+		}
 	}
+	g.loadExtraFonts()
+	g.bigfont = NewBigFont(g.fontTiles, g.fontSpans, g.rasters)
+}
 
-	// Create font tiles for each character
-	for row := 0; row < 6; row++ {
+func (g *Game) cacheFontTiles(img image.Image) {
+	// Create font tiles for each character, the lowercase rows when the
+	// sheet has them
+	rows := min(img.Bounds().Dy()/fontTileHeight, len(gridRows))
+	for row := 0; row < rows; row++ {
 		for col := 0; col < 10; col++ {
-			ch := charMap[row][col]
+			ch := gridRows[row][col]
 			if ch != 0 {
 				x := col * 32
 				y := row * 33
 				g.fontTiles[ch] = g.font.SubImage(
 					image.Rect(x, y, x+32, y+33),
 				).(*ebiten.Image)
+				g.fontSpans[ch] = glyphSpan(img, image.Rect(x, y, x+32, y+33))
 			}
 		}
 	}
 
 	// Space is a blank tile
 	g.fontTiles[' '] = ebiten.NewImage(32, 33)
+
+	addDigitTiles(g.fontTiles)
+	for d := '0'; d <= '9'; d++ {
+		g.fontSpans[d] = [2]int{digitLeft, digitRight}
+	}
 }
 
-func (g *Game) initAudio() {
-	g.audioContext = audio.NewContext(44100)
+func (g *Game) initEffects() {
+	if !g.cfg.PostEffects {
+		return
+	}
 
-	var err error
-	g.ymPlayer, err = NewYMPlayer(musicData, 44100, true)
+	displacer, err := NewDisplacer()
 	if err != nil {
-		log.Printf("Failed to create YM player: %v", err)
-		return
+		log.Printf("Failed to create displacement effect: %v", err)
+	}
+	g.blurrer, err = NewBlurrer(g.cfg.BlurQuality)
+	if err != nil {
+		log.Printf("Failed to create blur effect: %v", err)
+	}
+
+	w := g.papercanvas2.Bounds().Dx()
+	h := g.papercanvas2.Bounds().Dy()
+	if displacer != nil {
+		g.effects.Register("haze", StageLandscape,
+			NewHeatHaze(displacer, w, g.cfg.HeatHazeIntensity, g.rng.Stream("haze")), g.cfg.HeatHaze)
+		g.effects.Register("ripple", StageLandscape,
+			NewWaterRipple(displacer, image.Rect(0, hazeTop+hazeHeight, w, h), 2), g.cfg.WaterRipple)
+	}
+
+	if g.blurrer != nil {
+		glow := NewTextGlow(g.blurrer, g.scrollcanvas, g.cfg.GlowColor, g.cfg.GlowRadius, func() float64 {
+			return g.channelLevel(g.cfg.GlowChannel)
+		})
+		g.effects.Register("glow", StageScroller, glow, g.cfg.Glow)
+		g.effects.Register("bloom", StageScreen,
+			NewBloomEffect(g.blurrer, screenWidth, screenHeight), g.cfg.Bloom)
 	}
 
-	g.audioPlayer, err = g.audioContext.NewPlayer(g.ymPlayer)
+	if displacer != nil {
+		g.wobble = NewWobbleTransition(displacer, screenWidth, screenHeight, 60, 12)
+		g.effects.Register("wobble", StageScreen, g.wobble, true)
+	}
+
+	g.grain, err = NewGrainEffect(screenWidth, screenHeight, g.rng.Stream("grain"))
 	if err != nil {
-		log.Printf("Failed to create audio player: %v", err)
-		g.ymPlayer.Close()
-		g.ymPlayer = nil
+		log.Printf("Failed to create grain effect: %v", err)
+	} else {
+		g.grain.Grain = g.cfg.Grain
+		g.grain.Quantize = g.cfg.Quantize
+		g.grain.Dither = g.cfg.Dither
+		g.effects.Register("grain", StageScreen, g.grain, true)
+	}
+}
+
+// hookContext returns the context handed to the extension hooks
+func (g *Game) hookContext(screen *ebiten.Image) *hooks.Context {
+	if g.hookCtx == nil {
+		g.hookCtx = &hooks.Context{
+			Effects: g.effects,
+			Canvases: map[string]*ebiten.Image{
+				"landscape": g.papercanvas2,
+				"logo":      g.papercanvas,
+				"scroller":  g.scrollcanvas,
+				"composite": g.mycanvas,
+			},
+		}
+	}
+	g.hookCtx.Frame = g.frame
+	g.hookCtx.Screen = screen
+	return g.hookCtx
+}
+
+// channelLevel returns the level of a music channel, or 0 without music
+func (g *Game) channelLevel(channel int) float64 {
+	if g.music == nil {
+		return 0
+	}
+	levels := g.music.ChannelLevels()
+	if channel < 0 || channel >= len(levels) {
+		return 0
+	}
+	return levels[channel]
+}
+
+func (g *Game) initAudio() {
+	// Visualizer only: the sound comes from elsewhere
+	if g.cfg.AudioInput != "" {
+		if g.cfg.AudioInput == "-" && g.cfg.Stdin {
+			log.Printf("Standard input already feeds the scroll text, not the audio input")
+			return
+		}
+		capture, err := NewCaptureSource(g.cfg.AudioInput)
+		if err != nil {
+			log.Printf("Failed to open audio input: %v", err)
+			return
+		}
+		capture.SetBPM(g.cfg.BPM)
+		g.music = capture
+		return
+	}
+
+	if g.cfg.AudioDevice != "" {
+		if err := selectAudioDevice(g.cfg.AudioDevice); err != nil {
+			log.Printf("Failed to select audio device: %v", err)
+		}
+	}
+
+	rate := g.cfg.AudioRate
+	if rate <= 0 {
+		log.Printf("Invalid audio rate %d, using 44100 Hz", rate)
+		rate = 44100
+	}
+	g.audioContext = audio.NewContext(rate)
+	if g.cfg.SFXVolume > 0 {
+		g.sfx = NewSFXMixer(rate, g.cfg.SFXVolume)
+	}
+
+	g.initPlaylist()
+	if len(g.tracks) == 0 {
 		return
 	}
+	g.playTrack(0)
+	if g.music != nil && g.cfg.Subsong > 1 {
+		if err := g.music.SetSubsong(g.cfg.Subsong - 1); err != nil {
+			log.Printf("Failed to select subsong: %v", err)
+		}
+	}
+}
 
-	g.audioPlayer.SetVolume(0.7)
-	g.audioPlayer.Play()
+// selectSubsong switches the music to subsong n, counted from 0
+func (g *Game) selectSubsong(n int) {
+	if g.music == nil {
+		return
+	}
+	if err := g.music.SetSubsong(n); err != nil {
+		g.notice.Show(err.Error())
+		return
+	}
+	g.notice.Show(fmt.Sprintf("Subsong %d/%d", n+1, g.music.Subsongs()))
+}
+
+// volumeStep is the volume change of one key press
+const volumeStep = 0.1
+
+// Scroll speeds reached with [ and ], in pixels per update
+const (
+	scrollSpeedStep = 1
+	maxScrollSpeed  = 64
+)
+
+// setScrollSpeed sets the speed of the main scroller, in pixels per
+// update, and shows it
+func (g *Game) setScrollSpeed(v float64) {
+	g.scroller.Speed = min(max(v, scrollSpeedStep), maxScrollSpeed)
+	g.notice.Show(fmt.Sprintf("Scroll speed %g", g.scroller.Speed))
+}
+
+// setVolume ramps the music volume to v and shows it
+func (g *Game) setVolume(v float64) {
+	if g.music == nil {
+		return
+	}
+	g.music.SetVolume(v)
+	g.notice.Show(fmt.Sprintf("Volume %d%%", int(math.Round(g.music.Volume()*100))))
+}
+
+// subsongKeys select subsongs 1 to 9 together with Shift
+var subsongKeys = []ebiten.Key{
+	ebiten.Key1, ebiten.Key2, ebiten.Key3, ebiten.Key4, ebiten.Key5,
+	ebiten.Key6, ebiten.Key7, ebiten.Key8, ebiten.Key9,
 }
 
 func (g *Game) Update() error {
-	// Handle fullscreen toggle
-	if inpututil.IsKeyJustPressed(ebiten.KeyF) {
-		ebiten.SetFullscreen(!ebiten.IsFullscreen())
+	g.frame++
+	g.stats.Updates++
+	if g.pacing != nil {
+		g.pacing.Update(g.audioPlayer)
+	}
+	if err := g.hooks.Run(hooks.PreUpdate, g.hookContext(nil)); err != nil {
+		return err
 	}
+	g.updateDrawPath()
 
-	// Update background parallax (exactly as in JS)
-	for i := 0; i < 32; i++ {
-		g.bgPos[i] = math.Mod(g.bgPos[i]-g.bgSpeed[i], 256)
+	// Quitting shows the statistics screen before closing
+	if g.quitting {
+		return g.updateQuit()
+	}
+	if ebiten.IsWindowBeingClosed() || (!g.editor.active && inpututil.IsKeyJustPressed(ebiten.KeyEscape)) {
+		g.startQuit()
+		return nil
 	}
 
-	// Update logo distortion counter
-	g.dcounter++
-	if g.dcounter > len(g.logoSin)-80 {
-		g.dcounter = 0
+	// Keys type into the scroll text while the editor is open
+	if g.editor.active {
+		g.updateEditor()
+	} else {
+		g.updateKeys()
+	}
+	g.playDroppedFiles()
+	if len(inpututil.AppendJustPressedKeys(nil)) > 0 {
+		g.sfx.Play(SFXKey)
+	}
+	g.notice.Update()
+	g.updateAchievements()
+	if g.pointer != nil {
+		g.pointer.Update(g.timeline.Loading())
 	}
 
-	// Update logo rotation
-	g.rotPos += g.rotAdd * 0.08
-	if g.rotPos > 1 {
-		g.rotPos = -1
-		g.next++
-		if g.next > 1 {
-			g.next = 0
+	// Commands received by the remote API
+	g.runRemoteCommands()
+	g.publishStatus()
+
+	// Everything animated stays where it is while paused, unless
+	// stepped a frame at a time
+	if g.paused {
+		if g.stepFrame {
+			g.stepFrame, g.stepped = false, true
+			if err := g.step(); err != nil {
+				return err
+			}
 		}
+		return g.hooks.Run(hooks.PostUpdate, g.hookContext(nil))
 	}
 
-	// Update 3D scroll
-	g.scroll3D(4)
+	g.updateMusicSync()
+	g.updateLyrics()
+	if g.updateEnding() {
+		return g.hooks.Run(hooks.PostUpdate, g.hookContext(nil))
+	}
 
-	return nil
+	// The demo moves on at the tick rate, e.g. 50 Hz as on the ST, or
+	// by the clock when updates can't be trusted to arrive on time
+	steps := 1
+	if g.pacing != nil {
+		rate := float64(ebiten.TPS())
+		if g.cfg.TickRate > 0 {
+			rate = float64(g.cfg.TickRate)
+		}
+		g.ticks += g.pacing.Step().Seconds() * rate
+		steps = int(g.ticks)
+		g.ticks -= float64(steps)
+	} else if g.cfg.TickRate > 0 {
+		g.ticks += float64(g.cfg.TickRate) / float64(ebiten.TPS())
+		steps = int(g.ticks)
+		g.ticks -= float64(steps)
+	}
+	for range steps {
+		if err := g.step(); err != nil {
+			return err
+		}
+	}
+	g.memory.Update(g.timeline.Current())
+
+	return g.hooks.Run(hooks.PostUpdate, g.hookContext(nil))
 }
 
-func (g *Game) scroll3D(scrollspeed float64) {
-	// Update sine adder for animation
-	g.sinAdder += 0.02
+// step moves the demo on by one tick
+func (g *Game) step() error {
+	// Update shader effects
+	if !g.frozenLayers[LayerEffects] {
+		g.effects.Update()
+	}
+
+	// Update camera pan
+	g.camera.Update()
+
+	// Update the current scene
+	return g.timeline.Update()
+}
+
+// updateKeys handles the keyboard shortcuts
+func (g *Game) updateKeys() {
+	// Handle fullscreen toggle
+	if inpututil.IsKeyJustPressed(ebiten.KeyF) {
+		ebiten.SetFullscreen(!ebiten.IsFullscreen())
+	}
 
-	// Clear printPos array
-	for i := range g.printPos {
-		g.printPos[i] = PrintPos{}
+	// Handle effect toggles
+	if inpututil.IsKeyJustPressed(ebiten.KeyH) {
+		g.effects.Toggle("haze")
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyR) {
+		g.effects.Toggle("ripple")
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyG) {
+		g.effects.Toggle("glow")
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyB) {
+		g.effects.Toggle("bloom")
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyT) {
+		g.startTransition()
 	}
 
-	// Process characters
-	printIdx := 0
-	for i := 0; i < 30; i++ {
-		charIdx := g.addi + i
-		// Handle wrapping
-		for charIdx >= len(g.scrollText) {
-			charIdx -= len(g.scrollText)
+	if inpututil.IsKeyJustPressed(ebiten.KeyC) {
+		g.camera.Enabled = !g.camera.Enabled
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyO) {
+		g.toggleScopes()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeySpace) {
+		g.togglePause()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyN) {
+		g.stepTrack(1)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyP) {
+		g.stepTrack(-1)
+	}
+
+	// The silent demo of -compare shares the music of the other one,
+	// which handles these keys
+	if !g.cfg.Silent {
+		g.updateMusicKeys()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyK) {
+		g.credits.Toggle()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyD) {
+		if ebiten.IsKeyPressed(ebiten.KeyControl) {
+			g.requestDrawList()
+		} else {
+			g.debug.Toggle()
 		}
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyM) {
+		g.shareMoment()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyX) && ebiten.IsKeyPressed(ebiten.KeyControl) {
+		g.exportDemo()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyE) {
+		g.openEditor()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyV) {
+		g.scroller.reverse()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyW) {
+		g.toggleFormEditor()
+	}
+	g.updateLayerKeys()
+	if inpututil.IsKeyJustPressed(ebiten.KeyBracketRight) {
+		g.setScrollSpeed(g.scroller.Speed + scrollSpeedStep)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyBracketLeft) {
+		g.setScrollSpeed(g.scroller.Speed - scrollSpeedStep)
+	}
 
-		letter := string(g.scrollText[charIdx])
+	// Handle the about pages, the waveform editor and the options menu,
+	// which share the arrow keys
+	if inpututil.IsKeyJustPressed(ebiten.KeyI) {
+		g.about.Toggle()
+	}
+	switch {
+	case g.about.visible:
+		g.about.Update()
+	case g.formEditor.active:
+		g.updateFormEditor()
+	default:
+		if inpututil.IsKeyJustPressed(ebiten.KeyTab) {
+			g.options.Toggle()
+		}
+		if !g.options.Visible() {
+			g.updateSeek()
+		}
+		g.options.Update()
+	}
+}
 
-		// Handle control codes
-		if letter == "^" && charIdx+1 < len(g.scrollText) {
-			nextChar := g.scrollText[(charIdx+1)%len(g.scrollText)]
-			if nextChar >= '0' && nextChar <= '7' {
-				g.form = int(nextChar - '0')
-				letter = string(g.scrollText[(charIdx-1+len(g.scrollText))%len(g.scrollText)])
+// updateMusicKeys handles the keyboard shortcuts of the music playing
+func (g *Game) updateMusicKeys() {
+	if inpututil.IsKeyJustPressed(ebiten.KeyL) {
+		g.setLowPass(!g.cfg.LowPass)
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyShift) {
+		for i, key := range subsongKeys {
+			if inpututil.IsKeyJustPressed(key) {
+				g.selectSubsong(i)
 			}
 		}
+	}
+	if g.music != nil {
+		if inpututil.IsKeyJustPressed(ebiten.KeyEqual) || inpututil.IsKeyJustPressed(ebiten.KeyNumpadAdd) {
+			g.setVolume(g.music.Volume() + volumeStep)
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyMinus) || inpututil.IsKeyJustPressed(ebiten.KeyNumpadSubtract) {
+			g.setVolume(g.music.Volume() - volumeStep)
+		}
+	}
+}
+
+// togglePause freezes the demo and its music, or resumes both
+func (g *Game) togglePause() {
+	g.paused = !g.paused
+	g.stepped = false
+	if g.audioPlayer == nil {
+		return
+	}
+	if g.paused {
+		g.audioPlayer.Pause()
+	} else {
+		g.audioPlayer.Play()
+	}
+}
+
+// scrollLandscape moves every mountain layer on by its speed, the strips
+// repeating every 256 ST pixels
+func (g *Game) scrollLandscape() {
+	g.landscapeTicks++
+	for i, l := range g.layers {
+		g.bgPos[i] = -g.landscapeTicks.Wrap(l.Speed, 256)
+	}
+}
+
+// updateDemo advances the scroller screen by one frame
+func (g *Game) updateDemo(s *Scroller) {
+	// Update background parallax (exactly as in JS)
+	if !g.frozenLayers[LayerMountains] {
+		g.scrollLandscape()
+	}
+
+	// Update logo distortion counter, in time with the music when
+	// there is some and it plays, stepping frames otherwise
+	switch {
+	case g.frozenLayers[LayerLogo]:
+	case g.music != nil && !g.paused:
+		g.dcounter = int(max(0, g.musicFrame)) % (len(g.logoSin) - 79)
+	default:
+		g.dcounter++
+		if g.dcounter > len(g.logoSin)-80 {
+			g.dcounter = 0
+		}
+	}
 
-		// Skip numbers after control codes
-		if charIdx > 0 && g.scrollText[(charIdx-1+len(g.scrollText))%len(g.scrollText)] == '^' {
-			if g.scrollText[charIdx] >= '0' && g.scrollText[charIdx] <= '7' {
-				if charIdx >= 2 {
-					letter = string(g.scrollText[(charIdx-2+len(g.scrollText))%len(g.scrollText)])
-				}
+	// Update logo rotation
+	if !g.frozenLayers[LayerTCB] {
+		g.rotPos += g.rotAdd * 0.08
+		if g.rotPos > 1 {
+			g.rotPos = -1
+			g.next++
+			if g.next > 1 {
+				g.next = 0
 			}
 		}
+	}
 
-		// Calculate 3D position using current form
-		sf := g.scrollForms[g.form]
+	// Update 3D scroll
+	if !g.frozenLayers[LayerRasters] {
+		s.Pulse = g.pulse
+	}
+	if g.frozenLayers[LayerScroller] {
+		return
+	}
+	s.Update()
+	g.updatePlanes()
+	if g.chat != nil {
+		g.chat.Update()
+	}
+}
 
-		// IMPORTANT: Use charIdx (not i) for the wave calculation to keep it stable
-		// This ensures each character keeps its wave position as it scrolls
-		letterZ := sf.zSize*math.Sin(sf.zAdd+float64(charIdx)*sf.zAmount*0.01+g.sinAdder*sf.zSpeed) + 150
-		letterY := sf.ySize*math.Cos(1.5+float64(charIdx)*sf.yAmount*0.01+g.sinAdder*sf.ySpeed) - 4
+func (g *Game) Draw(screen *ebiten.Image) {
+	if g.frame%uint64(g.drawEvery()) != 0 {
+		return
+	}
+	g.stats.FramesRendered++
+	if g.metrics != nil {
+		g.metrics.Frame()
+	}
+	g.startDrawList()
+	defer g.finishDrawList()
+	if err := g.hooks.Run(hooks.PreDraw, g.hookContext(screen)); err != nil {
+		log.Printf("Draw hook failed: %v", err)
+	}
 
-		scale := fov / (fov + letterZ)
+	// Draw the current scene, or the end screen once the demo ended
+	if g.ended {
+		g.drawEndScreen(g.mycanvas)
+	} else {
+		g.timeline.Draw(g.mycanvas)
+		g.drawBeatFlash(g.mycanvas)
+	}
 
-		// Position calculation with smooth scrolling
-		letterX := -450.0 + float64(i)*32 - g.scrollX
-		x2d := ((letterX-16)*scale) + float64(g.papercanvas.Bounds().Dx())/2
-		y2d := ((letterY-14)*scale) + float64(g.papercanvas.Bounds().Dy())/2
+	// Apply full-frame effects (transitions)
+	g.effects.Apply(StageScreen, g.mycanvas)
+	g.drawList.addEffects("screen", g.effects, StageScreen)
 
-		g.printPos[printIdx].x = x2d
-		g.printPos[printIdx].y = y2d
-		g.printPos[printIdx].z = scale
-		g.printPos[printIdx].letter = letter
-		printIdx++
+	// Draw to screen, darkening with the music fade
+	op := &ebiten.DrawImageOptions{}
+	if f := g.fadeLevel(); f < 1 {
+		op.ColorScale.Scale(f, f, f, 1)
 	}
+	g.calibration.Draw(screen, g.mycanvas, op)
+	g.drawList.add("screen", "canvas", g.mycanvas.Bounds(), op)
 
-	// Sort by depth (back to front)
-	sort.Slice(g.printPos, func(i, j int) bool {
-		return g.printPos[i].z < g.printPos[j].z
-	})
+	if err := g.hooks.Run(hooks.PostDraw, g.hookContext(screen)); err != nil {
+		log.Printf("Draw hook failed: %v", err)
+	}
 
-	// Update scroll position
-	g.scrollX += scrollspeed
+	// Overlays
+	if g.paused && !g.stepped {
+		ebitenutil.DebugPrintAt(screen, "PAUSED", screenWidth/2-18, screenHeight/2-8)
+	}
+	g.drawScopes(screen)
+	g.drawLyrics(screen)
+	g.drawSeekBar(screen)
+	g.credits.Draw(screen, g.songInfo())
+	g.notice.Draw(screen)
+	g.drawHalfMeg(screen)
+	g.drawEditor(screen)
+	g.achievements.Draw(screen, g.bigfont)
+	g.options.Draw(screen)
+	g.drawFormEditor(screen)
+	g.about.Draw(screen)
+	g.drawDebug(screen)
+	g.drawStats(screen)
+	if g.pointer != nil {
+		g.pointer.Draw(screen)
+	}
+}
 
-	// When we've scrolled one character width, advance index
-	if g.scrollX >= 32 {
-		g.scrollX -= 32
-		g.addi++
-		if g.addi >= len(g.scrollText) {
-			g.addi = 0
-		}
+// drawEvery returns how many updates each drawn frame lasts
+func (g *Game) drawEvery() int {
+	if g.cfg.DrawFPS <= 0 {
+		return 1
 	}
+	return max(1, int(math.Round(float64(ebiten.TPS())/float64(g.cfg.DrawFPS))))
 }
 
-func (g *Game) Draw(screen *ebiten.Image) {
+// drawDemo renders the scroller screen into mycanvas
+func (g *Game) drawDemo(s *Scroller) {
 	// Clear main canvas
 	g.mycanvas.Fill(color.Black)
 	g.papercanvas.Clear()
@@ -552,130 +1488,95 @@ func (g *Game) Draw(screen *ebiten.Image) {
 
 	// Draw parallax mountains
 	// In the JS version: mountains.drawTile(papercanvas2,i,(bgpos[i])*2,i*10);
-	// The mountains image is 1024 wide, and we draw tiles that are the full width
-	for i := 0; i < 16; i++ {
-		xPos := int(g.bgPos[i]) * 2
-		yPos := i * 10
-
-		// Draw the full width mountain strip for this layer
-		srcY := i * 10
-		mountainStrip := g.mountains.SubImage(image.Rect(0, srcY, 1024, srcY+10)).(*ebiten.Image)
-
-		op := &ebiten.DrawImageOptions{}
-		op.GeoM.Translate(float64(xPos), float64(yPos))
-		g.papercanvas2.DrawImage(mountainStrip, op)
+	// We draw tiles that are the full width of the mountains image
+	if g.layerShown(LayerMountains) {
+		for i, l := range g.layers {
+			mountainStrip := g.mountains.SubImage(image.Rect(0, l.Top, g.mountains.Bounds().Dx(), l.Bottom)).(*ebiten.Image)
 
-		// Draw wrapped tile to ensure continuous scrolling
-		op.GeoM.Translate(640, 0)
-		g.papercanvas2.DrawImage(mountainStrip, op)
+			op := &ebiten.DrawImageOptions{}
+			op.GeoM.Translate(g.landscapeX(i), float64(l.Y))
+			g.papercanvas2.DrawImage(mountainStrip, op)
+			g.drawList.add("mountains", "mountains", mountainStrip.Bounds(), op)
+
+			// Draw wrapped tile to ensure continuous scrolling
+			op.GeoM.Translate(640, 0)
+			g.papercanvas2.DrawImage(mountainStrip, op)
+			g.drawList.add("mountains", "mountains", mountainStrip.Bounds(), op)
+		}
 	}
 
-	// Draw bottom mountain layers
-	for i := 16; i < 32; i++ {
-		xPos := int(g.bgPos[i]) * 2
-		yPos := i*10 + 84
-
-		srcY := i * 10
-		mountainStrip := g.mountains.SubImage(image.Rect(0, srcY, 1024, srcY+10)).(*ebiten.Image)
-
-		op := &ebiten.DrawImageOptions{}
-		op.GeoM.Translate(float64(xPos), float64(yPos))
-		g.papercanvas2.DrawImage(mountainStrip, op)
-
-		op.GeoM.Translate(640, 0)
-		g.papercanvas2.DrawImage(mountainStrip, op)
-	}
+	// Apply landscape effects (heat haze, ripples)
+	g.effects.Apply(StageLandscape, g.papercanvas2)
+	g.drawList.addEffects("mountains", g.effects, StageLandscape)
 
 	// Draw papercanvas2 to main canvas
 	op := &ebiten.DrawImageOptions{}
 	op.GeoM.Translate(64, 60)
 	g.mycanvas.DrawImage(g.papercanvas2, op)
+	g.drawList.add("composite", "landscape", g.papercanvas2.Bounds(), op)
 
 	// Draw distorted logo
-	for i := 0; i < 32; i++ {
-		xOffset := g.logoSin[g.dcounter+i]
-
+	logoShift := g.camera.Shift(logoDepth)
+	for i := 0; i < logoRows && g.layerShown(LayerLogo); i++ {
+		xOffset := g.logoRowX(i)
 		src := g.logo.SubImage(image.Rect(0, 16+i, 303, 17+i)).(*ebiten.Image)
 		op := &ebiten.DrawImageOptions{}
-		op.GeoM.Translate(8+xOffset, float64(96+i))
+		op.GeoM.Translate(logoX+xOffset, float64(logoTop+i))
 		g.papercanvas.DrawImage(src, op)
+		g.drawList.add("logo", "logo", src.Bounds(), op)
 	}
 
 	// Draw rotating TCB text
-	if g.thecanvas != nil && g.thecanvas2 != nil {
+	if g.thecanvas != nil && g.thecanvas2 != nil && g.layerShown(LayerTCB) {
 		op = &ebiten.DrawImageOptions{}
 		// Center the rotation on the text
 		op.GeoM.Translate(-40, -8)
 		op.GeoM.Scale(1, g.rotPos)
-		op.GeoM.Translate(160, 88)
+		op.GeoM.Translate(160+logoShift, 88)
 
 		if g.next == 0 {
 			g.papercanvas.DrawImage(g.thecanvas, op)
+			g.drawList.add("tcb", "tcb", g.thecanvas.Bounds(), op)
 		} else {
 			g.papercanvas.DrawImage(g.thecanvas2, op)
+			g.drawList.add("tcb", "tcb-flipped", g.thecanvas2.Bounds(), op)
 		}
 	}
 
 	// Draw 3D scroll
-	g.drawScroll3D()
+	if g.layerShown(LayerScroller) {
+		s.Draw()
+	}
+
+	// Glow around the letters, behind the scroller
+	g.effects.Apply(StageScroller, g.papercanvas)
+	g.drawList.addEffects("scroller", g.effects, StageScroller)
 
 	// Composite scroll onto paper canvas
 	op = &ebiten.DrawImageOptions{}
 	g.papercanvas.DrawImage(g.scrollcanvas, op)
+	g.drawList.add("composite", "scroller", g.scrollcanvas.Bounds(), op)
+	if g.layerShown(LayerScroller) {
+		g.drawPlanes(g.papercanvas)
+		if g.chat != nil {
+			g.chatcanvas.Clear()
+			g.chat.Draw()
+			g.papercanvas.DrawImage(g.chatcanvas, nil)
+			g.drawList.add("composite", "chat", g.chatcanvas.Bounds(), nil)
+		}
+	}
 
 	// Draw paper canvas to main canvas (scaled 2x)
 	op = &ebiten.DrawImageOptions{}
 	op.GeoM.Scale(2, 2)
 	op.GeoM.Translate(64, 60)
 	g.mycanvas.DrawImage(g.papercanvas, op)
+	g.drawList.add("composite", "paper", g.papercanvas.Bounds(), op)
 
-	// Draw to screen
-	screen.DrawImage(g.mycanvas, nil)
-}
-
-func (g *Game) drawScroll3D() {
-	// Don't clear the canvas, it's already cleared in Draw()
-
-	// Draw each character
-	for i := 0; i < 30; i++ {
-		if g.printPos[i].letter == "" || g.printPos[i].z <= 0 {
-			continue
-		}
-
-		ch := rune(g.printPos[i].letter[0])
-		tile, ok := g.fontTiles[ch]
-		if !ok {
-			// Try uppercase
-			if ch >= 'a' && ch <= 'z' {
-				ch = ch - 'a' + 'A'
-				tile, ok = g.fontTiles[ch]
-			}
-			if !ok {
-				tile = g.fontTiles[' ']
-			}
-		}
-
-		if tile != nil {
-			op := &ebiten.DrawImageOptions{}
-			// Center the character sprite
-			op.GeoM.Translate(-16, -16.5)
-			op.GeoM.Scale(g.printPos[i].z, g.printPos[i].z)
-			op.GeoM.Translate(g.printPos[i].x, g.printPos[i].y)
-
-			// Use nearest neighbor filter for pixel-perfect rendering
-			op.Filter = ebiten.FilterNearest
-
-			g.scrollcanvas.DrawImage(tile, op)
-		}
+	// Palette and shift changes down the screen
+	if g.scanlines != nil {
+		g.scanlines.Apply(g.mycanvas, image.Pt(64, 60))
 	}
-
-	// Apply raster colors
-	// The raster image needs to be stretched to cover the full canvas width
-	// Then source-atop will apply it only inside the already drawn letters
-	op := &ebiten.DrawImageOptions{}
-	op.GeoM.Scale(float64(g.scrollcanvas.Bounds().Dx())/float64(g.rasters.Bounds().Dx()), 1)
-	op.CompositeMode = ebiten.CompositeModeSourceAtop
-	g.scrollcanvas.DrawImage(g.rasters, op)
 }
 
 func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
@@ -687,8 +1588,8 @@ func (g *Game) Cleanup() {
 	if g.audioPlayer != nil {
 		g.audioPlayer.Close()
 	}
-	if g.ymPlayer != nil {
-		g.ymPlayer.Close()
+	if g.music != nil {
+		g.music.Close()
 	}
 }
 
@@ -696,11 +1597,90 @@ func main() {
 	ebiten.SetWindowSize(screenWidth, screenHeight)
 	ebiten.SetWindowTitle("TCB SUPER-MULTI-PLANE-3D-SCROLLER")
 
-	game := NewGame()
+	cfg := DefaultConfig()
+	cfg.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	if err := cfg.ApplyDemo(flag.CommandLine); err != nil {
+		log.Fatal(err)
+	}
+	if err := cfg.ApplyProfile(flag.CommandLine); err != nil {
+		cfg.CloseDemo()
+		log.Fatal(err)
+	}
+	if cfg.AudioDevice == "list" {
+		printAudioDevices()
+		cfg.CloseDemo()
+		return
+	}
+	if cfg.DumpAudio != "" {
+		err := dumpAudio(cfg, cfg.DumpAudio)
+		cfg.CloseDemo()
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if cfg.DrawFPS > 0 {
+		// Skipped frames keep showing the last one drawn
+		ebiten.SetScreenClearedEveryFrame(false)
+	}
+
+	// The comparison runs two demos, the left one counting for the
+	// statistics and the subtitles
+	var (
+		game   *Game
+		runner ebiten.Game
+	)
+	if cfg.Compare {
+		compare := NewCompare(cfg)
+		game, runner = compare.demos[0], compare
+	} else {
+		game = NewGame(cfg)
+		runner = game
+	}
 
-	if err := ebiten.RunGame(game); err != nil {
+	if cfg.Gallery != "" {
+		ebiten.SetRunnableOnUnfocused(true)
+		if _, err := NewGallery(game, cfg.Gallery); err != nil {
+			cfg.CloseDemo()
+			log.Fatal(err)
+		}
+	}
+	if cfg.MountainSheet != "" {
+		ebiten.SetRunnableOnUnfocused(true)
+		NewMountainSheet(game, cfg.MountainSheet)
+	}
+	var soak *Soak
+	if cfg.Soak > 0 {
+		ebiten.SetRunnableOnUnfocused(true)
+		soak = NewSoak(runner, game, cfg.Soak, cfg.SoakReport)
+		runner = soak
+	}
+
+	ebiten.SetWindowClosingHandled(true)
+	if cfg.STPointer && !cfg.Compare {
+		ebiten.SetCursorMode(ebiten.CursorModeHidden)
+	}
+
+	if err := ebiten.RunGame(runner); err != nil {
+		cfg.CloseDemo()
 		log.Fatal(err)
 	}
 
+	if cfg.StatsFile != "" {
+		if err := game.writeStats(cfg.StatsFile); err != nil {
+			log.Printf("Failed to write statistics: %v", err)
+		}
+	}
+	if game.subtitles != nil {
+		if err := game.subtitles.WriteFile(cfg.Subtitles); err != nil {
+			log.Printf("Failed to write subtitles: %v", err)
+		}
+	}
+
 	game.Cleanup()
+	cfg.CloseDemo()
+	if soak != nil && soak.Failed() {
+		os.Exit(1)
+	}
 }
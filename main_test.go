@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+	"time"
+)
+
+func newTestYMPlayer(t *testing.T, loop bool) *YMPlayer {
+	t.Helper()
+	data, err := os.ReadFile("assets/music/Thundercats.ym")
+	if err != nil {
+		t.Fatal(err)
+	}
+	y, err := NewYMPlayer(data, 44100, loop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { y.Close() })
+	return y
+}
+
+func TestRenderToRepeats(t *testing.T) {
+	const d = 3 * time.Second
+	var runs [2]bytes.Buffer
+	for i := range runs {
+		y := newTestYMPlayer(t, true)
+		n, err := y.RenderTo(&runs[i], d)
+		if err != nil {
+			t.Fatalf("run %d: %v", i, err)
+		}
+		want := int64(d/time.Second) * 44100 * int64(y.format.FrameSize())
+		if n != want || int64(runs[i].Len()) != want {
+			t.Fatalf("run %d: wrote %d bytes, %d in the buffer, want %d", i, n, runs[i].Len(), want)
+		}
+	}
+	if !bytes.Equal(runs[0].Bytes(), runs[1].Bytes()) {
+		t.Error("two renders from the same state differ")
+	}
+}
+
+func TestRenderToStopsAtEnd(t *testing.T) {
+	y := newTestYMPlayer(t, false)
+	size := int64(y.format.FrameSize())
+	var out bytes.Buffer
+	n, err := y.RenderTo(&out, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n == 0 || n != int64(out.Len()) {
+		t.Fatalf("wrote %d bytes, %d in the buffer", n, out.Len())
+	}
+	// Nothing past the tune: at most the samples of the frame it ended on
+	if frames := n / size; frames > y.totalSamples+44100/ymFrameRate {
+		t.Errorf("wrote %d samples of a %d sample tune", frames, y.totalSamples)
+	}
+}
+
+func TestClosedYMPlayer(t *testing.T) {
+	y := newTestYMPlayer(t, true)
+	y.Close()
+	if err := y.SetSubsong(0); err == nil {
+		t.Error("SetSubsong on a closed player succeeded")
+	}
+	if _, err := y.Seek(0, io.SeekStart); err == nil {
+		t.Error("Seek on a closed player succeeded")
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// metricsWindow is the number of recent frames the frame time
+// percentiles are computed over
+const metricsWindow = 600
+
+// Metrics collects the health of the demo and serves it in the
+// Prometheus text format
+type Metrics struct {
+	mu sync.Mutex
+
+	lastFrame  time.Time
+	frameTimes []float64 // seconds, ring of the last metricsWindow frames
+	next       int
+	frameSum   float64
+	frameCount int64
+	fps        float64
+
+	underruns int64
+}
+
+// NewMetrics creates empty metrics
+func NewMetrics() *Metrics {
+	return &Metrics{frameTimes: make([]float64, 0, metricsWindow)}
+}
+
+// Frame records a rendered frame
+func (m *Metrics) Frame() {
+	now := time.Now()
+	fps := ebiten.ActualFPS()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fps = fps
+	if !m.lastFrame.IsZero() {
+		d := now.Sub(m.lastFrame).Seconds()
+		if len(m.frameTimes) < metricsWindow {
+			m.frameTimes = append(m.frameTimes, d)
+		} else {
+			m.frameTimes[m.next] = d
+			m.next = (m.next + 1) % metricsWindow
+		}
+		m.frameSum += d
+		m.frameCount++
+	}
+	m.lastFrame = now
+}
+
+// Audio wraps the music stream to count underruns
+func (m *Metrics) Audio(r io.Reader, sampleRate int) io.Reader {
+	return &audioMeter{r: r, metrics: m, sampleRate: sampleRate}
+}
+
+// audioMeter counts an underrun every time the audio delivered falls
+// behind the wall clock, the player having drained its buffer
+type audioMeter struct {
+	r          io.Reader
+	metrics    *Metrics
+	sampleRate int
+
+	start     time.Time
+	delivered time.Duration
+}
+
+func (a *audioMeter) Read(p []byte) (int, error) {
+	now := time.Now()
+	if a.start.IsZero() || now.Sub(a.start) > a.delivered {
+		if !a.start.IsZero() {
+			a.metrics.mu.Lock()
+			a.metrics.underruns++
+			a.metrics.mu.Unlock()
+		}
+		a.start = now
+		a.delivered = 0
+	}
+
+	n, err := a.r.Read(p)
+	a.delivered += time.Duration(n/4) * time.Second / time.Duration(a.sampleRate)
+	return n, err
+}
+
+// Seek keeps the wrapped stream seekable
+func (a *audioMeter) Seek(offset int64, whence int) (int64, error) {
+	s, ok := a.r.(io.Seeker)
+	if !ok {
+		return 0, fmt.Errorf("music stream is not seekable")
+	}
+	a.start = time.Time{}
+	return s.Seek(offset, whence)
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	m.mu.Lock()
+	times := slices.Clone(m.frameTimes)
+	sum, count, fps, underruns := m.frameSum, m.frameCount, m.fps, m.underruns
+	m.mu.Unlock()
+	slices.Sort(times)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	fmt.Fprintln(w, "# HELP tcb_frame_time_seconds Time between rendered frames, quantiles over the last frames.")
+	fmt.Fprintln(w, "# TYPE tcb_frame_time_seconds summary")
+	for _, q := range []float64{0.5, 0.9, 0.99} {
+		v := 0.0
+		if len(times) > 0 {
+			v = times[min(len(times)-1, int(q*float64(len(times))))]
+		}
+		fmt.Fprintf(w, "tcb_frame_time_seconds{quantile=\"%g\"} %g\n", q, v)
+	}
+	fmt.Fprintf(w, "tcb_frame_time_seconds_sum %g\n", sum)
+	fmt.Fprintf(w, "tcb_frame_time_seconds_count %d\n", count)
+
+	fmt.Fprintln(w, "# HELP tcb_fps Frames rendered per second.")
+	fmt.Fprintln(w, "# TYPE tcb_fps gauge")
+	fmt.Fprintf(w, "tcb_fps %g\n", fps)
+
+	fmt.Fprintln(w, "# HELP tcb_audio_underruns_total Times the audio player ran out of music.")
+	fmt.Fprintln(w, "# TYPE tcb_audio_underruns_total counter")
+	fmt.Fprintf(w, "tcb_audio_underruns_total %d\n", underruns)
+
+	fmt.Fprintln(w, "# HELP tcb_gc_pause_seconds_total Time the garbage collector stopped the demo.")
+	fmt.Fprintln(w, "# TYPE tcb_gc_pause_seconds_total counter")
+	fmt.Fprintf(w, "tcb_gc_pause_seconds_total %g\n", float64(mem.PauseTotalNs)/1e9)
+	fmt.Fprintln(w, "# HELP tcb_gc_cycles_total Garbage collections completed.")
+	fmt.Fprintln(w, "# TYPE tcb_gc_cycles_total counter")
+	fmt.Fprintf(w, "tcb_gc_cycles_total %d\n", mem.NumGC)
+}
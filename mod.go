@@ -0,0 +1,652 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"time"
+)
+
+// Amiga PAL timings
+const (
+	paulaClock    = 3546894.6 // sample rate of period 1
+	modDefaultBPM = 125
+	modRows       = 64
+	modChannels   = 4
+)
+
+// modSample is one instrument of a MOD file
+type modSample struct {
+	data       []int8
+	finetune   int
+	volume     int
+	loopStart  int
+	loopLength int // 0 when the sample does not loop
+}
+
+// modNote is one cell of a pattern
+type modNote struct {
+	sample int
+	period int
+	effect int
+	param  int
+}
+
+// modChannel is the playback state of one Paula voice
+type modChannel struct {
+	sample   *modSample
+	pos      float64
+	period   int
+	volume   int
+	finetune int
+
+	// Period actually played this tick (arpeggio, vibrato)
+	outPeriod int
+
+	portaTarget int
+	portaSpeed  int
+	vibPos      int
+	vibSpeed    int
+	vibDepth    int
+	lastOffset  int
+	effect      int
+	param       int
+	delayed     *modNote
+}
+
+// MODPlayer decodes 4-channel ProTracker modules for Ebiten audio
+type MODPlayer struct {
+	mutex      sync.Mutex
+	sampleRate int
+	loop       bool
+	volume     volumeRamp
+	fade       volumeRamp
+
+	title    string
+	samples  [31]modSample
+	orders   []int
+	patterns [][modRows][modChannels]modNote
+
+	channels [modChannels]modChannel
+	order    int
+	row      int
+	tick     int
+	speed    int
+	bpm      int
+	tickLeft int // output samples left in the current tick
+
+	breakOrder int // -1 when no jump is pending
+	breakRow   int
+	patDelay   int
+
+	loops  int
+	ended  bool
+	played int64 // output samples
+	scope  *scopeRing
+	ticks  int64
+	levels [modChannels]float64
+
+	// First order of every song of the module and of the one playing
+	subsongs []int
+	start    int
+}
+
+// NewMODPlayer parses a 31-sample, 4-channel MOD file
+func NewMODPlayer(data []byte, sampleRate int, loop bool) (*MODPlayer, error) {
+	if len(data) < 1084 {
+		return nil, fmt.Errorf("failed to load MOD data: file too short")
+	}
+	switch sig := string(data[1080:1084]); sig {
+	case "M.K.", "M!K!", "FLT4", "4CHN":
+	default:
+		return nil, fmt.Errorf("failed to load MOD data: unsupported format %q", sig)
+	}
+
+	m := &MODPlayer{
+		sampleRate: sampleRate,
+		loop:       loop,
+		volume:     newVolumeRamp(0.7, sampleRate),
+		fade:       newVolumeRamp(1, sampleRate),
+		scope:      newScopeRing(modChannels),
+		title:      trimNul(data[:20]),
+	}
+
+	word := func(i int) int { return int(data[i])<<8 | int(data[i+1]) }
+	for i := range m.samples {
+		h := 20 + i*30
+		s := &m.samples[i]
+		s.finetune = int(data[h+24] & 0x0f)
+		if s.finetune > 7 {
+			s.finetune -= 16
+		}
+		s.volume = min(64, int(data[h+25]))
+		s.loopStart = word(h+26) * 2
+		if l := word(h+28) * 2; l > 2 {
+			s.loopLength = l
+		}
+		s.data = make([]int8, word(h+22)*2)
+	}
+
+	songLength := int(data[950])
+	if songLength == 0 || songLength > 128 {
+		return nil, fmt.Errorf("failed to load MOD data: invalid song length %d", songLength)
+	}
+	numPatterns := 0
+	for i := 0; i < 128; i++ {
+		numPatterns = max(numPatterns, int(data[952+i])+1)
+	}
+	for i := 0; i < songLength; i++ {
+		m.orders = append(m.orders, int(data[952+i]))
+	}
+
+	off := 1084
+	if off+numPatterns*modRows*modChannels*4 > len(data) {
+		return nil, fmt.Errorf("failed to load MOD data: truncated patterns")
+	}
+	m.patterns = make([][modRows][modChannels]modNote, numPatterns)
+	for p := range m.patterns {
+		for r := 0; r < modRows; r++ {
+			for c := 0; c < modChannels; c++ {
+				b := data[off : off+4]
+				m.patterns[p][r][c] = modNote{
+					sample: int(b[0]&0xf0) | int(b[2]>>4),
+					period: int(b[0]&0x0f)<<8 | int(b[1]),
+					effect: int(b[2] & 0x0f),
+					param:  int(b[3]),
+				}
+				off += 4
+			}
+		}
+	}
+
+	// Sample data follows, some rippers truncate the last one
+	for i := range m.samples {
+		s := &m.samples[i]
+		raw := data[min(off, len(data)):min(off+len(s.data), len(data))]
+		n := copy(s.data, toInt8(raw))
+		s.data = s.data[:n]
+		off += n
+		if s.loopStart+s.loopLength > len(s.data) {
+			s.loopLength = max(0, len(s.data)-s.loopStart)
+			if s.loopLength <= 2 {
+				s.loopLength = 0
+			}
+		}
+	}
+
+	m.subsongs = scanSubsongs(m.orders, m.patterns)
+	m.restart()
+	return m, nil
+}
+
+// scanSubsongs follows the order list from the start, through position
+// jumps and pattern breaks, and returns the first order of the song and
+// of every further song made of orders the previous ones never reach
+func scanSubsongs(orders []int, patterns [][modRows][modChannels]modNote) []int {
+	visited := make([]bool, len(orders))
+	var starts []int
+	for first := range orders {
+		if visited[first] {
+			continue
+		}
+		starts = append(starts, first)
+		for pos := first; pos < len(orders) && !visited[pos]; {
+			visited[pos] = true
+			next := pos + 1
+		rows:
+			for _, row := range patterns[orders[pos]] {
+				jump, brk := -1, false
+				for _, note := range row {
+					switch note.effect {
+					case 0xb:
+						jump = note.param
+					case 0xd:
+						brk = true
+					}
+				}
+				switch {
+				case jump >= 0:
+					next = jump
+					break rows
+				case brk:
+					break rows
+				}
+			}
+			pos = next
+		}
+	}
+	return starts
+}
+
+func trimNul(b []byte) string {
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+	return string(b)
+}
+
+// toInt8 converts signed 8-bit sample bytes
+func toInt8(b []byte) []int8 {
+	out := make([]int8, len(b))
+	for i, v := range b {
+		out[i] = int8(v)
+	}
+	return out
+}
+
+// restart rewinds to the beginning of the song
+func (m *MODPlayer) restart() {
+	m.order = m.start
+	m.row = 0
+	m.tick = 0
+	m.speed = 6
+	m.bpm = modDefaultBPM
+	m.breakOrder = -1
+	m.patDelay = 0
+	m.tickLeft = 0
+	for i := range m.channels {
+		m.channels[i] = modChannel{}
+	}
+}
+
+// Title returns the song name stored in the module
+func (m *MODPlayer) Title() string {
+	return m.title
+}
+
+// Read implements io.Reader for audio streaming
+func (m *MODPlayer) Read(p []byte) (n int, err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	frames := len(p) / 4
+	for i := 0; i < frames; i++ {
+		if m.tickLeft == 0 {
+			if m.ended {
+				return i * 4, io.EOF
+			}
+			m.processTick()
+			m.tickLeft = m.sampleRate * 5 / (m.bpm * 2)
+		}
+		m.tickLeft--
+		m.played++
+
+		l, r := m.mix()
+		v := m.volume.next() * m.fade.next()
+		putSample(p[i*4:], l*v)
+		putSample(p[i*4+2:], r*v)
+	}
+	return frames * 4, nil
+}
+
+func putSample(b []byte, v float64) {
+	s := int16(max(-32768, min(32767, v*32767)))
+	b[0] = byte(s)
+	b[1] = byte(s >> 8)
+}
+
+// mix renders one stereo frame. Paula plays voices 0 and 3 on the left
+// and 1 and 2 on the right, blended a little to soften headphones.
+func (m *MODPlayer) mix() (l, r float64) {
+	var out [modChannels]float64
+	for i := range m.channels {
+		ch := &m.channels[i]
+		s := ch.sample
+		if s == nil || ch.outPeriod == 0 || len(s.data) == 0 {
+			continue
+		}
+		idx := int(ch.pos)
+		if idx >= len(s.data) {
+			ch.sample = nil
+			continue
+		}
+		out[i] = float64(s.data[idx]) / 128 * float64(ch.volume) / 64
+
+		ch.pos += paulaClock / float64(ch.outPeriod) / float64(m.sampleRate)
+		if s.loopLength > 0 {
+			for ch.pos >= float64(s.loopStart+s.loopLength) {
+				ch.pos -= float64(s.loopLength)
+			}
+		}
+	}
+
+	m.scope.push(out[:])
+
+	left := out[0] + out[3]
+	right := out[1] + out[2]
+	return (left*0.75 + right*0.25) / 2, (right*0.75 + left*0.25) / 2
+}
+
+// processTick advances the sequencer by one tick
+func (m *MODPlayer) processTick() {
+	m.ticks++
+	if m.tick == 0 {
+		if m.patDelay > 0 {
+			m.patDelay--
+		} else {
+			m.playRow()
+		}
+	} else {
+		for i := range m.channels {
+			m.tickEffect(&m.channels[i])
+		}
+	}
+
+	for i := range m.channels {
+		ch := &m.channels[i]
+		if ch.sample != nil && ch.outPeriod > 0 {
+			m.levels[i] = float64(ch.volume) / 64
+		} else {
+			m.levels[i] = 0
+		}
+	}
+
+	m.tick++
+	if m.tick >= m.speed {
+		m.tick = 0
+		m.nextRow()
+	}
+}
+
+// nextRow moves the song position, following jumps and breaks
+func (m *MODPlayer) nextRow() {
+	if m.patDelay > 0 {
+		return
+	}
+	if m.breakOrder >= 0 {
+		if m.breakOrder <= m.order {
+			// Jumping back, count it as a loop of the song
+			m.loops++
+			if !m.loop {
+				m.ended = true
+			}
+		}
+		m.order = m.breakOrder
+		m.row = m.breakRow
+		m.breakOrder = -1
+	} else {
+		m.row++
+		if m.row >= modRows {
+			m.row = 0
+			m.order++
+		}
+	}
+	if m.order >= len(m.orders) {
+		m.order = m.start
+		m.loops++
+		if !m.loop {
+			m.ended = true
+		}
+	}
+}
+
+// periodFor returns period transposed by semitones and finetune eighths
+// of a semitone
+func periodFor(period, semitones, finetune int) int {
+	if period == 0 {
+		return 0
+	}
+	return int(math.Round(float64(period) * math.Pow(2, -float64(semitones*8+finetune)/96)))
+}
+
+// playRow triggers the notes of the current row and runs tick 0 effects
+func (m *MODPlayer) playRow() {
+	row := &m.patterns[m.orders[m.order]][m.row]
+	for i := range m.channels {
+		ch := &m.channels[i]
+		note := row[i]
+		ch.effect = note.effect
+		ch.param = note.param
+
+		if note.effect == 0xe && note.param>>4 == 0xd && note.param&0x0f > 0 {
+			// Note delay, triggered on a later tick
+			ch.delayed = &row[i]
+			continue
+		}
+		m.trigger(ch, &note)
+		m.rowEffect(ch, &note)
+		ch.outPeriod = ch.period
+	}
+}
+
+// trigger starts the sample and note of a cell
+func (m *MODPlayer) trigger(ch *modChannel, note *modNote) {
+	if note.sample > 0 && note.sample <= len(m.samples) {
+		s := &m.samples[note.sample-1]
+		ch.sample = s
+		ch.volume = s.volume
+		ch.finetune = s.finetune
+	}
+	if note.period == 0 {
+		return
+	}
+	period := periodFor(note.period, 0, ch.finetune)
+	if note.effect == 0x3 || note.effect == 0x5 {
+		// Tone portamento slides to the note instead of playing it
+		ch.portaTarget = period
+		return
+	}
+	ch.period = period
+	ch.pos = 0
+	ch.vibPos = 0
+}
+
+// rowEffect applies the effects acting on the first tick of a row
+func (m *MODPlayer) rowEffect(ch *modChannel, note *modNote) {
+	x, y := note.param>>4, note.param&0x0f
+	switch note.effect {
+	case 0x3:
+		if note.param != 0 {
+			ch.portaSpeed = note.param
+		}
+	case 0x4:
+		if x != 0 {
+			ch.vibSpeed = x
+		}
+		if y != 0 {
+			ch.vibDepth = y
+		}
+	case 0x9:
+		if note.param != 0 {
+			ch.lastOffset = note.param * 256
+		}
+		ch.pos = float64(ch.lastOffset)
+	case 0xb:
+		m.breakOrder = note.param
+		m.breakRow = 0
+	case 0xc:
+		ch.volume = min(64, note.param)
+	case 0xd:
+		if m.breakOrder < 0 {
+			m.breakOrder = m.order + 1
+		}
+		m.breakRow = min(modRows-1, x*10+y)
+	case 0xe:
+		switch x {
+		case 0x1:
+			ch.period = max(113, ch.period-y)
+		case 0x2:
+			ch.period = min(856*2, ch.period+y)
+		case 0xa:
+			ch.volume = min(64, ch.volume+y)
+		case 0xb:
+			ch.volume = max(0, ch.volume-y)
+		case 0xe:
+			m.patDelay = y
+		}
+	case 0xf:
+		if note.param == 0 {
+			break
+		}
+		if note.param < 32 {
+			m.speed = note.param
+		} else {
+			m.bpm = note.param
+		}
+	}
+}
+
+// vibratoOffset returns the vibrato period offset at the current
+// position of the channel
+func vibratoOffset(ch *modChannel) int {
+	return int(math.Sin(float64(ch.vibPos)*math.Pi/32) * float64(ch.vibDepth) * 2)
+}
+
+// tickEffect applies the effects acting on the later ticks of a row
+func (m *MODPlayer) tickEffect(ch *modChannel) {
+	x, y := ch.param>>4, ch.param&0x0f
+	ch.outPeriod = ch.period
+
+	switch ch.effect {
+	case 0x0:
+		if ch.param != 0 {
+			ch.outPeriod = periodFor(ch.period, [3]int{0, x, y}[m.tick%3], 0)
+		}
+	case 0x1:
+		ch.period = max(113, ch.period-ch.param)
+		ch.outPeriod = ch.period
+	case 0x2:
+		ch.period = min(856*2, ch.period+ch.param)
+		ch.outPeriod = ch.period
+	case 0x3:
+		m.tonePorta(ch)
+	case 0x4:
+		ch.vibPos = (ch.vibPos + ch.vibSpeed) % 64
+		ch.outPeriod = ch.period + vibratoOffset(ch)
+	case 0x5:
+		m.tonePorta(ch)
+		volumeSlide(ch, x, y)
+	case 0x6:
+		ch.vibPos = (ch.vibPos + ch.vibSpeed) % 64
+		ch.outPeriod = ch.period + vibratoOffset(ch)
+		volumeSlide(ch, x, y)
+	case 0xa:
+		volumeSlide(ch, x, y)
+	case 0xe:
+		switch x {
+		case 0x9:
+			if y > 0 && m.tick%y == 0 {
+				ch.pos = 0
+			}
+		case 0xc:
+			if m.tick == y {
+				ch.volume = 0
+			}
+		case 0xd:
+			if m.tick == y && ch.delayed != nil {
+				note := *ch.delayed
+				ch.delayed = nil
+				m.trigger(ch, &note)
+				ch.outPeriod = ch.period
+			}
+		}
+	}
+}
+
+// tonePorta slides the period towards the portamento target
+func (m *MODPlayer) tonePorta(ch *modChannel) {
+	if ch.portaTarget == 0 {
+		return
+	}
+	if ch.period < ch.portaTarget {
+		ch.period = min(ch.portaTarget, ch.period+ch.portaSpeed)
+	} else {
+		ch.period = max(ch.portaTarget, ch.period-ch.portaSpeed)
+	}
+	ch.outPeriod = ch.period
+}
+
+func volumeSlide(ch *modChannel, up, down int) {
+	if up > 0 {
+		ch.volume = min(64, ch.volume+up)
+	} else {
+		ch.volume = max(0, ch.volume-down)
+	}
+}
+
+// ChannelLevels returns the current volume of the four Paula voices in
+// the range [0,1]
+func (m *MODPlayer) ChannelLevels() []float64 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return append([]float64(nil), m.levels[:]...)
+}
+
+// Scope returns the last samples of the four voices
+func (m *MODPlayer) Scope() [][]float32 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.scope.copy()
+}
+
+// Sync returns the tick and voice state at the last audio callback
+func (m *MODPlayer) Sync() MusicSync {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return MusicSync{
+		Frame:    m.ticks,
+		Levels:   append([]float64(nil), m.levels[:]...),
+		Envelope: make([]bool, modChannels),
+	}
+}
+
+// Loops returns how many times the song has been played through
+func (m *MODPlayer) Loops() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.loops
+}
+
+// Elapsed returns how long the module has played
+func (m *MODPlayer) Elapsed() time.Duration {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return time.Duration(m.played) * time.Second / time.Duration(m.sampleRate)
+}
+
+// Volume returns the volume set last
+func (m *MODPlayer) Volume() float64 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.volume.target
+}
+
+// SetVolume ramps the volume to v
+func (m *MODPlayer) SetVolume(v float64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.volume.target = max(0, min(1, v))
+}
+
+// FadeOut fades the module to silence over d
+func (m *MODPlayer) FadeOut(d time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.fade.fadeOut(d, m.sampleRate)
+}
+
+// Subsongs returns the number of songs found in the module
+func (m *MODPlayer) Subsongs() int {
+	return len(m.subsongs)
+}
+
+// SetSubsong restarts playback at the start of song n, counted from 0
+func (m *MODPlayer) SetSubsong(n int) error {
+	if n < 0 || n >= len(m.subsongs) {
+		return fmt.Errorf("no subsong %d, the module has %d", n+1, len(m.subsongs))
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.start = m.subsongs[n]
+	m.ended = false
+	m.restart()
+	return nil
+}
+
+// Close releases resources
+func (m *MODPlayer) Close() error {
+	return nil
+}
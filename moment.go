@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"log"
+	"time"
+)
+
+// momentVersion is the first byte of a moment token, bumped when its
+// fields change
+const momentVersion = 2
+
+// Moment is a point of the demo that can be shared and reproduced: where
+// the scroll text and its waveform are, the palette and the music time
+type Moment struct {
+	TextSum uint32 // CRC-32 of the scroll text
+	TextPos uint32 // offset of the letter in the first slot
+	ScrollX float32
+	Wave    uint32 // updates the waveform moved on
+	Music   uint32 // milliseconds into the music
+	Form    uint8
+	Palette uint8
+}
+
+// String encodes m as a short token safe in a URL
+func (m Moment) String() string {
+	data, _ := binary.Append([]byte{momentVersion}, binary.LittleEndian, m)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// ParseMoment decodes a token made by Moment.String
+func ParseMoment(token string) (Moment, error) {
+	var m Moment
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return m, fmt.Errorf("failed to decode moment: %w", err)
+	}
+	if len(data) == 0 || data[0] != momentVersion {
+		return m, errors.New("unknown moment version")
+	}
+	if _, err := binary.Decode(data[1:], binary.LittleEndian, &m); err != nil {
+		return m, fmt.Errorf("failed to decode moment: %w", err)
+	}
+	return m, nil
+}
+
+// captureMoment takes the moment the demo is at
+func (g *Game) captureMoment() Moment {
+	s := g.scroller
+	m := Moment{
+		TextSum: crc32.ChecksumIEEE([]byte(s.Text)),
+		TextPos: uint32(s.addi),
+		ScrollX: float32(s.scrollX),
+		Wave:    uint32(s.wave),
+		Form:    uint8(s.form),
+		Palette: uint8(g.palette),
+	}
+	if g.audioPlayer != nil {
+		m.Music = uint32(g.audioPlayer.Position().Milliseconds())
+	}
+	return m
+}
+
+// applyMoment puts the demo at m. The random effects, such as the grain,
+// carry on from where they are.
+func (g *Game) applyMoment(m Moment) error {
+	s := g.scroller
+	if m.TextSum != crc32.ChecksumIEEE([]byte(s.Text)) {
+		return errors.New("the moment was taken with another scroll text")
+	}
+	s.Restart()
+	s.addi = min(int(m.TextPos), len(s.Text))
+	s.scrollX = float64(m.ScrollX)
+	s.wave = Ticks(m.Wave)
+	s.form = min(int(m.Form), len(scrollForms)-1)
+	s.snapForm()
+	g.setPalette(Palette(min(int(m.Palette), len(paletteNames)-1)))
+
+	if g.audioPlayer != nil {
+		if err := g.audioPlayer.SetPosition(time.Duration(m.Music) * time.Millisecond); err != nil {
+			return fmt.Errorf("failed to seek the music: %w", err)
+		}
+	}
+	return nil
+}
+
+// initMoment goes to the moment of -moment, or of the page address in a
+// browser
+func (g *Game) initMoment() {
+	token := g.cfg.Moment
+	if token == "" {
+		token = pageMoment()
+	}
+	if token == "" {
+		return
+	}
+	m, err := ParseMoment(token)
+	if err == nil {
+		err = g.applyMoment(m)
+	}
+	if err != nil {
+		log.Printf("Failed to go to the moment: %v", err)
+	}
+}
+
+// shareMoment takes the moment the demo is at and hands it out: logged
+// as a -moment option, and in a browser put in the page address
+func (g *Game) shareMoment() {
+	token := g.captureMoment().String()
+	if url := setPageMoment(token); url != "" {
+		log.Printf("Moment: %s", url)
+		g.notice.Show("Moment saved in the page address")
+		return
+	}
+	log.Printf("Moment: -moment %s", token)
+	g.notice.Show("Moment: -moment " + token)
+}
@@ -0,0 +1,29 @@
+//go:build js
+
+package main
+
+import "syscall/js"
+
+// momentParam is the query parameter of the page address holding a
+// moment
+const momentParam = "moment"
+
+// pageMoment returns the moment of the page address, empty for none
+func pageMoment() string {
+	url := js.Global().Get("URL").New(js.Global().Get("location").Get("href"))
+	v := url.Get("searchParams").Call("get", momentParam)
+	if v.IsNull() {
+		return ""
+	}
+	return v.String()
+}
+
+// setPageMoment puts a moment in the page address, without reloading,
+// and returns the address to share
+func setPageMoment(token string) string {
+	url := js.Global().Get("URL").New(js.Global().Get("location").Get("href"))
+	url.Get("searchParams").Call("set", momentParam, token)
+	href := url.Call("toString").String()
+	js.Global().Get("history").Call("replaceState", js.Null(), "", href)
+	return href
+}
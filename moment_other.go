@@ -0,0 +1,13 @@
+//go:build !js
+
+package main
+
+// pageMoment has no page address to read off the browser
+func pageMoment() string {
+	return ""
+}
+
+// setPageMoment has no page address to write off the browser
+func setPageMoment(string) string {
+	return ""
+}
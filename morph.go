@@ -0,0 +1,130 @@
+package main
+
+import (
+	"math"
+
+	"tcb-multi-plane-3d-scroller/easings"
+)
+
+// defaultFormMorph is how many seconds a scroller takes to morph into a
+// new waveform
+const defaultFormMorph = 0.5
+
+// defaultMorphCurve eases in and out, as the original glided between its
+// forms
+const defaultMorphCurve = "smooth"
+
+// lerpForm returns the waveform t of the way from a to b
+func lerpForm(a, b ScrollForm, t float64) ScrollForm {
+	lerp := func(x, y float64) float64 { return easings.Lerp(x, y, t) }
+	return ScrollForm{
+		zSize:    lerp(a.zSize, b.zSize),
+		zAmount:  lerp(a.zAmount, b.zAmount),
+		zSpeed:   lerp(a.zSpeed, b.zSpeed),
+		zAdd:     lerp(a.zAdd, b.zAdd),
+		ySize:    lerp(a.ySize, b.ySize),
+		yAmount:  lerp(a.yAmount, b.yAmount),
+		ySpeed:   lerp(a.ySpeed, b.ySpeed),
+		xSize:    lerp(a.xSize, b.xSize),
+		xAmount:  lerp(a.xAmount, b.xAmount),
+		xSpeed:   lerp(a.xSpeed, b.xSpeed),
+		rSize:    lerp(a.rSize, b.rSize),
+		rAmount:  lerp(a.rAmount, b.rAmount),
+		rSpeed:   lerp(a.rSpeed, b.rSpeed),
+		fogDark:  lerp(a.fogDark, b.fogDark),
+		fogFade:  lerp(a.fogFade, b.fogFade),
+		fogCurve: pickCurve(a.fogCurve, b.fogCurve, t),
+	}
+}
+
+// wavePhases holds where the depth, height, wobble and rotation waves of
+// a form are, in radians
+type wavePhases [4]float64
+
+// speeds returns the speeds of the waves of sf, in the order of
+// wavePhases
+func (sf ScrollForm) speeds() wavePhases {
+	return wavePhases{sf.zSpeed, sf.ySpeed, sf.xSpeed, sf.rSpeed}
+}
+
+// phasesAt returns where the waves of sf are after t updates
+func (sf ScrollForm) phasesAt(t Ticks) wavePhases {
+	var ph wavePhases
+	for k, speed := range sf.speeds() {
+		ph[k] = t.Phase(waveStep * speed)
+	}
+	return ph
+}
+
+// pickCurve returns curve a until halfway through a morph, then b, as
+// curves don't blend
+func pickCurve(a, b easings.Curve, t float64) easings.Curve {
+	if t < 0.5 {
+		return a
+	}
+	return b
+}
+
+// targetForm returns the waveform the scroller is set to, by the codes
+// or locked
+func (s *Scroller) targetForm() int {
+	if s.lockedForm >= 0 {
+		return s.lockedForm
+	}
+	return s.form
+}
+
+// morphedForm returns the waveform of the line, on its way from the
+// last one to the one set, and where its waves are.
+//
+// The phases are not those of the blended speeds, which are the updates
+// so far times the speed and would sweep through many turns as the speed
+// changes. The waves of both forms run on at their own speeds from where
+// they were when the morph started, and the phases are blended between
+// them, the way the phases of the new form were then the nearest.
+func (s *Scroller) morphedForm() (ScrollForm, wavePhases) {
+	to := s.Forms[min(s.morphTo, len(s.Forms)-1)]
+	if s.morphAt >= 1 {
+		return to, to.phasesAt(s.wave)
+	}
+	t := s.MorphCurve.At(s.morphAt)
+	since := (float64(s.wave) - float64(s.morphWave)) * waveStep
+	from, toSpeeds := s.morphFrom.speeds(), to.speeds()
+	var ph wavePhases
+	for k := range ph {
+		start := s.morphPhases[k] + since*from[k]
+		gap := s.morphGap[k] + since*(toSpeeds[k]-from[k])
+		ph[k] = math.Mod(start+gap*t, 2*math.Pi)
+		if ph[k] < 0 {
+			ph[k] += 2 * math.Pi
+		}
+	}
+	return lerpForm(s.morphFrom, to, t), ph
+}
+
+// morph starts morphing into the waveform set when it changed, and moves
+// the morph on by an update
+func (s *Scroller) morph() {
+	if target := s.targetForm(); target != s.morphTo {
+		s.morphFrom, s.morphPhases = s.morphedForm()
+		s.morphTo = target
+		s.morphAt = 0
+		s.morphWave = s.wave
+		to := s.Forms[min(target, len(s.Forms)-1)].phasesAt(s.wave)
+		for k := range to {
+			s.morphGap[k] = math.Remainder(to[k]-s.morphPhases[k], 2*math.Pi)
+		}
+	}
+	if s.FormMorph <= 0 || s.Rate <= 0 {
+		s.morphAt = 1
+		return
+	}
+	s.morphAt = min(1, s.morphAt+1/(s.FormMorph*float64(s.Rate)))
+}
+
+// snapForm ends the morph at the waveform set, for states that must be
+// shown exactly
+func (s *Scroller) snapForm() {
+	s.morphTo = s.targetForm()
+	s.morphAt = 1
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// turn returns how far phase a is from b, the short way round
+func turn(a, b float64) float64 {
+	return math.Abs(math.Remainder(a-b, 2*math.Pi))
+}
+
+func TestMorphPhasesLongRun(t *testing.T) {
+	s := newTestScroller("HELLO")
+	s.Rate = 60
+	s.FormMorph = defaultFormMorph
+	// Ten minutes in, a morph between forms of opposite speeds
+	s.wave = 10 * 60 * 60
+	s.lockedForm = 5
+	s.snapForm()
+	_, last := s.morphedForm()
+	s.lockedForm = 6
+
+	steps := int(s.FormMorph*float64(s.Rate)) + 2
+	for i := 0; i < steps; i++ {
+		s.wave++
+		s.morph()
+		form, ph := s.morphedForm()
+		for k := range ph {
+			// The most a wave moves in an update, plus the morph
+			// closing a gap of at most half a turn over its updates
+			limit := waveStep*math.Max(math.Abs(s.morphFrom.speeds()[k]), math.Abs(form.speeds()[k])) + 2*math.Pi/float64(steps)
+			if d := turn(ph[k], last[k]); d > limit {
+				t.Fatalf("update %d: wave %d jumped %g radians", i, k, d)
+			}
+		}
+		last = ph
+	}
+	if s.morphAt < 1 {
+		t.Fatalf("morph at %g after %d updates", s.morphAt, steps)
+	}
+	want := s.Forms[6].phasesAt(s.wave)
+	for k := range last {
+		if d := turn(last[k], want[k]); d > 1e-9 {
+			t.Errorf("wave %d ends %g radians off form 6", k, d)
+		}
+	}
+}
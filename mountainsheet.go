@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"os"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"tcb-multi-plane-3d-scroller/hooks"
+)
+
+// Layout of the mountain contact sheet, in pixels
+const (
+	sheetZoom   = 2   // vertical zoom of the strips
+	sheetLabel  = 280 // width of the label column
+	sheetGap    = 6   // between rows
+	sheetMargin = 8
+	sheetHeader = 24
+	sheetRepeat = 512 // the strips repeat every 512 canvas pixels
+)
+
+// MountainSheet renders every layer of the mountains image on its own row with
+// its place and speed, for authoring replacement backgrounds, then
+// stops the demo
+type MountainSheet struct {
+	game *Game
+	path string
+}
+
+// NewMountainSheet hooks the sheet into the first update
+func NewMountainSheet(g *Game, path string) *MountainSheet {
+	m := &MountainSheet{game: g, path: path}
+	g.hooks.Register(hooks.PreUpdate, "mountain-sheet", m.update)
+	return m
+}
+
+func (m *MountainSheet) update(ctx *hooks.Context) error {
+	if err := m.write(); err != nil {
+		return err
+	}
+	log.Printf("Mountain layers written to %s", m.path)
+	return ebiten.Termination
+}
+
+// write draws the sheet and saves it as PNG
+func (m *MountainSheet) write() error {
+	g := m.game
+	w := sheetMargin*2 + sheetLabel + g.mountains.Bounds().Dx()
+	h := sheetHeader + sheetMargin
+	for _, l := range g.layers {
+		h += sheetRow(l)
+	}
+	sheet := ebiten.NewImage(w, h)
+	defer sheet.Deallocate()
+	sheet.Fill(color.RGBA{0x20, 0x20, 0x20, 0xff})
+
+	ebitenutil.DebugPrintAt(sheet, "LAYER  SOURCE Y  SCREEN Y  SPEED (PX/FRAME)", sheetMargin, 4)
+	x0 := float32(sheetMargin + sheetLabel)
+	ebitenutil.DebugPrintAt(sheet, fmt.Sprintf("| REPEATS EVERY %d", sheetRepeat), int(x0)+sheetRepeat, 4)
+
+	y := sheetHeader
+	for i, l := range g.layers {
+		// Source rows, then the place of the strip on papercanvas2 as
+		// drawDemo lays it out
+		label := fmt.Sprintf("%5d  %3d-%-3d   %3d-%-3d   %.1f", i, l.Top, l.Bottom, l.Y, l.Y+l.Height(), l.Speed)
+		ebitenutil.DebugPrintAt(sheet, label, sheetMargin, y+(sheetRow(l)-16)/2)
+
+		// Magenta shows through the transparent parts of the strip
+		vector.DrawFilledRect(sheet, x0, float32(y), float32(g.mountains.Bounds().Dx()), float32(l.Height()*sheetZoom), color.RGBA{0xff, 0, 0xff, 0xff}, false)
+		strip := g.mountains.SubImage(image.Rect(0, l.Top, g.mountains.Bounds().Dx(), l.Bottom)).(*ebiten.Image)
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Scale(1, sheetZoom)
+		op.GeoM.Translate(float64(x0), float64(y))
+		sheet.DrawImage(strip, op)
+		y += sheetRow(l)
+	}
+	vector.StrokeLine(sheet, x0+sheetRepeat, sheetHeader-4, x0+sheetRepeat, float32(h-sheetMargin), 1, color.White, false)
+
+	img := image.NewRGBA(sheet.Bounds())
+	sheet.ReadPixels(img.Pix)
+
+	f, err := os.Create(m.path)
+	if err != nil {
+		return fmt.Errorf("failed to create mountain sheet: %w", err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		return fmt.Errorf("failed to write mountain sheet: %w", err)
+	}
+	return nil
+}
+
+// sheetRow returns the height of the sheet row showing l
+func sheetRow(l MountainLayer) int {
+	return l.Height()*sheetZoom + sheetGap
+}
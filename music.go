@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// MusicSource is a music backend streamed to the audio player as 16-bit
+// stereo samples
+type MusicSource interface {
+	io.Reader
+
+	// ChannelLevels returns the current volume of every voice in [0,1]
+	ChannelLevels() []float64
+
+	// Loops returns how many times the tune has been played through
+	Loops() int
+
+	// Scope returns the last samples of every voice, oldest first
+	Scope() [][]float32
+
+	// Sync returns the state of the music at the last audio callback
+	Sync() MusicSync
+
+	// Elapsed returns how long the music has played
+	Elapsed() time.Duration
+
+	// Volume returns the volume set last, in [0,1]
+	Volume() float64
+
+	// SetVolume ramps the volume to v in [0,1]
+	SetVolume(v float64)
+
+	// FadeOut fades the music to silence over d, on top of the volume
+	FadeOut(d time.Duration)
+
+	// Subsongs returns how many tunes the music holds
+	Subsongs() int
+
+	// SetSubsong restarts playback with tune n, counted from 0
+	SetSubsong(n int) error
+
+	Close() error
+}
+
+// NewMusicSource loads the music file at path, or the first built-in
+// tune when path is empty. MOD files are recognized by their extension or
+// their signature, WAV, Ogg Vorbis and MP3 files by their extension,
+// anything else is played as YM.
+func NewMusicSource(path string, sampleRate int, loop bool) (MusicSource, error) {
+	if path == "" {
+		tracks := embeddedTracks()
+		if len(tracks) == 0 {
+			return nil, fmt.Errorf("no built-in music")
+		}
+		return tracks[0].Load(sampleRate, loop)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read music: %w", err)
+	}
+	return loadMusic(data, filepath.Ext(path), sampleRate, loop)
+}
+
+// loadMusic creates the music source playing data, in the format of the
+// file extension ext
+func loadMusic(data []byte, ext string, sampleRate int, loop bool) (MusicSource, error) {
+	if strings.EqualFold(ext, ".mod") || isMOD(data) {
+		return NewMODPlayer(data, sampleRate, loop)
+	}
+	if isSampledAudio(ext) {
+		return NewSampledPlayer(data, ext, sampleRate, loop)
+	}
+	return NewYMPlayer(data, sampleRate, loop)
+}
+
+// isMusicFile reports whether the file name has the extension of a
+// format the demo plays
+func isMusicFile(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	return ext == ".ym" || ext == ".mod" || isSampledAudio(ext)
+}
+
+// MusicSync is the state of the music for effects following the tune
+type MusicSync struct {
+	// Frame counts the replay frames played: 50 Hz YM frames, MOD ticks
+	Frame int64
+	// Levels is the volume of every voice in [0,1]
+	Levels []float64
+	// Envelope marks the YM voices following the hardware envelope
+	Envelope []bool
+	// Beats counted on the beat grid of sampled music at BPM, 0 for
+	// chip music
+	Beat int64
+	BPM  float64
+}
+
+// volumeRampTime is how long a volume change takes, in seconds. Going
+// there sample by sample avoids the clicks of a sudden gain step.
+const volumeRampTime = 0.05
+
+// volumeRamp is a music volume moving linearly to its target
+type volumeRamp struct {
+	current float64
+	target  float64
+	step    float64 // change per sample
+}
+
+func newVolumeRamp(v float64, sampleRate int) volumeRamp {
+	return volumeRamp{current: v, target: v, step: 1 / (volumeRampTime * float64(sampleRate))}
+}
+
+// fadeOut ramps to silence over d from the current volume
+func (r *volumeRamp) fadeOut(d time.Duration, sampleRate int) {
+	r.target = 0
+	r.step = r.current / max(1, d.Seconds()*float64(sampleRate))
+}
+
+// next returns the volume of the next sample
+func (r *volumeRamp) next() float64 {
+	switch {
+	case r.current < r.target:
+		r.current = min(r.target, r.current+r.step)
+	case r.current > r.target:
+		r.current = max(r.target, r.current-r.step)
+	}
+	return r.current
+}
+
+// isMOD reports whether data carries a 4-channel ProTracker signature
+func isMOD(data []byte) bool {
+	if len(data) < 1084 {
+		return false
+	}
+	sig := data[1080:1084]
+	for _, s := range []string{"M.K.", "M!K!", "FLT4", "4CHN"} {
+		if bytes.Equal(sig, []byte(s)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// Music pulse driving the logo distortion and the rasters
+const (
+	pulseAttack = 0.3  // voice level rise starting a pulse
+	pulseDecay  = 0.9  // pulse kept from one frame to the next
+	syncPull    = 0.05 // share of the drift to the music corrected per frame
+
+	flashDecay   = 0.8  // beat flash kept from one frame to the next
+	flashAlpha   = 0.15 // opacity of the beat flash
+	beatsPerForm = 64   // 16 bars of 4 beats between two scroller forms
+)
+
+// updateMusicSync follows the music: the replay frame, for effects
+// counting in time with the tune, and a pulse set when a voice gets
+// louder, as on a new note
+func (g *Game) updateMusicSync() {
+	g.pulse *= pulseDecay
+	g.flash *= flashDecay
+	if g.music == nil {
+		return
+	}
+
+	s := g.music.Sync()
+	for c, level := range s.Levels {
+		if c < len(g.musicSync.Levels) && level-g.musicSync.Levels[c] >= pulseAttack {
+			g.pulse = 1
+		}
+	}
+
+	// The audio callbacks come in bursts, so advance at the replay rate
+	// and slowly pull toward the frame of the last callback
+	g.musicFrame += ymFrameRate / float64(ebiten.TPS())
+	g.musicFrame += (float64(s.Frame) - g.musicFrame) * syncPull
+	// Sampled music has no notes to follow, only its beat grid
+	if s.Beat != g.musicSync.Beat {
+		g.flash = 1
+		if s.Beat%beatsPerForm == 0 && g.scroller != nil {
+			g.scroller.form = (g.scroller.form + 1) % len(g.scroller.Forms)
+		}
+	}
+	g.musicSync = s
+}
+
+// drawBeatFlash lights the screen up on the beats of sampled music
+func (g *Game) drawBeatFlash(dst *ebiten.Image) {
+	if g.flash < 0.01 {
+		return
+	}
+	a := uint8(255 * flashAlpha * g.flash)
+	vector.DrawFilledRect(dst, 0, 0, float32(screenWidth), float32(screenHeight), color.RGBA{a, a, a, a}, false)
+}
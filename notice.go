@@ -0,0 +1,44 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// noticeFrames is how long a notice stays on screen
+const noticeFrames = 120
+
+// Notice is a one-line message shown briefly at the top of the screen,
+// e.g. after a key changed a setting
+type Notice struct {
+	text   string
+	frames int
+}
+
+// Show replaces the current message
+func (n *Notice) Show(text string) {
+	n.text = text
+	n.frames = noticeFrames
+}
+
+// Update counts down the display time
+func (n *Notice) Update() {
+	if n.frames > 0 {
+		n.frames--
+	}
+}
+
+// Draw renders the message while it is shown
+func (n *Notice) Draw(screen *ebiten.Image) {
+	if n.frames == 0 {
+		return
+	}
+	// Debug font glyphs are 6x16
+	w := len(n.text)*6 + 16
+	x := (screen.Bounds().Dx() - w) / 2
+	vector.DrawFilledRect(screen, float32(x), 8, float32(w), 24, color.RGBA{0, 0, 0, 0xc0}, false)
+	ebitenutil.DebugPrintAt(screen, n.text, x+8, 12)
+}
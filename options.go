@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// Option is one adjustable entry of the options menu
+type Option struct {
+	Label  string
+	Value  func() string
+	Change func(delta int)
+}
+
+// OptionsMenu is the in-demo settings overlay
+type OptionsMenu struct {
+	items   []Option
+	cursor  int
+	visible bool
+}
+
+// Add appends an entry to the menu
+func (m *OptionsMenu) Add(o Option) {
+	m.items = append(m.items, o)
+}
+
+// AddToggle appends an on/off entry
+func (m *OptionsMenu) AddToggle(label string, get func() bool, set func(bool)) {
+	m.Add(Option{
+		Label: label,
+		Value: func() string {
+			if get() {
+				return "ON"
+			}
+			return "OFF"
+		},
+		Change: func(int) { set(!get()) },
+	})
+}
+
+// AddEffect appends an entry switching a registered effect
+func (m *OptionsMenu) AddEffect(label string, effects *EffectRegistry, name string) {
+	if effects.Lookup(name) == nil {
+		return
+	}
+	m.AddToggle(label,
+		func() bool { return effects.Enabled(name) },
+		func(on bool) { effects.SetEnabled(name, on) })
+}
+
+// Toggle shows or hides the menu
+func (m *OptionsMenu) Toggle() {
+	m.visible = !m.visible
+}
+
+// Visible reports whether the menu is shown
+func (m *OptionsMenu) Visible() bool {
+	return m.visible
+}
+
+// Update moves the cursor and changes values while the menu is shown
+func (m *OptionsMenu) Update() {
+	if !m.visible || len(m.items) == 0 {
+		return
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyUp) {
+		m.cursor = (m.cursor + len(m.items) - 1) % len(m.items)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyDown) {
+		m.cursor = (m.cursor + 1) % len(m.items)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyLeft) {
+		m.items[m.cursor].Change(-1)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyRight) || inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
+		m.items[m.cursor].Change(1)
+	}
+}
+
+// Draw renders the menu over the screen
+func (m *OptionsMenu) Draw(screen *ebiten.Image) {
+	if !m.visible {
+		return
+	}
+
+	const (
+		x          = 24
+		y          = 24
+		lineHeight = 16
+	)
+	h := float32(len(m.items)*lineHeight + 40)
+	vector.DrawFilledRect(screen, x, y, 320, h, color.RGBA{0, 0, 0, 0xc0}, false)
+
+	ebitenutil.DebugPrintAt(screen, "OPTIONS  (TAB to close)", x+8, y+8)
+	for i, item := range m.items {
+		cursor := " "
+		if i == m.cursor {
+			cursor = ">"
+		}
+		line := fmt.Sprintf("%s %-20s %s", cursor, item.Label, item.Value())
+		ebitenutil.DebugPrintAt(screen, line, x+8, y+28+i*lineHeight)
+	}
+}
+
+// cycle steps an index through n values, wrapping around
+func cycle(i, delta, n int) int {
+	return ((i+delta)%n + n) % n
+}
+
+// initOptions builds the options menu from the available settings
+func (g *Game) initOptions() {
+	g.options = &OptionsMenu{}
+
+	if g.blurrer != nil {
+		g.options.Add(Option{
+			Label: "Blur quality",
+			Value: func() string { return g.blurrer.Quality.String() },
+			Change: func(delta int) {
+				g.blurrer.Quality = BlurQuality(cycle(int(g.blurrer.Quality), delta, len(blurQualityNames)))
+			},
+		})
+	}
+	g.options.AddEffect("Heat haze", g.effects, "haze")
+	g.options.AddEffect("Water ripple", g.effects, "ripple")
+	g.options.AddEffect("Scroller glow", g.effects, "glow")
+	g.options.AddEffect("Bloom", g.effects, "bloom")
+	g.options.Add(Option{
+		Label: "Scroll mode",
+		Value: func() string { return g.scroller.Mode.String() },
+		Change: func(delta int) {
+			g.scroller.Mode = ScrollMode(cycle(int(g.scroller.Mode), delta, len(scrollModeNames)))
+		},
+	})
+	g.options.AddToggle("Proportional text",
+		func() bool { return g.scroller.Proportional },
+		func(on bool) { g.scroller.Proportional = on })
+	g.options.AddToggle("ST filter",
+		func() bool { return g.cfg.LowPass },
+		g.setLowPass)
+	g.options.AddToggle("Camera pan",
+		func() bool { return g.camera.Enabled },
+		func(on bool) { g.camera.Enabled = on })
+
+	if g.grain != nil {
+		grainLevels := []float64{0, 0.05, 0.12}
+		g.options.Add(Option{
+			Label: "Film grain",
+			Value: func() string { return fmt.Sprintf("%.2f", g.grain.Grain) },
+			Change: func(delta int) {
+				i := 0
+				for j, v := range grainLevels {
+					if v <= g.grain.Grain {
+						i = j
+					}
+				}
+				g.grain.Grain = grainLevels[cycle(i, delta, len(grainLevels))]
+			},
+		})
+		g.options.AddToggle("ST palette",
+			func() bool { return g.grain.Quantize },
+			func(on bool) { g.grain.Quantize = on })
+		g.options.AddToggle("Dithering",
+			func() bool { return g.grain.Dither },
+			func(on bool) { g.grain.Dither = on })
+	}
+	g.addCalibrationOptions()
+
+	// Accessibility
+	g.options.Add(Option{
+		Label: "Color palette",
+		Value: func() string { return g.palette.String() },
+		Change: func(delta int) {
+			g.setPalette(Palette(cycle(int(g.palette), delta, len(paletteNames))))
+		},
+	})
+}
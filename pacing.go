@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/audio"
+)
+
+const (
+	// pacingLogInterval is how often the diagnostics are logged
+	pacingLogInterval = 10 * time.Second
+	// pacingMaxStep caps the time one update catches up, so a stall
+	// doesn't fast-forward the demo
+	pacingMaxStep = 250 * time.Millisecond
+	// pacingAudioSlack is how far the music may fall behind the clock
+	// before it counts as an underrun
+	pacingAudioSlack = 100 * time.Millisecond
+)
+
+// intervalStats sums up the intervals between frames
+type intervalStats struct {
+	n    int
+	sum  time.Duration
+	max  time.Duration
+	long int // more than twice the expected interval
+}
+
+func (s *intervalStats) add(d, expected time.Duration) {
+	s.n++
+	s.sum += d
+	s.max = max(s.max, d)
+	if d > 2*expected {
+		s.long++
+	}
+}
+
+func (s intervalStats) String() string {
+	if s.n == 0 {
+		return "none"
+	}
+	return fmt.Sprintf("avg %v max %v, %d long", (s.sum / time.Duration(s.n)).Round(100*time.Microsecond), s.max.Round(100*time.Microsecond), s.long)
+}
+
+// FramePacing watches how regularly frames arrive and times the demo by
+// the clock rather than by counting updates. Browsers throttle the
+// requestAnimationFrame callbacks of hidden or busy tabs, which made the
+// demo stutter and slow down.
+type FramePacing struct {
+	expected time.Duration // between two updates at the TPS
+
+	// Reported by the browser, from its own callbacks
+	mu         sync.Mutex
+	animation  intervalStats // requestAnimationFrame intervals
+	visibility int           // visibility changes
+	resumed    bool          // shown again since the last update
+	browser    bool
+
+	updates   intervalStats
+	underruns int
+	last      time.Time
+	lastLog   time.Time
+	step      time.Duration
+
+	audioPos time.Duration
+	audioAt  time.Time
+}
+
+// NewFramePacing starts watching the frames, and the page when running
+// in a browser
+func NewFramePacing() *FramePacing {
+	p := &FramePacing{
+		expected: time.Second / time.Duration(ebiten.TPS()),
+		lastLog:  time.Now(),
+	}
+	p.browser = watchBrowser(p)
+	return p
+}
+
+// animationFrame records a requestAnimationFrame interval
+func (p *FramePacing) animationFrame(d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.animation.add(d, time.Second/60)
+}
+
+// visibilityChanged records the page being hidden or shown
+func (p *FramePacing) visibilityChanged(hidden bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.visibility++
+	if !hidden {
+		p.resumed = true
+	}
+	state := "visible"
+	if hidden {
+		state = "hidden"
+	}
+	log.Printf("Frame pacing: page %s", state)
+}
+
+// Update measures the time since the last update, and how far player
+// kept up with it, nil for no music
+func (p *FramePacing) Update(player *audio.Player) {
+	now := time.Now()
+	d := p.expected
+	if !p.last.IsZero() {
+		d = now.Sub(p.last)
+		p.updates.add(d, p.expected)
+	}
+	p.last = now
+
+	p.mu.Lock()
+	resumed := p.resumed
+	p.resumed = false
+	p.mu.Unlock()
+
+	// The time the page was hidden is dropped rather than caught up
+	p.step = min(d, pacingMaxStep)
+	if resumed {
+		p.step = p.expected
+	}
+
+	p.checkAudio(player, now, resumed)
+	if now.Sub(p.lastLog) >= pacingLogInterval {
+		p.log(now)
+	}
+}
+
+// checkAudio counts an underrun when the music fell behind the clock
+func (p *FramePacing) checkAudio(player *audio.Player, now time.Time, resumed bool) {
+	if player == nil || !player.IsPlaying() {
+		p.audioAt = time.Time{}
+		return
+	}
+	pos := player.Position()
+	if p.audioAt.IsZero() || resumed {
+		p.audioPos, p.audioAt = pos, now
+		return
+	}
+	played, wall := pos-p.audioPos, now.Sub(p.audioAt)
+	switch {
+	case played >= 0 && played < wall-pacingAudioSlack:
+		p.underruns++
+	case played >= 0 && wall < time.Second:
+		// Keep measuring from the same point
+		return
+	}
+	// Start over after an underrun, a seek back or a second
+	p.audioPos, p.audioAt = pos, now
+}
+
+// log writes the diagnostics since the last log and starts over
+func (p *FramePacing) log(now time.Time) {
+	p.mu.Lock()
+	animation, visibility := p.animation, p.visibility
+	p.animation, p.visibility = intervalStats{}, 0
+	p.mu.Unlock()
+
+	log.Printf("Frame pacing: updates %v; animation frames %v; %d audio underruns; %d visibility changes",
+		p.updates, animation, p.underruns, visibility)
+	p.updates, p.underruns = intervalStats{}, 0
+	p.lastLog = now
+}
+
+// Step returns the time the demo moves on by this update
+func (p *FramePacing) Step() time.Duration {
+	return p.step
+}
+
+// Summary returns the pacing since the last log, for the debug overlay
+func (p *FramePacing) Summary() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := fmt.Sprintf("PACING   updates %v, %d underruns", p.updates, p.underruns)
+	if p.browser {
+		s += fmt.Sprintf("; rAF %v", p.animation)
+	}
+	return s
+}
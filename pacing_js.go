@@ -0,0 +1,37 @@
+//go:build js
+
+package main
+
+import (
+	"syscall/js"
+	"time"
+)
+
+// watchBrowser times the requestAnimationFrame callbacks and listens to
+// the visibility changes of the page
+func watchBrowser(p *FramePacing) bool {
+	window := js.Global()
+	document := window.Get("document")
+
+	var last float64
+	var frame js.Func
+	frame = js.FuncOf(func(this js.Value, args []js.Value) any {
+		now := args[0].Float()
+		if last > 0 {
+			p.animationFrame(time.Duration((now - last) * float64(time.Millisecond)))
+		}
+		last = now
+		window.Call("requestAnimationFrame", frame)
+		return nil
+	})
+	window.Call("requestAnimationFrame", frame)
+
+	document.Call("addEventListener", "visibilitychange", js.FuncOf(func(this js.Value, args []js.Value) any {
+		hidden := document.Get("hidden").Bool()
+		// Hidden tabs get no animation frames, don't count the gap
+		last = 0
+		p.visibilityChanged(hidden)
+		return nil
+	}))
+	return true
+}
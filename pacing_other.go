@@ -0,0 +1,8 @@
+//go:build !js
+
+package main
+
+// watchBrowser has no page to watch off the browser
+func watchBrowser(*FramePacing) bool {
+	return false
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"slices"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/colorm"
+)
+
+// Palette selects the colors of the rasters, the background and the
+// color banks, with alternatives for red-green color blindness
+type Palette int
+
+const (
+	// PaletteNormal keeps the colors of the original demo
+	PaletteNormal Palette = iota
+	// PaletteDeuteranopia is tuned for a missing green cone
+	PaletteDeuteranopia
+	// PaletteProtanopia is tuned for a missing red cone
+	PaletteProtanopia
+)
+
+var paletteNames = []string{"normal", "deuteranopia", "protanopia"}
+
+func (p Palette) String() string {
+	if p < PaletteNormal || p > PaletteProtanopia {
+		return "unknown"
+	}
+	return paletteNames[p]
+}
+
+// Set implements flag.Value
+func (p *Palette) Set(s string) error {
+	for i, name := range paletteNames {
+		if strings.EqualFold(s, name) {
+			*p = Palette(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown palette %q", s)
+}
+
+// paletteSimulations are how each palette's viewer sees the RGB colors,
+// after Machado, Oliveira and Fernandes (2009) at full severity
+var paletteSimulations = [...][3][3]float64{
+	PaletteNormal: {{1, 0, 0}, {0, 1, 0}, {0, 0, 1}},
+	PaletteDeuteranopia: {
+		{0.367322, 0.860646, -0.227968},
+		{0.280085, 0.672501, 0.047413},
+		{-0.011820, 0.042940, 0.968881},
+	},
+	PaletteProtanopia: {
+		{0.152286, 1.052583, -0.204868},
+		{0.114503, 0.786281, 0.099216},
+		{-0.003882, -0.048116, 1.051998},
+	},
+}
+
+// matrix returns the matrix of p: the colors lost to the viewer
+// are moved to the green and blue channels they still tell apart
+// (daltonization). Grays, the rasters' white included, are kept.
+func (p Palette) matrix() [3][3]float64 {
+	sim := paletteSimulations[p]
+	shift := [3][3]float64{{0, 0, 0}, {0.7, 1, 0}, {0.7, 0, 1}}
+
+	var m [3][3]float64
+	for i := range 3 {
+		for j := range 3 {
+			if i == j {
+				m[i][j] = 1
+			}
+			for k := range 3 {
+				lost := -sim[k][j]
+				if k == j {
+					lost++
+				}
+				m[i][j] += shift[i][k] * lost
+			}
+		}
+	}
+	return m
+}
+
+// Color returns c in palette p
+func (p Palette) Color(c color.RGBA) color.RGBA {
+	if p == PaletteNormal {
+		return c
+	}
+	m := p.matrix()
+	in := [3]float64{float64(c.R), float64(c.G), float64(c.B)}
+	var out [3]uint8
+	for i := range 3 {
+		v := m[i][0]*in[0] + m[i][1]*in[1] + m[i][2]*in[2]
+		out[i] = uint8(min(max(v, 0), float64(c.A)))
+	}
+	return color.RGBA{out[0], out[1], out[2], c.A}
+}
+
+// colorM returns the color matrix recoloring images into p
+func (p Palette) colorM() colorm.ColorM {
+	var cm colorm.ColorM
+	m := p.matrix()
+	for i := range 3 {
+		for j := range 3 {
+			cm.SetElement(i, j, m[i][j])
+		}
+	}
+	return cm
+}
+
+// okabeItoBanks are the ^C colors of the color blind palettes, the
+// Okabe-Ito colors, told apart with either cone missing
+var okabeItoBanks = []color.RGBA{
+	{},
+	{0xd5, 0x5e, 0x00, 0xff}, // vermillion
+	{0x00, 0x9e, 0x73, 0xff}, // bluish green
+	{0x00, 0x72, 0xb2, 0xff}, // blue
+	{0xf0, 0xe4, 0x42, 0xff}, // yellow
+	{0x56, 0xb4, 0xe9, 0xff}, // sky blue
+	{0xcc, 0x79, 0xa7, 0xff}, // reddish purple
+	{0xff, 0xff, 0xff, 0xff},
+}
+
+// paletteBanks are the ^C colors of each palette
+var paletteBanks = [...][]color.RGBA{
+	PaletteNormal:       colorBanks,
+	PaletteDeuteranopia: okabeItoBanks,
+	PaletteProtanopia:   okabeItoBanks,
+}
+
+// recolored is an image redrawn in a palette from its original colors
+type recolored struct {
+	img  *ebiten.Image
+	orig *ebiten.Image // nil until first recolored
+}
+
+// set redraws the image in p, in place so its users keep drawing it
+func (r *recolored) set(p Palette) {
+	if r.img == nil {
+		return
+	}
+	if r.orig == nil {
+		if p == PaletteNormal {
+			return
+		}
+		b := r.img.Bounds()
+		r.orig = ebiten.NewImage(b.Dx(), b.Dy())
+		r.orig.DrawImage(r.img, nil)
+	}
+
+	r.img.Clear()
+	op := &colorm.DrawImageOptions{}
+	op.Blend = ebiten.BlendCopy
+	colorm.DrawImage(r.img, r.orig, p.colorM(), op)
+}
+
+// setPalette recolors the rasters, the mountains, the color banks, the
+// flashing words, the glow and the scanline tints with p
+func (g *Game) setPalette(p Palette) {
+	g.palette = p
+	g.paletteRasters.img = g.rasters
+	g.paletteRasters.set(p)
+	g.paletteMountains.img = g.mountains
+	g.paletteMountains.set(p)
+
+	colorBanks = paletteBanks[p]
+	if g.paletteFlash == nil {
+		g.paletteFlash = slices.Clone(g.rasterFlash)
+	}
+	// In place, the scrollers share the slice
+	for i, c := range g.paletteFlash {
+		g.rasterFlash[i] = p.Color(c)
+	}
+	if glow, ok := g.effects.Lookup("glow").(*TextGlow); ok {
+		glow.color = p.Color(g.cfg.GlowColor)
+	}
+	if g.scanlines != nil {
+		g.scanlines.Palette = p
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// PCMFormat is the sample format of a music stream, interleaved stereo
+// in little-endian order
+type PCMFormat int
+
+const (
+	// PCMInt16 is signed 16-bit samples, what Ebiten plays
+	PCMInt16 PCMFormat = iota
+	// PCMFloat32 is 32-bit floats in [-1,1], as web audio pipelines take
+	PCMFloat32
+	// PCMUint8 is unsigned 8-bit samples centered on 128, as in WAV files
+	PCMUint8
+)
+
+var pcmFormatNames = []string{"int16", "float32", "uint8"}
+
+func (f PCMFormat) String() string {
+	if f < PCMInt16 || f > PCMUint8 {
+		return "unknown"
+	}
+	return pcmFormatNames[f]
+}
+
+// Set implements flag.Value
+func (f *PCMFormat) Set(s string) error {
+	for i, name := range pcmFormatNames {
+		if strings.EqualFold(s, name) {
+			*f = PCMFormat(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown PCM format %q (want %s)", s, strings.Join(pcmFormatNames, ", "))
+}
+
+// SampleSize returns the bytes of one sample of one channel
+func (f PCMFormat) SampleSize() int {
+	switch f {
+	case PCMFloat32:
+		return 4
+	case PCMUint8:
+		return 1
+	}
+	return 2
+}
+
+// FrameSize returns the bytes of a stereo frame
+func (f PCMFormat) FrameSize() int {
+	return 2 * f.SampleSize()
+}
+
+// put writes sample v, on the 16-bit scale, at the start of out
+func (f PCMFormat) put(out []byte, v float64) {
+	switch f {
+	case PCMFloat32:
+		binary.LittleEndian.PutUint32(out, math.Float32bits(float32(v/32768)))
+	case PCMUint8:
+		out[0] = uint8(int(min(max(v, -32768), 32767))>>8 + 128)
+	default:
+		binary.LittleEndian.PutUint16(out, uint16(int16(v)))
+	}
+}
+
+// putFrame writes the stereo frame left, right at the start of out
+func (f PCMFormat) putFrame(out []byte, left, right float64) {
+	f.put(out, left)
+	f.put(out[f.SampleSize():], right)
+}
+
+// PCMOutput is a music source whose stream can be read in another
+// sample format, for audio backends other than Ebiten's such as oto or
+// web audio
+type PCMOutput interface {
+	MusicSource
+
+	// SetPCMFormat sets the format of the bytes read from now on
+	SetPCMFormat(f PCMFormat)
+}
+
+// pcmConverter reads a 16-bit stereo stream in another format, for the
+// music sources that only produce 16-bit samples
+type pcmConverter struct {
+	src    io.Reader
+	format PCMFormat
+	buf    []byte
+}
+
+// NewPCMReader returns src read in format f: src itself when it is a
+// PCMOutput or already in f, a converter otherwise
+func NewPCMReader(src io.Reader, f PCMFormat) io.Reader {
+	if out, ok := src.(PCMOutput); ok {
+		out.SetPCMFormat(f)
+		return src
+	}
+	if f == PCMInt16 {
+		return src
+	}
+	return &pcmConverter{src: src, format: f}
+}
+
+func (c *pcmConverter) Read(p []byte) (int, error) {
+	frames := len(p) / c.format.FrameSize()
+	if frames == 0 {
+		return 0, nil
+	}
+	if len(c.buf) < frames*4 {
+		c.buf = make([]byte, frames*4)
+	}
+	// Whole frames, so the formats stay in step
+	n, err := io.ReadFull(c.src, c.buf[:frames*4])
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	size := c.format.FrameSize()
+	for i := 0; i < n/4; i++ {
+		left := int16(binary.LittleEndian.Uint16(c.buf[i*4:]))
+		right := int16(binary.LittleEndian.Uint16(c.buf[i*4+2:]))
+		c.format.putFrame(p[i*size:], float64(left), float64(right))
+	}
+	return n / 4 * size, err
+}
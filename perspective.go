@@ -0,0 +1,81 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"tcb-multi-plane-3d-scroller/hooks"
+)
+
+// minTiltScale keeps the far edge of a steeply tilted letter from
+// folding over
+const minTiltScale = 0.1
+
+// quad holds where the top left, top right, bottom left and bottom right
+// corners of an image are drawn
+type quad [4][2]float64
+
+// letterTilt returns how the scale changes across letter n laid out at
+// cursor, from the projections of its ends along the line: the fraction
+// of scale its right edge grows by, or its bottom edge down a vertical
+// line. Letters on steep slopes of the wave turn away from the camera.
+func (s *Scroller) letterTilt(cursor float64, adv letterAdvance, n int, sf ScrollForm, ph *wavePhases, scale float64) [2]float64 {
+	if !s.Perspective || s.Mode == ScrollPath || scale <= 0 {
+		return [2]float64{}
+	}
+	const half = fontTileWidth / 2
+	x0, y0, s0 := s.place(cursor-half, adv, float64(n)-0.5, sf, ph)
+	x1, y1, s1 := s.place(cursor+half, adv, float64(n)+0.5, sf, ph)
+	grow := (s1 - s0) / scale / 2
+	// Right to left and round the back of the ring, the line runs the
+	// other way on screen
+	if s.Mode == ScrollVertical {
+		if y1 < y0 {
+			grow = -grow
+		}
+		return [2]float64{0, grow}
+	}
+	if x1 < x0 {
+		grow = -grow
+	}
+	return [2]float64{grow, 0}
+}
+
+// letterQuad places the w×h image of letter l, transformed around its
+// center by local at scale 1, centered on x, y and scaled corner by
+// corner by the tilt of l
+func letterQuad(local ebiten.GeoM, w, h float64, l *hooks.Letter, x, y float64) quad {
+	var q quad
+	for i, c := range [4][2]float64{{0, 0}, {w, 0}, {0, h}, {w, h}} {
+		cx, cy := local.Apply(c[0], c[1])
+		k := l.Scale * max(1+(l.Tilt[0]*cx+l.Tilt[1]*cy)/(fontTileWidth/2), minTiltScale)
+		q[i] = [2]float64{x + cx*k, y + cy*k}
+	}
+	return q
+}
+
+// drawQuad draws the whole of src stretched onto the corners of q,
+// scaled by c
+func drawQuad(dst, src *ebiten.Image, q quad, c ebiten.ColorScale) {
+	b := src.Bounds()
+	vertices := make([]ebiten.Vertex, 4)
+	for i, corner := range [4][2]int{{b.Min.X, b.Min.Y}, {b.Max.X, b.Min.Y}, {b.Min.X, b.Max.Y}, {b.Max.X, b.Max.Y}} {
+		vertices[i] = ebiten.Vertex{
+			DstX:   float32(q[i][0]),
+			DstY:   float32(q[i][1]),
+			SrcX:   float32(corner[0]),
+			SrcY:   float32(corner[1]),
+			ColorR: c.R(),
+			ColorG: c.G(),
+			ColorB: c.B(),
+			ColorA: c.A(),
+		}
+	}
+	op := &ebiten.DrawTrianglesOptions{
+		ColorScaleMode: ebiten.ColorScaleModePremultipliedAlpha,
+		Filter:         ebiten.FilterNearest,
+	}
+	dst.DrawTriangles(vertices, quadIndices, src, op)
+}
+
+// quadIndices split a quad into two triangles
+var quadIndices = []uint16{0, 1, 2, 1, 3, 2}
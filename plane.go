@@ -0,0 +1,62 @@
+package main
+
+import (
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// initPlanes creates the second scroller plane of -scroller2: one more
+// Scroller, with its own text, speed, layout and waveform, drawn over
+// the main one from its own canvas
+func (g *Game) initPlanes() {
+	if g.cfg.Scroller2 == "" {
+		return
+	}
+	text, err := LoadScrollText(g.cfg.Scroller2)
+	if err != nil {
+		log.Printf("Failed to load the second scroller text: %v", err)
+		return
+	}
+
+	s := g.newScroller()
+	s.canvas = ebiten.NewImage(canvasWidth, canvasHeight)
+	s.Mode = g.cfg.Scroller2Mode
+	s.Speed = g.cfg.Scroller2Speed
+	s.lockedForm = min(g.cfg.Scroller2Form, len(scrollForms)-1)
+	s.snapForm()
+	y := float64(g.cfg.Scroller2Y)
+	s.Path = func(float64) float64 { return y }
+	s.Overlay = nil
+	s.Text = text
+	s.Restart()
+	g.planes = append(g.planes, s)
+}
+
+// updatePlanes moves the extra scroller planes on
+func (g *Game) updatePlanes() {
+	for _, s := range g.planes {
+		s.Pulse = g.pulse
+		s.Update()
+	}
+}
+
+// drawPlanes draws the extra scroller planes onto dst
+func (g *Game) drawPlanes(dst *ebiten.Image) {
+	for _, s := range g.planes {
+		s.canvas.Clear()
+		s.Draw()
+		dst.DrawImage(s.canvas, nil)
+		g.drawList.add("composite", "plane", s.canvas.Bounds(), nil)
+	}
+}
+
+// allScrollers returns the main scroller, the other planes and the chat
+// scroller
+func (g *Game) allScrollers() []*Scroller {
+	scrollers := append([]*Scroller{g.scroller}, g.planes...)
+	if g.chat != nil {
+		scrollers = append(scrollers, g.chat)
+	}
+	return scrollers
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Tunes built into the demo, played in name order. YM files dropped
+// into assets/music join the playlist.
+//
+//go:embed assets/music/*.ym
+var musicFiles embed.FS
+
+// Track is one tune of the playlist
+type Track struct {
+	Name string
+	Path string // file played with -music, empty for a tune in memory
+	data []byte
+	ext  string // format of data, as a file extension
+}
+
+// embeddedTracks returns the built-in tunes
+func embeddedTracks() []Track {
+	entries, err := musicFiles.ReadDir("assets/music")
+	if err != nil {
+		log.Printf("Failed to list the built-in music: %v", err)
+		return nil
+	}
+	var tracks []Track
+	for _, e := range entries {
+		data, err := musicFiles.ReadFile(path.Join("assets/music", e.Name()))
+		if err != nil {
+			log.Printf("Failed to read %s: %v", e.Name(), err)
+			continue
+		}
+		tracks = append(tracks, Track{Name: strings.TrimSuffix(e.Name(), path.Ext(e.Name())), data: data, ext: ".ym"})
+	}
+	return tracks
+}
+
+// Load creates the music source playing the track
+func (t Track) Load(sampleRate int, loop bool) (MusicSource, error) {
+	if t.Path != "" {
+		return NewMusicSource(t.Path, sampleRate, loop)
+	}
+	return loadMusic(t.data, t.ext, sampleRate, loop)
+}
+
+// initPlaylist lists the file given with -music, or the built-in tunes
+func (g *Game) initPlaylist() {
+	if g.cfg.Music != "" {
+		name := filepath.Base(g.cfg.Music)
+		g.tracks = []Track{{Name: strings.TrimSuffix(name, filepath.Ext(name)), Path: g.cfg.Music}}
+		return
+	}
+	g.tracks = embeddedTracks()
+}
+
+// playTrack tears down the music playing and starts track i, keeping
+// the volume
+func (g *Game) playTrack(i int) {
+	volume := -1.0
+	if g.audioPlayer != nil {
+		g.audioPlayer.Close()
+		g.audioPlayer = nil
+	}
+	if g.music != nil {
+		volume = g.music.Volume()
+		g.music.Close()
+		g.music = nil
+	}
+
+	g.track = i
+	t := g.tracks[i]
+	music, err := t.Load(g.audioContext.SampleRate(), true)
+	if err != nil {
+		log.Printf("Failed to create music player for %s: %v", t.Name, err)
+		return
+	}
+	if y, ok := music.(*YMPlayer); ok {
+		y.SetChunkSize(g.cfg.AudioChunk)
+		y.SetStereo(g.cfg.YMStereo)
+		y.SetLowPass(g.cfg.LowPass)
+	}
+	if volume >= 0 {
+		music.SetVolume(volume)
+	}
+	if b, ok := music.(interface{ SetBPM(float64) }); ok {
+		b.SetBPM(g.cfg.BPM)
+	}
+
+	var stream io.Reader = music
+	if g.sfx != nil {
+		stream = g.sfx.Stream(stream)
+	}
+	if g.metrics != nil {
+		stream = g.metrics.Audio(stream, g.audioContext.SampleRate())
+	}
+	player, err := g.audioContext.NewPlayer(stream)
+	if err != nil {
+		log.Printf("Failed to create audio player: %v", err)
+		music.Close()
+		return
+	}
+	if g.cfg.AudioBuffer > 0 {
+		player.SetBufferSize(g.cfg.AudioBuffer)
+	}
+	player.SetVolume(0.7)
+	if !g.paused {
+		player.Play()
+	}
+
+	g.music = music
+	g.audioPlayer = player
+	if n := music.Subsongs(); n > 1 {
+		log.Printf("%s holds %d subsongs", t.Name, n)
+	}
+}
+
+// stepTrack moves delta tracks along the playlist and shows the name of
+// the new one
+func (g *Game) stepTrack(delta int) {
+	if g.audioContext == nil || len(g.tracks) == 0 {
+		return
+	}
+	i := cycle(g.track, delta, len(g.tracks))
+	g.playTrack(i)
+	g.notice.Show(g.trackTitle())
+}
+
+// trackTitle returns the song name stored in the music, or the file
+// name
+func (g *Game) trackTitle() string {
+	if len(g.tracks) == 0 {
+		return ""
+	}
+	title := g.tracks[g.track].Name
+	if t, ok := g.music.(interface{ Title() string }); ok && t.Title() != "" {
+		title = t.Title()
+	}
+	if len(g.tracks) > 1 {
+		title = fmt.Sprintf("%d/%d %s", g.track+1, len(g.tracks), title)
+	}
+	return title
+}
+
+// playDroppedFiles adds the music files dropped onto the window to the
+// playlist and plays the first one
+func (g *Game) playDroppedFiles() {
+	files := ebiten.DroppedFiles()
+	if files == nil {
+		return
+	}
+	if g.audioContext == nil {
+		g.notice.Show("No music in visualizer mode")
+		return
+	}
+
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		log.Printf("Failed to list dropped files: %v", err)
+		return
+	}
+	first := -1
+	for _, e := range entries {
+		if e.IsDir() || !isMusicFile(e.Name()) {
+			continue
+		}
+		data, err := fs.ReadFile(files, e.Name())
+		if err != nil {
+			log.Printf("Failed to read %s: %v", e.Name(), err)
+			continue
+		}
+		ext := path.Ext(e.Name())
+		g.tracks = append(g.tracks, Track{Name: strings.TrimSuffix(e.Name(), ext), data: data, ext: ext})
+		if first < 0 {
+			first = len(g.tracks) - 1
+		}
+	}
+	if first < 0 {
+		g.notice.Show("Drop YM, MOD, WAV, OGG or MP3 files")
+		return
+	}
+	g.playTrack(first)
+	g.notice.Show(g.trackTitle())
+}
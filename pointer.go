@@ -0,0 +1,119 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// The GEM mouse sprites of the ST: '#' black, '.' white, ' ' clear
+var (
+	pointerArrow = []string{
+		"..        ",
+		".#.       ",
+		".##.      ",
+		".###.     ",
+		".####.    ",
+		".#####.   ",
+		".######.  ",
+		".#######. ",
+		".########.",
+		".#####....",
+		".##.##.   ",
+		".#. .##.  ",
+		"..  .##.  ",
+		"     .##. ",
+		"     .##. ",
+		"      ..  ",
+	}
+	pointerBee = []string{
+		"  ...   ...     ",
+		" .###. .###.    ",
+		".#...#.#...#.   ",
+		".#....#....#.   ",
+		" .#...#...#.    ",
+		"  .#######.     ",
+		"   .#...#.  .   ",
+		"  .#######..#.  ",
+		"  .#.....#.#.   ",
+		"  .#######.#.   ",
+		"  .#.....#.#.   ",
+		"   .#####.#.    ",
+		"    .#.#..#.    ",
+		"    .#..#.#.    ",
+		"     .#. .#.    ",
+		"      .   .     ",
+	}
+)
+
+// Pointer behavior, in updates
+const (
+	pointerIdleFrames = 3 * 60 // hidden after this long without moving
+	pointerScale      = 2      // ST pixels on screen
+)
+
+// Pointer draws the ST mouse pointer in place of the system one: the
+// arrow, or the busy bee while the demo is loading. It hides when the
+// mouse rests.
+type Pointer struct {
+	arrow, bee *ebiten.Image
+	x, y       int
+	idle       int
+	busy       bool
+}
+
+// NewPointer builds the sprites, hidden until the mouse moves
+func NewPointer() *Pointer {
+	return &Pointer{
+		arrow: pointerSprite(pointerArrow),
+		bee:   pointerSprite(pointerBee),
+		idle:  pointerIdleFrames,
+	}
+}
+
+// pointerSprite draws the rows of a sprite into an image
+func pointerSprite(rows []string) *ebiten.Image {
+	img := ebiten.NewImage(len(rows[0]), len(rows))
+	for y, row := range rows {
+		for x, c := range row {
+			switch c {
+			case '#':
+				img.Set(x, y, color.Black)
+			case '.':
+				img.Set(x, y, color.White)
+			}
+		}
+	}
+	return img
+}
+
+// Update follows the mouse, busy showing the bee
+func (p *Pointer) Update(busy bool) {
+	p.busy = busy
+	x, y := ebiten.CursorPosition()
+	if x != p.x || y != p.y || busy {
+		p.x, p.y = x, y
+		p.idle = 0
+		return
+	}
+	p.idle = min(p.idle+1, pointerIdleFrames)
+}
+
+// Draw renders the pointer unless the mouse rested long enough. The bee
+// is centered on the mouse, the arrow points at it.
+func (p *Pointer) Draw(screen *ebiten.Image) {
+	if p.idle >= pointerIdleFrames {
+		return
+	}
+	sprite, x, y := p.arrow, float64(p.x), float64(p.y)
+	if p.busy {
+		sprite = p.bee
+		x -= float64(sprite.Bounds().Dx() * pointerScale / 2)
+		y -= float64(sprite.Bounds().Dy() * pointerScale / 2)
+	}
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(pointerScale, pointerScale)
+	op.GeoM.Translate(x, y)
+	op.Filter = ebiten.FilterNearest
+	screen.DrawImage(sprite, op)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+// PowerSave selects when the power saving profile is used
+type PowerSave int
+
+const (
+	PowerSaveAuto PowerSave = iota // on battery power
+	PowerSaveOn
+	PowerSaveOff
+)
+
+var powerSaveNames = []string{"auto", "on", "off"}
+
+func (p PowerSave) String() string {
+	if p < PowerSaveAuto || p > PowerSaveOff {
+		return "unknown"
+	}
+	return powerSaveNames[p]
+}
+
+// Set implements flag.Value
+func (p *PowerSave) Set(s string) error {
+	for i, name := range powerSaveNames {
+		if s == name {
+			*p = PowerSave(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown power save mode %q, expected auto, on or off", s)
+}
+
+// powerSaveProfile is the profile used to save the battery
+const powerSaveProfile = "powersave"
+
+// choosePowerProfile selects the power saving profile when asked to, or
+// when running on battery without a profile chosen on the command line
+func (c *Config) choosePowerProfile() {
+	switch {
+	case c.PowerSave == PowerSaveOff:
+	case c.PowerSave == PowerSaveOn:
+		c.Profile = powerSaveProfile
+	case c.Profile == "" && onBattery():
+		log.Printf("Running on battery, using the %s profile (-power-save off to disable)", powerSaveProfile)
+		c.Profile = powerSaveProfile
+	}
+}
@@ -0,0 +1,17 @@
+//go:build darwin
+
+package main
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// onBattery reports whether pmset says the power comes from the battery
+func onBattery() bool {
+	out, err := exec.Command("pmset", "-g", "batt").Output()
+	if err != nil {
+		return false
+	}
+	return strings.Contains(string(out), "'Battery Power'")
+}
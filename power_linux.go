@@ -0,0 +1,32 @@
+//go:build linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// onBattery reports whether the machine has a battery and no mains
+// supply online
+func onBattery() bool {
+	supplies, _ := filepath.Glob("/sys/class/power_supply/*")
+	battery := false
+	for _, dir := range supplies {
+		kind, err := os.ReadFile(filepath.Join(dir, "type"))
+		if err != nil {
+			continue
+		}
+		switch strings.TrimSpace(string(kind)) {
+		case "Mains", "USB":
+			online, _ := os.ReadFile(filepath.Join(dir, "online"))
+			if strings.TrimSpace(string(online)) == "1" {
+				return false
+			}
+		case "Battery":
+			battery = true
+		}
+	}
+	return battery
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !windows && !darwin
+
+package main
+
+// onBattery cannot tell on this platform and assumes mains power
+func onBattery() bool {
+	return false
+}
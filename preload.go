@@ -0,0 +1,167 @@
+package main
+
+import (
+	"log"
+	"runtime"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// SceneAssets is implemented by scenes holding assets of their own. The
+// timeline loads them in the background while the scene before plays
+// and releases them when the scene is left.
+type SceneAssets interface {
+	// Preload loads the assets, away from the game loop
+	Preload() error
+	// Release frees the assets once the scene is left
+	Release()
+	// VRAM returns the bytes of the images the scene holds
+	VRAM() int64
+}
+
+// sceneLoad is the background load of the assets of a scene
+type sceneLoad struct {
+	done chan struct{}
+	err  error
+}
+
+// preload starts loading the assets of scene i unless they are loading
+// or loaded already
+func (t *Timeline) preload(i int) {
+	a, ok := t.scenes[i].(SceneAssets)
+	if !ok || t.loads[i] != nil {
+		return
+	}
+	l := &sceneLoad{done: make(chan struct{})}
+	t.loads[i] = l
+	go func() {
+		defer close(l.done)
+		l.err = a.Preload()
+	}()
+}
+
+// wait blocks until the assets of scene i are loaded, starting the load
+// when the scene was not preloaded
+func (t *Timeline) wait(i int) {
+	t.preload(i)
+	l := t.loads[i]
+	if l == nil {
+		return
+	}
+	<-l.done
+	if l.err != nil {
+		log.Printf("Failed to load the assets of scene %s: %v", t.scenes[i].Name(), l.err)
+	}
+}
+
+// Loading reports whether the assets of a scene are still loading
+func (t *Timeline) Loading() bool {
+	for _, l := range t.loads {
+		if l == nil {
+			continue
+		}
+		select {
+		case <-l.done:
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// release frees the assets of scene i, loaded again before it returns
+func (t *Timeline) release(i int) {
+	a, ok := t.scenes[i].(SceneAssets)
+	if !ok || t.loads[i] == nil {
+		return
+	}
+	<-t.loads[i].done
+	a.Release()
+	t.loads[i] = nil
+}
+
+// memorySampleEvery is how many updates pass between two readings of the
+// heap, which stops the world briefly
+const memorySampleEvery = 60
+
+// SceneMemory is the most memory used while a scene played
+type SceneMemory struct {
+	Scene string `json:"scene"`
+	Heap  uint64 `json:"heap_bytes"`
+	VRAM  int64  `json:"vram_bytes"` // estimated from the image sizes
+}
+
+// MemoryMonitor follows the memory used by every scene and reports when
+// it goes over the budget
+type MemoryMonitor struct {
+	budget int64 // bytes, 0 for none
+	shared int64 // VRAM of the images every scene uses
+	scenes []SceneMemory
+	over   map[string]bool // scenes reported over the budget
+	frames int
+}
+
+// NewMemoryMonitor creates a monitor with a budget of budgetMB megabytes
+// of heap and VRAM together, 0 for none
+func NewMemoryMonitor(budgetMB int, shared int64) *MemoryMonitor {
+	return &MemoryMonitor{budget: int64(budgetMB) << 20, shared: shared, over: make(map[string]bool)}
+}
+
+// Update samples the memory used while scene plays
+func (m *MemoryMonitor) Update(scene Scene) {
+	m.frames++
+	if m.frames%memorySampleEvery != 1 {
+		return
+	}
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	vram := m.shared
+	if a, ok := scene.(SceneAssets); ok {
+		vram += a.VRAM()
+	}
+
+	name := scene.Name()
+	i := 0
+	for i < len(m.scenes) && m.scenes[i].Scene != name {
+		i++
+	}
+	if i == len(m.scenes) {
+		m.scenes = append(m.scenes, SceneMemory{Scene: name})
+	}
+	s := &m.scenes[i]
+	s.Heap = max(s.Heap, ms.HeapAlloc)
+	s.VRAM = max(s.VRAM, vram)
+
+	if m.budget > 0 && int64(ms.HeapAlloc)+vram > m.budget && !m.over[name] {
+		m.over[name] = true
+		log.Printf("Scene %s uses %d MB of heap and %d MB of VRAM, over the budget of %d MB",
+			name, ms.HeapAlloc>>20, vram>>20, m.budget>>20)
+	}
+}
+
+// Scenes returns the memory used by the scenes played, in the order
+// they were first played
+func (m *MemoryMonitor) Scenes() []SceneMemory {
+	return append([]SceneMemory(nil), m.scenes...)
+}
+
+// imageBytes estimates the VRAM of images, 4 bytes a pixel. Sub-images
+// share the memory of their parent and must not be counted.
+func imageBytes(images ...*ebiten.Image) int64 {
+	var n int64
+	for _, img := range images {
+		if img != nil {
+			b := img.Bounds()
+			n += int64(b.Dx()) * int64(b.Dy()) * 4
+		}
+	}
+	return n
+}
+
+// sharedVRAM estimates the VRAM of the images and canvases every scene
+// draws with
+func (g *Game) sharedVRAM() int64 {
+	return imageBytes(g.rasters, g.mountains, g.logo, g.font,
+		g.mycanvas, g.papercanvas, g.papercanvas2, g.scrollcanvas, g.lettercanvas,
+		g.thecanvas, g.thecanvas2, g.chatcanvas)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Profile is a named set of flag values for a kind of machine. Flags
+// given on the command line win over the profile.
+type Profile struct {
+	Description string
+	Flags       map[string]string
+}
+
+// Profiles are the performance profiles selectable with -profile. Add an
+// entry to support another machine.
+var Profiles = map[string]Profile{
+	"pi": {
+		Description: "Raspberry Pi and other low-end GPUs",
+		Flags: map[string]string{
+			"post":         "false",
+			"blur-quality": "low",
+			"haze":         "false",
+			"ripple":       "false",
+			"glow":         "false",
+			"bloom":        "false",
+			"grain":        "0",
+			"quantize":     "false",
+			"draw-fps":     "30",
+		},
+	},
+	powerSaveProfile: {
+		Description: "laptops on battery",
+		Flags: map[string]string{
+			"bloom":        "false",
+			"glow":         "false",
+			"blur-quality": "low",
+			"draw-fps":     "30",
+		},
+	},
+	halfMegProfile: {
+		Description: "the 512K mode of -halfmeg",
+		Flags: map[string]string{
+			"halfmeg":      "true",
+			"max-letters":  "16",
+			"letter-scale": "0.75",
+			"perspective":  "false",
+			"ym-stereo":    "mono",
+			"quantize":     "true",
+			"bloom":        "false",
+			"glow":         "false",
+			"haze":         "false",
+			"ripple":       "false",
+		},
+	},
+}
+
+// profileNames lists the profiles for the flag help
+func profileNames() string {
+	names := make([]string, 0, len(Profiles))
+	for name := range Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// ApplyProfile sets the flags of the selected profile that were not given
+// on the command line. Call it after parsing fs.
+func (c *Config) ApplyProfile(fs *flag.FlagSet) error {
+	c.choosePowerProfile()
+	c.chooseHalfMegProfile()
+	if c.Profile == "" {
+		return nil
+	}
+	p, ok := Profiles[c.Profile]
+	if !ok {
+		return fmt.Errorf("unknown profile %q, expected one of %s", c.Profile, profileNames())
+	}
+
+	given := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	for name, value := range p.Flags {
+		if given[name] {
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("failed to apply profile %s: %w", c.Profile, err)
+		}
+	}
+	return nil
+}
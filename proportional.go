@@ -0,0 +1,65 @@
+package main
+
+// Spacing of the proportional scroller, in pixels of the 32x33 tiles
+const (
+	letterGap   = 4  // between two glyphs
+	letterSpace = 16 // width of a space
+)
+
+// lineWidth is the length of the scroller line, a tile per letter slot
+const lineWidth = scrollLetters * fontTileWidth
+
+// proportionalSlots bounds the letters of a proportional line, enough
+// for glyphs down to 8 pixels wide
+const proportionalSlots = 4 * scrollLetters
+
+// letterAdvance is the room a letter takes on the line and where its
+// tile starts in it
+type letterAdvance struct {
+	width  float64
+	offset float64
+}
+
+// fixedAdvance is the advance of every letter of a fixed-width line
+var fixedAdvance = letterAdvance{width: fontTileWidth}
+
+// advance returns the room letter t takes on the line: a whole tile,
+// or with Proportional the columns its glyph covers and a gap
+func (s *Scroller) advance(t ScrollToken) letterAdvance {
+	if !s.Proportional {
+		return fixedAdvance
+	}
+	if t.Letter == stampMarker {
+		return s.stampAdvance(t.Stamp)
+	}
+
+	ch := rune(t.Letter)
+	if t.Letter == timeMarker && s.TimeText != nil {
+		ch = rune(s.timeChar(t.Pos)[0])
+	}
+	f := s.faces[min(max(t.Font, 0), len(s.faces)-1)]
+	tiles, spans := s.fonts[f.font], s.spans[f.font]
+	ch = s.fontRune(f.font, ch)
+
+	if _, drawn := tiles[ch]; ch == ' ' || !drawn {
+		return letterAdvance{width: letterSpace}
+	}
+	span, ok := spans[ch]
+	if !ok {
+		// Glyphs without a span take the whole tile
+		return fixedAdvance
+	}
+	if span[1] <= span[0] {
+		return letterAdvance{width: letterSpace}
+	}
+	// Styles scale the glyph across its tile center
+	w := float64(span[1]-span[0]) * f.style.width
+	left := fontTileWidth/2 + (float64(span[0])-fontTileWidth/2)*f.style.width
+	return letterAdvance{width: w + letterGap, offset: letterGap/2 - left}
+}
+
+// onScreen returns how many letters the line showed on the last update,
+// at least a slot's worth
+func (s *Scroller) onScreen() int {
+	return max(s.shown, scrollLetters)
+}
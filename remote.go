@@ -0,0 +1,297 @@
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// RemoteServer is the HTTP remote control API of the demo
+type RemoteServer struct {
+	Addr  string
+	Token string // bearer token required by the authenticated endpoints
+	mux   *http.ServeMux
+}
+
+// NewRemoteServer creates the API listening on addr
+func NewRemoteServer(addr, token string) *RemoteServer {
+	return &RemoteServer{
+		Addr:  addr,
+		Token: token,
+		mux:   http.NewServeMux(),
+	}
+}
+
+// Handle registers an endpoint open to everyone
+func (r *RemoteServer) Handle(pattern string, h http.Handler) {
+	r.mux.Handle(pattern, h)
+}
+
+// HandleAuth registers an endpoint requiring the bearer token. Without
+// a token configured the endpoint always refuses.
+func (r *RemoteServer) HandleAuth(pattern string, h http.Handler) {
+	r.mux.Handle(pattern, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if r.Token == "" || !ok || subtle.ConstantTimeCompare([]byte(token), []byte(r.Token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, req)
+	}))
+}
+
+// Timeouts of the remote API connections. There is no write timeout as
+// /events streams for as long as the client stays.
+const (
+	remoteHeaderTimeout = 5 * time.Second
+	remoteReadTimeout   = 30 * time.Second
+	remoteIdleTimeout   = 2 * time.Minute
+)
+
+// Start serves the API in the background
+func (r *RemoteServer) Start() {
+	srv := &http.Server{
+		Addr:              r.Addr,
+		Handler:           r.mux,
+		ReadHeaderTimeout: remoteHeaderTimeout,
+		ReadTimeout:       remoteReadTimeout,
+		IdleTimeout:       remoteIdleTimeout,
+	}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil {
+			log.Printf("Remote API stopped: %v", err)
+		}
+	}()
+}
+
+// maxRemoteText is the largest scroll text accepted by the remote API
+const maxRemoteText = 64 << 10
+
+// validateScrollText checks that every character of text can be drawn
+// or is a valid control code or field
+func validateScrollText(text string) error {
+	if strings.TrimSpace(text) == "" {
+		return errors.New("text is empty")
+	}
+	if len(text) > maxRemoteText {
+		return fmt.Errorf("text is longer than %d bytes", maxRemoteText)
+	}
+	return checkScrollChars(text)
+}
+
+// checkScrollChars checks that every character of text is in the font,
+// or its uppercase is, a control code or a field. Offsets are in bytes
+// of the UTF-8 text.
+func checkScrollChars(text string) error {
+	text = strings.ReplaceAll(text, timeField, "")
+	for i := 0; i < len(text); {
+		c, size := utf8.DecodeRuneInString(text[i:])
+		if c == '^' {
+			n := controlCodeLen(text, i)
+			if n == 0 {
+				return fmt.Errorf("invalid control code at offset %d", i)
+			}
+			i += n
+			continue
+		}
+		if c >= utf8.RuneSelf || (!strings.ContainsRune(fontChars, c) && !strings.ContainsRune(fontChars, rune(upper(byte(c))))) {
+			return fmt.Errorf("unsupported character %q at offset %d", c, i)
+		}
+		i += size
+	}
+	return nil
+}
+
+// textEdit is a scroll text change waiting for the next wrap
+type textEdit struct {
+	text   string
+	append bool
+}
+
+// remoteText queues the scroll text changes received by the remote API.
+// With a live feed the text goes straight to the feed instead. Without
+// lint the endpoint refuses every text.
+type remoteText struct {
+	mu     sync.Mutex
+	edits  []textEdit
+	feed   *TextFeed
+	filter *TextFilter
+	lint   *Textlint
+}
+
+func (t *remoteText) push(e textEdit) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.edits = append(t.edits, e)
+}
+
+func (t *remoteText) take() []textEdit {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	edits := t.edits
+	t.edits = nil
+	return edits
+}
+
+// ServeHTTP replaces the scroll text on PUT and appends to it on POST
+func (t *remoteText) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPut && req.Method != http.MethodPost {
+		w.Header().Set("Allow", "PUT, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxRemoteText+1))
+	if err != nil {
+		http.Error(w, "failed to read text", http.StatusBadRequest)
+		return
+	}
+	text := strings.ReplaceAll(strings.TrimRight(string(body), "\r\n"), "\n", " ")
+	text = transliterate(text)
+	// The filter may cut the text short, through a control code, so the
+	// text is checked as it will scroll
+	text, ok := t.filter.Apply(text)
+	if !ok {
+		http.Error(w, "text rejected by the filter", http.StatusUnprocessableEntity)
+		return
+	}
+	if err := validateScrollText(text); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if t.lint == nil {
+		http.Error(w, "text checking is unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	problems, err := t.lint.Check(req.Context(), text)
+	if err != nil {
+		log.Printf("Remote text: %v", err)
+		http.Error(w, "failed to check text", http.StatusInternalServerError)
+		return
+	}
+	if len(problems) > 0 {
+		http.Error(w, "textlint: "+strings.Join(problems, "\n"), http.StatusUnprocessableEntity)
+		return
+	}
+
+	if t.feed != nil {
+		t.feed.Push(fontCase(text))
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintln(w, "text queued on the live feed")
+		return
+	}
+	t.push(textEdit{text: text, append: req.Method == http.MethodPost})
+	w.WriteHeader(http.StatusAccepted)
+	fmt.Fprintln(w, "text queued for the next wrap")
+}
+
+// initRemote starts the remote API
+func (g *Game) initRemote() {
+	g.remote = NewRemoteServer(g.cfg.RemoteAddr, g.cfg.RemoteToken)
+	if g.cfg.RemoteToken == "" {
+		log.Printf("No remote token set, the text endpoint is disabled")
+	}
+	g.remoteText.feed = g.scroller.Feed
+	g.remoteText.filter = g.cfg.FilterFor("remote")
+	// Control codes and fields pass, they are validated after the filter
+	g.remoteText.filter.Chars += "^%"
+	lint, err := NewTextlint(g.cfg.Textlint)
+	if err != nil {
+		log.Printf("No textlint, the text endpoint is disabled: %v", err)
+	}
+	g.remoteText.lint = lint
+	g.remote.HandleAuth("/text", &g.remoteText)
+
+	g.remoteCommands = make(chan func(), 16)
+	g.remote.HandleAuth("/volume", g.remoteCommand(http.MethodPut, g.remoteVolume))
+	g.remote.HandleAuth("/scene/next", g.remoteCommand(http.MethodPost, func([]byte) (func(), error) {
+		return func() { g.timeline.Next() }, nil
+	}))
+
+	// Demo status for dashboards, open to everyone
+	g.events = NewEventStream()
+	g.remote.Handle("/events", g.events)
+
+	// Health of the demo for Prometheus
+	g.metrics = NewMetrics()
+	g.remote.Handle("/metrics", g.metrics)
+	g.remote.Start()
+}
+
+// remoteCommand returns a handler parsing the request body with parse and
+// queuing the resulting action for the game loop
+func (g *Game) remoteCommand(method string, parse func(body []byte) (func(), error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != method {
+			w.Header().Set("Allow", method)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(req.Body, 1024))
+		if err != nil {
+			http.Error(w, "failed to read request", http.StatusBadRequest)
+			return
+		}
+		action, err := parse(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+
+		select {
+		case g.remoteCommands <- action:
+			w.WriteHeader(http.StatusAccepted)
+			fmt.Fprintln(w, "command queued")
+		default:
+			http.Error(w, "too many pending commands", http.StatusServiceUnavailable)
+		}
+	})
+}
+
+// remoteVolume parses a volume between 0 and 1
+func (g *Game) remoteVolume(body []byte) (func(), error) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(string(body)), 64)
+	if err != nil || v < 0 || v > 1 {
+		return nil, fmt.Errorf("invalid volume %q, expected 0 to 1", strings.TrimSpace(string(body)))
+	}
+	return func() { g.setVolume(v) }, nil
+}
+
+// runRemoteCommands runs the actions queued by the remote API
+func (g *Game) runRemoteCommands() {
+	for {
+		select {
+		case action := <-g.remoteCommands:
+			action()
+		default:
+			return
+		}
+	}
+}
+
+// rebuildText applies the pending remote edits and the headlines to the
+// scroll text. It runs when the text wraps (or bounces back to its start
+// in ping-pong mode), so the change is seamless.
+func (g *Game) rebuildText() {
+	for _, e := range g.remoteText.take() {
+		text := g.clock.Expand(fontCase(e.text))
+		if e.append {
+			g.baseText += " " + text
+		} else {
+			g.baseText = " ^0" + strings.Repeat(" ", scrollLetters) + text + strings.Repeat(" ", scrollLetters)
+		}
+	}
+
+	g.scroller.Text = g.baseText
+	if g.headlines != nil {
+		g.scroller.Text = spliceHeadlines(g.baseText, g.headlines.Headlines())
+	}
+}
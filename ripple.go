@@ -0,0 +1,13 @@
+package main
+
+import "image"
+
+// NewWaterRipple creates gentle horizontal ripples over the region
+func NewWaterRipple(displacer *Displacer, region image.Rectangle, amount float64) *DisplacementEffect {
+	texture := NewSineDisplacementTexture(64, 24, 24, 0)
+
+	ripple := NewDisplacementEffect(displacer, region, texture, nil)
+	ripple.ScrollY = -0.5
+	ripple.AmountX = amount
+	return ripple
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"hash/fnv"
+	"math/rand"
+)
+
+// RNG hands out the random number streams of the demo, all derived from
+// the -seed value so a whole run can be reproduced for recordings and
+// regression checks. Every module draws from its own named stream, so
+// one drawing more numbers leaves the others unchanged.
+type RNG struct {
+	seed int64
+}
+
+// NewRNG creates the streams for seed
+func NewRNG(seed int64) *RNG {
+	return &RNG{seed: seed}
+}
+
+// Stream returns the random numbers of the module name
+func (r *RNG) Stream(name string) *rand.Rand {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return rand.New(rand.NewSource(r.seed ^ int64(h.Sum64())))
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2/audio/mp3"
+	"github.com/hajimehoshi/ebiten/v2/audio/vorbis"
+	"github.com/hajimehoshi/ebiten/v2/audio/wav"
+)
+
+// SampledPlayer plays WAV, Ogg Vorbis and MP3 files. Without register
+// data to follow, the music synced effects get the loudness of the bass,
+// middle and treble as three voices.
+type SampledPlayer struct {
+	mutex      sync.Mutex
+	stream     io.ReadSeeker // 16-bit stereo at sampleRate
+	length     int64         // bytes of stream, 0 when unknown
+	sampleRate int
+	loop       bool
+	volume     volumeRamp
+	fade       volumeRamp
+
+	analyzer *envelopeAnalyzer
+	levels   [analyzerBands]float64
+	scope    *scopeRing
+	played   int64 // frames
+	loops    int
+}
+
+// NewSampledPlayer decodes a file of the format named by its extension
+// (".wav", ".ogg" or ".mp3"), resampled to sampleRate
+func NewSampledPlayer(data []byte, ext string, sampleRate int, loop bool) (*SampledPlayer, error) {
+	var stream io.ReadSeeker
+	var err error
+	switch strings.ToLower(ext) {
+	case ".wav":
+		stream, err = wav.DecodeWithSampleRate(sampleRate, bytes.NewReader(data))
+	case ".ogg":
+		stream, err = vorbis.DecodeWithSampleRate(sampleRate, bytes.NewReader(data))
+	case ".mp3":
+		stream, err = mp3.DecodeWithSampleRate(sampleRate, bytes.NewReader(data))
+	default:
+		return nil, fmt.Errorf("unsupported audio format %q", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s audio: %w", ext, err)
+	}
+
+	var length int64
+	if l, ok := stream.(interface{ Length() int64 }); ok {
+		length = l.Length()
+	}
+
+	return &SampledPlayer{
+		stream:     stream,
+		length:     length,
+		sampleRate: sampleRate,
+		loop:       loop,
+		volume:     newVolumeRamp(0.7, sampleRate),
+		fade:       newVolumeRamp(1, sampleRate),
+		analyzer:   newEnvelopeAnalyzer(sampleRate),
+		scope:      newScopeRing(analyzerBands),
+	}, nil
+}
+
+// isSampledAudio reports whether the extension is a sampled format
+func isSampledAudio(ext string) bool {
+	switch strings.ToLower(ext) {
+	case ".wav", ".ogg", ".mp3":
+		return true
+	}
+	return false
+}
+
+// Read implements io.Reader for audio streaming
+func (s *SampledPlayer) Read(p []byte) (n int, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	p = p[:len(p)/4*4]
+	for n < len(p) {
+		m, err := s.stream.Read(p[n:])
+		n += m
+		if errors.Is(err, io.EOF) {
+			if !s.loop || m == 0 && n == 0 && s.played == 0 {
+				s.process(p[:n])
+				return n, io.EOF
+			}
+			if _, err := s.stream.Seek(0, io.SeekStart); err != nil {
+				return n, err
+			}
+			s.loops++
+			continue
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+	s.process(p[:n])
+	return n, nil
+}
+
+// process analyzes the frames of p and applies the volume
+func (s *SampledPlayer) process(p []byte) {
+	for i := 0; i+4 <= len(p); i += 4 {
+		l := int16(binary.LittleEndian.Uint16(p[i:]))
+		r := int16(binary.LittleEndian.Uint16(p[i+2:]))
+		s.analyzer.process((float64(l) + float64(r)) / 65536)
+		s.scope.push(s.analyzer.band[:])
+
+		v := s.volume.next() * s.fade.next()
+		binary.LittleEndian.PutUint16(p[i:], uint16(int16(float64(l)*v)))
+		binary.LittleEndian.PutUint16(p[i+2:], uint16(int16(float64(r)*v)))
+	}
+	s.played += int64(len(p) / 4)
+	s.analyzer.levels(s.levels[:])
+}
+
+// Seek implements io.Seeker. Offsets count the loops played, as the
+// position of the audio player does, so the loops and the time played
+// follow the new position.
+func (s *SampledPlayer) Seek(offset int64, whence int) (int64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var target int64
+	switch whence {
+	case io.SeekStart:
+		target = offset
+	case io.SeekCurrent:
+		target = s.played*4 + offset
+	case io.SeekEnd:
+		target = int64(s.loops)*s.length + s.length + offset
+	default:
+		return 0, fmt.Errorf("invalid whence %d", whence)
+	}
+	if target < 0 {
+		return 0, fmt.Errorf("negative position %d", target)
+	}
+	target -= target % 4
+
+	inFile, loops := target, 0
+	if s.length > 0 {
+		inFile, loops = target%s.length, int(target/s.length)
+	}
+	if _, err := s.stream.Seek(inFile, io.SeekStart); err != nil {
+		return 0, err
+	}
+	s.played = target / 4
+	s.loops = loops
+	return target, nil
+}
+
+// Length returns the duration of the file
+func (s *SampledPlayer) Length() time.Duration {
+	return time.Duration(s.length/4) * time.Second / time.Duration(s.sampleRate)
+}
+
+// ChannelLevels returns the loudness of the bass, middle and treble
+func (s *SampledPlayer) ChannelLevels() []float64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]float64(nil), s.levels[:]...)
+}
+
+// Scope returns the last samples of the three bands
+func (s *SampledPlayer) Scope() [][]float32 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.scope.copy()
+}
+
+// Sync returns the band levels, with frames counted at the YM rate
+func (s *SampledPlayer) Sync() MusicSync {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return MusicSync{
+		Frame:    s.played * ymFrameRate / int64(s.sampleRate),
+		Levels:   append([]float64(nil), s.levels[:]...),
+		Envelope: make([]bool, analyzerBands),
+		Beat:     s.analyzer.beat.beats,
+		BPM:      s.analyzer.beat.BPM(),
+	}
+}
+
+// SetBPM sets the tempo of the beat grid, 0 to detect it
+func (s *SampledPlayer) SetBPM(bpm float64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.analyzer.beat.fixed = bpm
+}
+
+// Elapsed returns how long the file has played, loops included
+func (s *SampledPlayer) Elapsed() time.Duration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return time.Duration(s.played) * time.Second / time.Duration(s.sampleRate)
+}
+
+// Volume returns the volume set last
+func (s *SampledPlayer) Volume() float64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.volume.target
+}
+
+// SetVolume ramps the volume to v
+func (s *SampledPlayer) SetVolume(v float64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.volume.target = max(0, min(1, v))
+}
+
+// FadeOut fades the file to silence over d
+func (s *SampledPlayer) FadeOut(d time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.fade.fadeOut(d, s.sampleRate)
+}
+
+// Loops returns how many times the file has been played through
+func (s *SampledPlayer) Loops() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.loops
+}
+
+// Subsongs returns 1, audio files hold a single track
+func (s *SampledPlayer) Subsongs() int {
+	return 1
+}
+
+// SetSubsong restarts the track, the only subsong of an audio file
+func (s *SampledPlayer) SetSubsong(n int) error {
+	if n != 0 {
+		return fmt.Errorf("no subsong %d, audio files hold a single track", n+1)
+	}
+	_, err := s.Seek(0, io.SeekStart)
+	return err
+}
+
+// Close releases resources
+func (s *SampledPlayer) Close() error {
+	return nil
+}
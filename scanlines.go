@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// ScanlineTable emulates the raster interrupts of the ST: at chosen
+// scanlines the palette or the horizontal shift of the picture changes
+// and holds until the next change, splitting the screen in bands
+type ScanlineTable struct {
+	Changes []ScanlineChange
+	Palette Palette // recolors the tints
+	scratch *ebiten.Image
+}
+
+// ScanlineChange is the state of the screen from Line down
+type ScanlineChange struct {
+	Line  int        // ST scanline, 0 to 199
+	Tint  color.RGBA // multiplies the colors, white for none
+	Shift int        // ST pixels the band moves right
+}
+
+// LoadScanlineTable reads a scanline table file
+func LoadScanlineTable(path string) (*ScanlineTable, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open scanline table: %w", err)
+	}
+	defer f.Close()
+	t, err := ParseScanlineTable(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return t, nil
+}
+
+// ParseScanlineTable reads one change a line, top to bottom:
+//
+//	line [tint=#rrggbb] [shift=n]
+//
+// Settings left out keep the value of the change before, as the
+// hardware registers would. Blank lines and lines starting with # are
+// skipped.
+func ParseScanlineTable(r io.Reader) (*ScanlineTable, error) {
+	t := &ScanlineTable{}
+	state := ScanlineChange{Line: -1, Tint: color.RGBA{0xff, 0xff, 0xff, 0xff}}
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: want line [tint=#rrggbb] [shift=n]", n)
+		}
+
+		at, err := strconv.Atoi(fields[0])
+		if err != nil || at <= state.Line || at >= canvasHeight {
+			return nil, fmt.Errorf("line %d: scanline %q is not between the one before and %d", n, fields[0], canvasHeight-1)
+		}
+		state.Line = at
+		for _, f := range fields[1:] {
+			key, value, _ := strings.Cut(f, "=")
+			switch key {
+			case "tint":
+				if err := (*hexColor)(&state.Tint).Set(value); err != nil {
+					return nil, fmt.Errorf("line %d: %w", n, err)
+				}
+			case "shift":
+				if state.Shift, err = strconv.Atoi(value); err != nil {
+					return nil, fmt.Errorf("line %d: invalid shift %q", n, value)
+				}
+			default:
+				return nil, fmt.Errorf("line %d: unknown setting %q", n, key)
+			}
+		}
+		t.Changes = append(t.Changes, state)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(t.Changes) == 0 {
+		return nil, fmt.Errorf("no changes")
+	}
+	return t, nil
+}
+
+// identity reports whether c leaves its band as drawn
+func (c ScanlineChange) identity() bool {
+	return c.Tint == color.RGBA{0xff, 0xff, 0xff, 0xff} && c.Shift == 0
+}
+
+// Apply redraws the bands of canvas, which shows the ST screen doubled
+// with its top left corner at origin
+func (t *ScanlineTable) Apply(canvas *ebiten.Image, origin image.Point) {
+	b := canvas.Bounds()
+	if t.scratch == nil || t.scratch.Bounds() != b {
+		t.scratch = ebiten.NewImage(b.Dx(), b.Dy())
+	}
+	t.scratch.Clear()
+	t.scratch.DrawImage(canvas, nil)
+
+	for i, c := range t.Changes {
+		if c.identity() {
+			continue
+		}
+		band := image.Rect(b.Min.X, origin.Y+c.Line*2, b.Max.X, b.Max.Y)
+		if i+1 < len(t.Changes) {
+			band.Max.Y = origin.Y + t.Changes[i+1].Line*2
+		}
+		dst := canvas.SubImage(band).(*ebiten.Image)
+		dst.Fill(color.Black)
+
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Translate(float64(band.Min.X+c.Shift*2), float64(band.Min.Y))
+		op.ColorScale.ScaleWithColor(t.Palette.Color(c.Tint))
+		dst.DrawImage(t.scratch.SubImage(band).(*ebiten.Image), op)
+	}
+}
+
+// initScanlines loads the scanline table set with -scanlines
+func (g *Game) initScanlines() {
+	if g.cfg.Scanlines == "" {
+		return
+	}
+	t, err := LoadScanlineTable(g.cfg.Scanlines)
+	if err != nil {
+		log.Printf("Failed to load scanline table: %v", err)
+		return
+	}
+	g.scanlines = t
+}
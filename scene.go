@@ -0,0 +1,145 @@
+package main
+
+import (
+	"image"
+	"log"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Scene is one part of the show
+type Scene interface {
+	// Name identifies the scene in config and logs
+	Name() string
+	// Enter is called every time the scene becomes current
+	Enter()
+	Update() error
+	// Draw renders the scene into the full-size canvas
+	Draw(canvas *ebiten.Image)
+}
+
+// Timeline plays the scenes in order, wrapping after the last one. The
+// assets of the next scene load while the current one plays.
+type Timeline struct {
+	scenes  []Scene
+	current int
+	loads   []*sceneLoad // by scene, nil when not loaded
+
+	// OnChange is called after the timeline moved to another scene
+	OnChange func(scene Scene)
+}
+
+// NewTimeline creates a timeline starting with the first scene
+func NewTimeline(scenes ...Scene) *Timeline {
+	t := &Timeline{scenes: scenes, loads: make([]*sceneLoad, len(scenes))}
+	if len(scenes) > 0 {
+		t.wait(0)
+		scenes[0].Enter()
+		t.preload(1 % len(scenes))
+	}
+	return t
+}
+
+// Current returns the scene being played
+func (t *Timeline) Current() Scene {
+	return t.scenes[t.current]
+}
+
+// Next moves to the following scene, restarting from the first one
+// after the last
+func (t *Timeline) Next() {
+	t.enter((t.current + 1) % len(t.scenes))
+}
+
+// Goto moves to the named scene and reports whether it exists
+func (t *Timeline) Goto(name string) bool {
+	for i, s := range t.scenes {
+		if s.Name() == name {
+			t.enter(i)
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Timeline) enter(i int) {
+	t.wait(i)
+	t.current = i
+	t.scenes[i].Enter()
+
+	// Only the scene playing and the next one keep their assets
+	next := (i + 1) % len(t.scenes)
+	for j := range t.scenes {
+		if j != i && j != next {
+			t.release(j)
+		}
+	}
+	t.preload(next)
+	if t.OnChange != nil {
+		t.OnChange(t.scenes[i])
+	}
+}
+
+// Update advances the current scene
+func (t *Timeline) Update() error {
+	return t.Current().Update()
+}
+
+// Draw renders the current scene
+func (t *Timeline) Draw(canvas *ebiten.Image) {
+	t.Current().Draw(canvas)
+}
+
+// demoScene is the original multi-plane scroller screen
+type demoScene struct {
+	g        *Game
+	scroller *Scroller
+
+	// Stamp images, decoded by Preload and uploaded on Enter
+	images map[string]image.Image
+	stamps map[string]*ebiten.Image
+}
+
+func (s *demoScene) Name() string { return "demo" }
+
+func (s *demoScene) Enter() {
+	s.useStamps()
+	s.scroller.Restart()
+}
+
+func (s *demoScene) Update() error {
+	s.g.updateDemo(s.scroller)
+	return nil
+}
+
+func (s *demoScene) Draw(canvas *ebiten.Image) {
+	s.g.drawDemo(s.scroller)
+}
+
+// initTimeline builds the scenes of the show
+func (g *Game) initTimeline() {
+	var scenes []Scene
+	if g.cfg.Countdown != "" {
+		target, err := parseDeadline(g.cfg.Countdown, time.Now())
+		if err != nil {
+			log.Printf("Failed to set up the countdown: %v", err)
+		} else {
+			scenes = append(scenes, g.newCountdownScene(target, g.cfg.CountdownText, g.cfg.CountdownNext))
+		}
+	}
+	scenes = append(scenes, &demoScene{g: g, scroller: g.scroller})
+
+	g.timeline = NewTimeline(scenes...)
+	g.timeline.OnChange = func(Scene) {
+		g.startTransition()
+	}
+}
+
+// startTransition plays the wobbly screen transition
+func (g *Game) startTransition() {
+	if g.wobble != nil {
+		g.wobble.Start()
+		g.effects.Trigger("wobble")
+	}
+}
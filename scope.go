@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// scopeSamples is the length of the waveform kept for every voice
+const scopeSamples = 1024
+
+// scopeRing keeps the last samples of every voice of a music player
+type scopeRing struct {
+	samples [][scopeSamples]float32
+	pos     int
+}
+
+func newScopeRing(voices int) *scopeRing {
+	return &scopeRing{samples: make([][scopeSamples]float32, voices)}
+}
+
+// push adds one sample per voice
+func (r *scopeRing) push(values []float64) {
+	for c, v := range values {
+		r.samples[c][r.pos] = float32(v)
+	}
+	r.pos = (r.pos + 1) % scopeSamples
+}
+
+// copy returns the samples of every voice, oldest first
+func (r *scopeRing) copy() [][]float32 {
+	out := make([][]float32, len(r.samples))
+	for c := range r.samples {
+		out[c] = make([]float32, 0, scopeSamples)
+		out[c] = append(out[c], r.samples[c][r.pos:]...)
+		out[c] = append(out[c], r.samples[c][:r.pos]...)
+	}
+	return out
+}
+
+// Atari ST PSG clock
+const psgClock = 2000000
+
+// ymDAC is the output of the YM DAC at the 16 volume levels, the table
+// of StSound
+var ymDAC = [16]float64{62, 161, 265, 377, 580, 774, 1155, 1575, 2260, 3088, 4570, 6233, 9330, 13187, 21220, 32767}
+
+// ymEnvelopes holds the four segments of every envelope shape, each
+// going from its first level (0 or 1) to its second. The first two play
+// once, the last two loop.
+var ymEnvelopes = [16][8]int{
+	{1, 0, 0, 0, 0, 0, 0, 0}, {1, 0, 0, 0, 0, 0, 0, 0}, {1, 0, 0, 0, 0, 0, 0, 0}, {1, 0, 0, 0, 0, 0, 0, 0},
+	{0, 1, 0, 0, 0, 0, 0, 0}, {0, 1, 0, 0, 0, 0, 0, 0}, {0, 1, 0, 0, 0, 0, 0, 0}, {0, 1, 0, 0, 0, 0, 0, 0},
+	{1, 0, 1, 0, 1, 0, 1, 0}, {1, 0, 0, 0, 0, 0, 0, 0}, {1, 0, 0, 1, 1, 0, 0, 1}, {1, 0, 1, 1, 1, 1, 1, 1},
+	{0, 1, 0, 1, 0, 1, 0, 1}, {0, 1, 1, 1, 1, 1, 1, 1}, {0, 1, 1, 0, 0, 1, 1, 0}, {0, 1, 0, 0, 0, 0, 0, 0},
+}
+
+// psgVoices re-synthesizes the three YM voices from the PSG registers:
+// the tones, the noise and the envelope, as the chip mixes them. StSound
+// only hands out the mixed output, so this is what the scopes show and
+// what the stereo placement mixes. The effects the replayer plays
+// besides the registers, digidrums and SID voices, are missing.
+type psgVoices struct {
+	phase [3]float64
+	noise float64 // position in the current noise step
+	lfsr  uint32
+	high  bool // noise output
+	env   float64
+	shape int
+	out   [3]float64
+	dac   [3]float64 // voice outputs, full scale 1 for the three voices
+}
+
+// next returns the voice samples for one output sample, square waves or
+// noise at the voice level for the scopes
+func (p *psgVoices) next(regs *[14]int, levels *[3]float64, sampleRate int) []float64 {
+	rate := float64(psgClock) / float64(sampleRate)
+
+	if period := regs[6] & 0x1f; period >= 3 {
+		p.noise += rate / (16 * float64(period))
+		for ; p.noise >= 1; p.noise-- {
+			if p.lfsr == 0 {
+				p.lfsr = 1
+			}
+			bit := (p.lfsr ^ p.lfsr>>2) & 1
+			p.lfsr = p.lfsr>>1 | bit<<16
+			if bit == 0 {
+				p.high = !p.high
+			}
+		}
+	} else {
+		p.noise, p.high = 0, true
+	}
+
+	// A new shape restarts the envelope. Writing the same one again
+	// does too on the chip, which the registers don't show.
+	if shape := regs[13] & 0x0f; shape != p.shape {
+		p.shape, p.env = shape, 0
+	}
+	if period := regs[11] | regs[12]<<8; period >= 3 {
+		p.env += rate / (256 * float64(period))
+		for p.env >= 4 {
+			p.env -= 2
+		}
+	}
+	seg := ymEnvelopes[p.shape][2*int(p.env) : 2*int(p.env)+2]
+	envLevel := min(15, seg[0]*15+(seg[1]-seg[0])*int((p.env-float64(int(p.env)))*16))
+
+	mixer := regs[7]
+	for c := 0; c < 3; c++ {
+		toneOff, noiseOff := mixer&(1<<c) != 0, mixer&(8<<c) != 0
+		tone := true
+		if period := regs[2*c] | (regs[2*c+1]&0x0f)<<8; period > 5 {
+			p.phase[c] += rate / (16 * float64(period))
+			p.phase[c] -= float64(int(p.phase[c]))
+			tone = p.phase[c] >= 0.5
+		}
+		on := (tone || toneOff) && (p.high || noiseOff)
+
+		level := regs[8+c] & 0x0f
+		if regs[8+c]&0x10 != 0 {
+			level = envLevel
+		}
+		p.dac[c] = 0
+		if on {
+			p.dac[c] = ymDAC[level] / ymDAC[15] / 3
+		}
+
+		p.out[c] = 0
+		if !toneOff || !noiseOff {
+			p.out[c] = -levels[c]
+			if on {
+				p.out[c] = levels[c]
+			}
+		}
+	}
+	return p.out[:]
+}
+
+// toggleScopes shows or hides the voice scopes
+func (g *Game) toggleScopes() {
+	g.scopes = !g.scopes
+}
+
+// drawScopes draws one small oscilloscope per music voice along the
+// bottom left of the screen
+func (g *Game) drawScopes(screen *ebiten.Image) {
+	if !g.scopes || g.music == nil {
+		return
+	}
+	const (
+		w, h = 96, 48
+		gap  = 8
+	)
+	voices := g.music.Scope()
+	y := float32(screen.Bounds().Dy() - h - gap)
+	for c, samples := range voices {
+		x := float32(gap + c*(w+gap))
+		vector.DrawFilledRect(screen, x, y, w, h, color.RGBA{0, 0, 0, 0xc0}, false)
+		vector.StrokeRect(screen, x, y, w, h, 1, color.RGBA{0x40, 0x40, 0x80, 0xff}, false)
+
+		// Start on a rising edge so the wave stands still
+		start := 0
+		for i := 1; i < len(samples)/2; i++ {
+			if samples[i-1] <= 0 && samples[i] > 0 {
+				start = i
+				break
+			}
+		}
+		span := samples[start : start+len(samples)/2]
+
+		mid := y + h/2
+		px, py := x, mid-span[0]*(h/2-2)
+		for i := 1; i < w; i++ {
+			v := span[i*len(span)/w]
+			nx, ny := x+float32(i), mid-v*(h/2-2)
+			vector.StrokeLine(screen, px, py, nx, ny, 1, color.RGBA{0x60, 0xff, 0x60, 0xff}, false)
+			px, py = nx, ny
+		}
+		ebitenutil.DebugPrintAt(screen, fmt.Sprintf("%c", 'A'+c), int(x)+3, int(y))
+	}
+}
@@ -0,0 +1,673 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"tcb-multi-plane-3d-scroller/easings"
+	"tcb-multi-plane-3d-scroller/hooks"
+)
+
+// ScrollMode selects how a scroller lays its letters out
+type ScrollMode int
+
+const (
+	// ScrollHorizontal is the original line swinging up and down
+	ScrollHorizontal ScrollMode = iota
+	// ScrollVertical runs the text bottom to top, swinging sideways
+	ScrollVertical
+	// ScrollRing carries the text around a tilted spinning ring
+	ScrollRing
+	// ScrollPath runs flat letters along the scroller's Path
+	ScrollPath
+)
+
+var scrollModeNames = []string{"horizontal", "vertical", "ring", "path"}
+
+func (m ScrollMode) String() string {
+	if m < ScrollHorizontal || m > ScrollPath {
+		return "unknown"
+	}
+	return scrollModeNames[m]
+}
+
+// Set implements flag.Value
+func (m *ScrollMode) Set(s string) error {
+	for i, name := range scrollModeNames {
+		if strings.EqualFold(s, name) {
+			*m = ScrollMode(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown scroll mode %q", s)
+}
+
+// Scroller is one 3D scroll text: its text, waveforms and position,
+// drawn with the shared font tiles and rasters
+type Scroller struct {
+	Mode        ScrollMode
+	Text        string
+	Forms       []ScrollForm
+	Speed       float64
+	TextEnd     TextEndMode
+	RightToLeft bool // direction after a restart
+
+	// Ring mode: radius in 3D units, tilt towards the camera in radians
+	// and spin in radians per frame, which sets the scroll speed
+	RingRadius float64
+	RingTilt   float64
+	RingSpeed  float64
+
+	// Path mode: canvas y of the path for a canvas x, the letters sit
+	// on it
+	Path func(x float64) float64
+
+	// Called when a character scrolled past, when the text wrapped to
+	// its start and when it ran out in TextEndNext mode
+	OnAdvance func()
+	OnWrap    func()
+	OnTextEnd func()
+
+	// Live text source, nil to scroll Text as is
+	Feed *TextFeed
+
+	// TimeText returns the time shown in place of the time fields
+	TimeText func() string
+
+	// Overlay draws extra text before the rasters color the letters
+	Overlay func(s *Scroller)
+
+	// Letter hooks run on every letter before it is drawn
+	Hooks *hooks.Registry
+
+	// Pulse brightens the rasters, from 0 to 1
+	Pulse float64
+
+	// Snap puts the letters on whole pixels, as the fixed-point maths of
+	// the ST did
+	Snap bool
+
+	// Rate is how many times a second Update runs, timing the pause
+	// codes
+	Rate int
+
+	// Perspective draws the letters as quads whose corners are scaled
+	// by their own depths, so they turn with steep slopes of the wave
+	Perspective bool
+
+	// Batched draws the letters of a pass with one DrawTriangles call
+	// from an atlas of the font, rather than one DrawImage each
+	Batched bool
+
+	// Proportional advances every letter by the width of its glyph,
+	// from the font spans, rather than by a whole tile
+	Proportional bool
+
+	// NoRasters leaves the letters in the colors of their font
+	NoRasters bool
+
+	// DrawList records the letters drawn, nil when not recording
+	DrawList *DrawList
+
+	// FlashColors are the colors the ^W1 words cycle through
+	FlashColors []color.RGBA
+
+	// Stamps are the images of the ^[img:name] codes
+	Stamps map[string]*ebiten.Image
+
+	// MaxLetters draws only the letters in the middle of the line, 0
+	// for all, and LetterScale sizes them
+	MaxLetters  int
+	LetterScale float64
+
+	// FormMorph is how many seconds the line takes to morph into a new
+	// waveform, 0 to switch at once, along MorphCurve
+	FormMorph  float64
+	MorphCurve easings.Curve
+
+	// Entrance is the width in letters over which the letters grow in as
+	// they enter the line, along EntranceCurve, 0 to show them whole
+	Entrance      float64
+	EntranceCurve easings.Curve
+
+	canvas    *ebiten.Image
+	fontTiles map[rune]*ebiten.Image
+	rasters   *ebiten.Image
+	camera    *Camera
+
+	form       int
+	lockedForm int // overrides the control codes when >= 0
+	lockedFace int // overrides the font codes when >= 0
+	scrollX    float64
+	addi       int
+	dir        float64 // 1 forward, -1 back, the sign of the scroll step
+	stopped    bool
+	rtl        bool
+	wave       Ticks // updates the waveforms moved on, waveStep each
+	trimmed    int   // letters dropped from the front of a fed text
+	ticks      uint64
+	printPos   []PrintPos
+
+	// Morph between waveforms: the shape it started from, the form it
+	// goes to and how far it got, from 0 to 1. The waves of the shape
+	// were at morphPhases on update morphWave, morphGap short of those
+	// of the form.
+	morphFrom   ScrollForm
+	morphTo     int
+	morphAt     float64
+	morphWave   Ticks
+	morphPhases wavePhases
+	morphGap    wavePhases
+
+	speed  float64 // set by the speed codes, 0 for Speed
+	pause  int     // updates left standing still
+	stream tokenStream
+	fonts  []map[rune]*ebiten.Image // the tiles of fontTiles first
+	spans  []map[rune][2]int        // drawn columns of the glyphs of fonts
+	caps   []FontCaps               // capabilities of fonts
+	faces  []fontFace
+	batch  *letterBatch
+	shade  *ebiten.Image // fog of the letters the rasters color
+	edge   int           // token after the last letter on screen
+	shown  int           // letters laid out on the last update
+}
+
+// NewScroller creates a scroller drawing into canvas. fontSpans are the
+// columns the glyphs of fontTiles cover, spacing proportional lines.
+func NewScroller(canvas *ebiten.Image, fontTiles map[rune]*ebiten.Image, fontSpans map[rune][2]int, rasters *ebiten.Image, camera *Camera) *Scroller {
+	faces := make([]fontFace, len(fontStyles))
+	for i, style := range fontStyles {
+		faces[i] = fontFace{style: style}
+	}
+	return &Scroller{
+		Speed:       4,
+		Rate:        50,
+		LetterScale: 1,
+		RingRadius:  120,
+		RingTilt:    0.35,
+		RingSpeed:   0.025,
+		canvas:      canvas,
+		fontTiles:   fontTiles,
+		fonts:       []map[rune]*ebiten.Image{fontTiles},
+		spans:       []map[rune][2]int{fontSpans},
+		caps:        []FontCaps{fontCapsOf(fontTiles)},
+		faces:       faces,
+		rasters:     rasters,
+		camera:      camera,
+		lockedForm:  -1,
+		lockedFace:  -1,
+		morphAt:     1,
+		MorphCurve:  easings.MustParse(defaultMorphCurve),
+		dir:         1,
+		printPos:    make([]PrintPos, proportionalSlots),
+	}
+}
+
+// Update lays the letters out for this frame and moves the text on
+func (s *Scroller) Update() {
+	s.ticks++
+
+	// Move the waveforms on
+	s.wave++
+
+	// Clear printPos array
+	for i := range s.printPos {
+		s.printPos[i] = PrintPos{}
+	}
+
+	// Process characters, from the letter in the first slot on in the
+	// order the text is consumed, until the line is full
+	wantRTL := s.rtl
+	far, farEnd := -1, 0.0
+	morphed, morphedPhases := s.morphedForm()
+	tokens := s.tokens()
+	k := s.tokenAt(s.addi)
+	cursor := 0.0
+	i := 0
+	for ; i < len(s.printPos) && cursor < lineWidth && s.stream.letters > 0; k += s.textDir() {
+		t := tokens[wrapToken(k, len(tokens))]
+		if s.lockedFace >= 0 {
+			t.Font = s.lockedFace
+		}
+
+		// Waveform and direction codes act while on screen
+		if t.IsCode() {
+			switch c := t.Code.Kind; {
+			case isFormCode(c):
+				s.form = t.Code.Arg
+			case c == 'R' || c == 'L':
+				wantRTL = c == 'R'
+			}
+			continue
+		}
+
+		letter := string(t.Letter)
+		if t.Letter == timeMarker && s.TimeText != nil {
+			letter = s.timeChar(t.Pos)
+		}
+
+		// Calculate 3D position using current form, the one the whole
+		// line morphs into when morphing
+		form := s.Forms[s.targetForm()]
+		phases := form.phasesAt(s.wave)
+		if s.FormMorph > 0 {
+			form, phases = morphed, morphedPhases
+		}
+		// The letter's index along everything scrolled, for the wave
+		n := t.N + s.trimmed
+		adv := s.advance(t)
+		x2d, y2d, scale := s.place(cursor, adv, float64(n), form, &phases)
+		if s.Snap {
+			x2d, y2d = math.Floor(x2d), math.Floor(y2d)
+		}
+
+		s.printPos[i].x = x2d
+		s.printPos[i].y = y2d
+		s.printPos[i].z = scale
+		s.printPos[i].letter = letter
+		s.printPos[i].slot = i
+		s.printPos[i].color = t.Color
+		s.printPos[i].font = t.Font
+		s.printPos[i].emphasis = t.Emphasis
+		s.printPos[i].n = n
+		s.printPos[i].stamp = t.Stamp
+		s.printPos[i].angle = s.letterAngle(n, form, &phases)
+		s.printPos[i].tilt = s.letterTilt(cursor, adv, n, form, &phases, scale)
+		s.printPos[i].dark, s.printPos[i].fade = s.fog(scale, form)
+		s.printPos[i].grow = s.entrance(cursor, adv)
+		cursor += adv.width
+		far, farEnd = t.Pos, cursor
+		i++
+	}
+	s.edge = k
+	s.shown = i
+	s.morph()
+
+	// Direction codes take effect once the whole line is laid out
+	s.setRightToLeft(wantRTL, far, farEnd)
+
+	// Sort by depth (back to front)
+	sort.Slice(s.printPos, func(i, j int) bool {
+		return s.printPos[i].z < s.printPos[j].z
+	})
+
+	if s.stopped {
+		return
+	}
+	if s.pause > 0 {
+		s.pause--
+		return
+	}
+
+	// Update scroll position
+	speed := s.Speed
+	if s.speed > 0 {
+		speed = s.speed
+	}
+	if s.Mode == ScrollRing {
+		// The spinning ring carries the letters
+		speed = s.RingSpeed * 32 / ringStep
+	}
+	s.scrollX += speed * s.dir * s.lineDir()
+
+	// Move on a letter for every letter width scrolled, several in one
+	// update at speeds above the width so none is skipped. Right to left
+	// scrollX runs below 0, a letter going out at minus its width.
+	for !s.stopped && s.pause == 0 {
+		x := s.scrollX * s.lineDir()
+		if w := s.advance(s.letterAt(0)).width; x >= w {
+			s.scrollX -= w * s.lineDir()
+			s.stepForward()
+		} else if x < 0 {
+			s.stepBack()
+		} else {
+			break
+		}
+	}
+}
+
+// stepForward moves the text on by the letter that scrolled out, towards
+// the start of the text right to left
+func (s *Scroller) stepForward() {
+	ranOut := false
+	if s.rtl {
+		ranOut = s.addi <= s.firstLetter()
+		if !ranOut {
+			s.addi = s.prevLetter()
+		}
+	} else {
+		s.addi = s.nextLetter()
+		ranOut = s.addi >= len(s.Text)
+	}
+	s.advanced()
+	s.enterCode()
+	if s.Feed != nil {
+		s.pullFeed()
+	}
+	s.advanceText(ranOut)
+}
+
+// stepBack moves the text back by a letter, at the ping-pong end of the
+// text or reversed
+func (s *Scroller) stepBack() {
+	from := s.addi
+	if s.atStart() && s.TextEnd != TextEndPingPong {
+		// Reversed past the start, carry on from the end
+		s.wrap()
+		s.addi = s.lastLetter()
+		if s.rtl {
+			s.addi = s.firstLetter()
+		}
+	} else {
+		s.addi = s.prevLetter()
+		if s.rtl {
+			s.addi = s.nextLetter()
+		}
+		s.enterCodeBack(from)
+	}
+	s.scrollX += s.advance(s.letterAt(0)).width * s.lineDir()
+	s.advanced()
+	if s.atStart() && s.TextEnd == TextEndPingPong {
+		s.wrap()
+		s.dir = 1
+	}
+}
+
+// atStart reports whether the first slot holds the letter the text
+// starts from, its last letter right to left
+func (s *Scroller) atStart() bool {
+	if s.rtl {
+		return s.addi >= s.lastLetter()
+	}
+	return s.addi <= 0
+}
+
+// waveStep is how far the waveforms move on each update, times their
+// speeds
+const waveStep = 0.02
+
+// place returns the canvas position and scale of letter n of the text,
+// laid out at cursor along the line, the waves of sf at ph
+func (s *Scroller) place(cursor float64, adv letterAdvance, n float64, sf ScrollForm, ph *wavePhases) (x, y, scale float64) {
+	// IMPORTANT: Use n (not i) for the wave calculation to keep it stable
+	// This ensures each character keeps its wave position as it scrolls,
+	// and through a change of direction
+	z := sf.zSize*math.Sin(sf.zAdd+n*sf.zAmount*0.01+ph[0]) + 150
+	swing := sf.ySize * math.Cos(1.5+n*sf.yAmount*0.01+ph[1])
+	wobble := sf.xSize * math.Sin(n*sf.xAmount*0.01+ph[2])
+
+	// Position calculation with smooth scrolling
+	along := -450.0 + cursor + adv.offset - s.scrollX + wobble
+	if s.rtl {
+		// The line is laid out from its right end, the letters entering
+		// from the left
+		along = -450.0 + lineWidth - cursor - adv.width + adv.offset - s.scrollX + wobble
+	}
+
+	switch s.Mode {
+	case ScrollVertical:
+		// The text reads top to bottom and rises, the wave moves it
+		// sideways instead of up and down
+		return s.project(swing, along, z)
+	case ScrollRing:
+		return s.project(s.placeOnRing(along))
+	case ScrollPath:
+		return s.placeOnPath(along)
+	}
+	return s.project(along, swing-4, z)
+}
+
+// letterAngle returns the rotation of letter n of the text in form sf
+// with its waves at ph, the letters rocking, or tumbling with a large rSize, along the wave
+func (s *Scroller) letterAngle(n int, sf ScrollForm, ph *wavePhases) float64 {
+	if sf.rSize == 0 {
+		return 0
+	}
+	return sf.rSize * math.Sin(float64(n)*sf.rAmount*0.01+ph[3])
+}
+
+// project maps a 3D letter position onto the canvas
+func (s *Scroller) project(x, y, z float64) (float64, float64, float64) {
+	scale := fov / (fov + z)
+	x2d := ((x - 16) * scale) + float64(s.canvas.Bounds().Dx())/2
+	y2d := ((y - 14) * scale) + float64(s.canvas.Bounds().Dy())/2
+	return x2d, y2d, scale
+}
+
+// ringStep is the angle between two letters on the ring, which holds
+// every visible letter
+const ringStep = 2 * math.Pi / scrollLetters
+
+// placeOnRing wraps the line position along around the ring. The ends
+// of the line meet at the back, the middle passes in front.
+func (s *Scroller) placeOnRing(along float64) (x, y, z float64) {
+	a := math.Pi + (along+450+16)*ringStep/32
+	rx := s.RingRadius * math.Sin(a)
+	rz := -s.RingRadius * math.Cos(a)
+
+	// Tilt the ring so its front dips towards the camera
+	y = -rz * math.Sin(s.RingTilt)
+	z = rz*math.Cos(s.RingTilt) + 150
+	return rx, y, z
+}
+
+// pathScale is the size of the flat letters following a path
+const pathScale = 0.5
+
+// placeOnPath stands the letter at line position along on the path
+func (s *Scroller) placeOnPath(along float64) (x, y, scale float64) {
+	x = (along+16)*pathScale + float64(s.canvas.Bounds().Dx())/2
+	y = float64(s.canvas.Bounds().Dy()) / 2
+	if s.Path != nil {
+		y = s.Path(x)
+	}
+	// Letters are drawn centered, lift them onto the path
+	return x, y - 16.5*pathScale, pathScale
+}
+
+// advanced reports a character scrolling past
+func (s *Scroller) advanced() {
+	if s.OnAdvance != nil {
+		s.OnAdvance()
+	}
+}
+
+// Draw renders the letters into the scroller canvas and colors them
+// with the rasters
+func (s *Scroller) Draw() {
+	// Don't clear the canvas, it's already cleared in Draw()
+
+	// Draw each character, keeping the ones opting out of the rasters
+	// for last
+	type lateLetter struct {
+		l     hooks.Letter
+		font  int
+		stamp string
+	}
+	var late []lateLetter
+	var shaded []shadedLetter
+	for i := range s.printPos {
+		p := s.printPos[i]
+		if p.letter == "" || p.z <= 0 || p.grow <= 0 || !s.drawnSlot(p.slot) {
+			continue
+		}
+
+		l := hooks.Letter{
+			Frame: s.ticks,
+			Slot:  p.slot,
+			Char:  p.letter[0],
+			X:     p.x,
+			Y:     p.y,
+			Scale: p.z * s.LetterScale * p.grow,
+			Angle: p.angle,
+			Tilt:  p.tilt,
+		}
+		if p.color > 0 {
+			// Color banks replace the rasters
+			l.Color.ScaleWithColor(colorBanks[p.color])
+			l.NoRaster = true
+		}
+		fadeLetter(&l, &p)
+		s.emphasize(&l, &p)
+		if s.Hooks != nil {
+			s.Hooks.RunLetter(&l)
+		}
+		if l.Skip {
+			continue
+		}
+		if l.NoRaster {
+			late = append(late, lateLetter{l, p.font, p.stamp})
+			continue
+		}
+		s.drawGlyph(&l, p.font, p.stamp)
+		shaded = append(shaded, shadedLetter{l, p.font, p.stamp, p.dark})
+	}
+	s.flushLetters()
+
+	// Blinking cursor when the text stopped at its end
+	s.drawWrapCursor()
+	if s.Overlay != nil {
+		s.Overlay(s)
+	}
+
+	// Apply raster colors
+	// The raster image needs to be stretched to cover the full canvas width
+	// Then source-atop will apply it only inside the already drawn letters
+	if !s.NoRasters {
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Scale(float64(s.canvas.Bounds().Dx())/float64(s.rasters.Bounds().Dx()), 1)
+		op.CompositeMode = ebiten.CompositeModeSourceAtop
+		if s.Pulse > 0 {
+			b := float32(1 + s.Pulse/2)
+			op.ColorScale.Scale(b, b, b, 1)
+		}
+		s.canvas.DrawImage(s.rasters, op)
+		s.DrawList.add("scroller", "rasters", s.rasters.Bounds(), op)
+		s.drawShade(shaded)
+	}
+
+	for i := range late {
+		s.drawGlyph(&late[i].l, late[i].font, late[i].stamp)
+	}
+	s.flushLetters()
+}
+
+// drawnSlot reports whether the letter in slot is among the MaxLetters
+// in the middle of the line
+func (s *Scroller) drawnSlot(slot int) bool {
+	if s.MaxLetters <= 0 {
+		return true
+	}
+	first := (s.shown - s.MaxLetters) / 2
+	return slot >= first && slot < first+s.MaxLetters
+}
+
+// drawText draws flat text with its top left corner at (x, y)
+func (s *Scroller) drawText(text string, x, y, scale float64) {
+	for i, ch := range text {
+		tile, ok := s.fontTiles[ch]
+		if !ok {
+			continue
+		}
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Scale(scale, scale)
+		op.GeoM.Translate(x+float64(i)*32*scale, y)
+		s.canvas.DrawImage(tile, op)
+	}
+}
+
+// AddFont adds a font the ^F codes can switch to and returns the number
+// of its face
+func (s *Scroller) AddFont(tiles map[rune]*ebiten.Image, spans map[rune][2]int) int {
+	s.fonts = append(s.fonts, tiles)
+	s.spans = append(s.spans, spans)
+	s.caps = append(s.caps, fontCapsOf(tiles))
+	s.faces = append(s.faces, fontFace{font: len(s.fonts) - 1, style: fontStyles[0]})
+	// The atlas is built again with the new tiles
+	s.batch = nil
+	return len(s.faces) - 1
+}
+
+// drawGlyph draws the letter l, or the image stamp of a stampMarker
+func (s *Scroller) drawGlyph(l *hooks.Letter, face int, stamp string) {
+	if l.Char == stampMarker {
+		s.drawStamp(l, stamp)
+		return
+	}
+	s.drawLetter(l, face)
+}
+
+// drawLetter draws one letter centered on its position in font face
+// face, or adds it to the batch
+func (s *Scroller) drawLetter(l *hooks.Letter, face int) {
+	f := s.faces[min(max(face, 0), len(s.faces)-1)]
+	tiles := s.fonts[f.font]
+	ch := s.fontRune(f.font, rune(l.Char))
+	tile, ok := tiles[ch]
+	if !ok {
+		ch = ' '
+		tile = tiles[ch]
+	}
+	if tile == nil {
+		return
+	}
+	tile, frame := animFrame(tile, s.ticks, s.Rate)
+
+	var local ebiten.GeoM
+	// Center the character sprite
+	local.Translate(-16, -16.5)
+	f.style.apply(&local)
+	local.Rotate(l.Angle)
+	geoM := local
+	geoM.Scale(l.Scale, l.Scale)
+	// Nearer letters follow the camera more
+	x := l.X + s.camera.Shift(l.Scale)
+	geoM.Translate(x, l.Y)
+
+	// Tilted letters are drawn as quads, flat ones with their transform
+	tilted := l.Tilt != [2]float64{}
+	var q quad
+	if tilted {
+		b := tile.Bounds()
+		q = letterQuad(local, float64(b.Dx()), float64(b.Dy()), l, x, l.Y)
+	}
+	if s.DrawList != nil {
+		name := fmt.Sprintf("font%d:%c", f.font, ch)
+		if tilted {
+			s.DrawList.addQuad("scroller", name, tile.Bounds(), geoM, q, l.Color)
+		} else {
+			s.DrawList.addGeoM("scroller", name, tile.Bounds(), geoM, "source-over", l.Color)
+		}
+	}
+
+	if s.Batched {
+		if s.batch == nil {
+			s.batch = newLetterBatch(s.fonts)
+		}
+		k := letterKey{f.font, ch, frame}
+		if tilted {
+			s.batch.addQuad(k, q, l.Color)
+		} else {
+			s.batch.add(k, geoM, l.Color)
+		}
+		return
+	}
+	if tilted {
+		drawQuad(s.canvas, tile, q, l.Color)
+		return
+	}
+
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM = geoM
+	op.ColorScale = l.Color
+
+	// Use nearest neighbor filter for pixel-perfect rendering
+	op.Filter = ebiten.FilterNearest
+
+	s.canvas.DrawImage(tile, op)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// defaultScrollTextFile replaces the built-in scroll text when present
+const defaultScrollTextFile = "assets/scrolltext.txt"
+
+// scrollTextTimeout bounds the download of -scrolltext-url, so a dead
+// server doesn't hold the start of the demo
+const scrollTextTimeout = 10 * time.Second
+
+// LoadScrollText reads a UTF-8 scroll text file. Accented letters are
+// transliterated, lines are joined with spaces and letters upper-cased
+// unless the font has them in lowercase; every character must then be in
+// the font, a control code or the %TIME% field.
+func LoadScrollText(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read scroll text: %w", err)
+	}
+	return parseScrollText(path, data)
+}
+
+// FetchScrollText downloads a scroll text over HTTP, read as a file
+func FetchScrollText(url string) (string, error) {
+	client := &http.Client{Timeout: scrollTextTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", fmt.Errorf("failed to download scroll text: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download scroll text: unexpected status %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteText+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to download scroll text: %w", err)
+	}
+	if len(data) > maxRemoteText {
+		return "", fmt.Errorf("%s: scroll text is longer than %d bytes", url, maxRemoteText)
+	}
+	return parseScrollText(url, data)
+}
+
+// parseScrollText checks and lays out the scroll text data read from
+// name
+func parseScrollText(name string, data []byte) (string, error) {
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = transliterate(line)
+		if err := checkScrollChars(lines[i]); err != nil {
+			return "", fmt.Errorf("%s:%d: %w", name, i+1, err)
+		}
+	}
+	text := strings.TrimSpace(strings.Join(lines, " "))
+	if text == "" {
+		return "", fmt.Errorf("%s: scroll text is empty", name)
+	}
+
+	// Blank screens around the text, as in the built-in one
+	spc := strings.Repeat(" ", scrollLetters)
+	return spc + fontCase(text) + spc, nil
+}
+
+// loadScrollText downloads the text of -scrolltext-url, or reads the
+// text set with -scrolltext or the default file when there is one, and
+// reports whether it replaces the built-in text. A failed download falls
+// back to the files.
+func (g *Game) loadScrollText() (string, bool) {
+	if url := g.cfg.ScrollTextURL; url != "" {
+		text, err := FetchScrollText(url)
+		if err == nil {
+			return text, true
+		}
+		log.Printf("Falling back from -scrolltext-url: %v", err)
+	}
+
+	path := g.cfg.ScrollText
+	if path == "" {
+		if _, err := os.Stat(defaultScrollTextFile); errors.Is(err, os.ErrNotExist) {
+			return "", false
+		}
+		path = defaultScrollTextFile
+	}
+	text, err := LoadScrollText(path)
+	if err != nil {
+		log.Printf("Failed to load the scroll text, using the built-in one: %v", err)
+		return "", false
+	}
+	return text, true
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// seekStep is how far an arrow key moves through the tune
+const seekStep = 5 * time.Second
+
+// Progress bar shown after seeking, at the bottom of the screen
+const (
+	seekBarFrames = 120
+	seekBarMargin = 48
+	seekBarHeight = 6
+)
+
+// musicLength returns the duration of one loop of the music playing, 0
+// when it cannot be scrubbed through
+func (g *Game) musicLength() time.Duration {
+	if g.audioPlayer == nil {
+		return 0
+	}
+	if l, ok := g.music.(interface{ Length() time.Duration }); ok {
+		return l.Length()
+	}
+	return 0
+}
+
+// updateSeek moves through the tune with the left and right arrows
+func (g *Game) updateSeek() {
+	if g.seekBar > 0 {
+		g.seekBar--
+	}
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyRight):
+		g.seek(seekStep)
+	case inpututil.IsKeyJustPressed(ebiten.KeyLeft):
+		g.seek(-seekStep)
+	}
+}
+
+// seek moves the music by delta, staying within the loop playing so the
+// loops counted are kept
+func (g *Game) seek(delta time.Duration) {
+	length := g.musicLength()
+	if length <= 0 {
+		g.notice.Show("This music cannot be scrubbed")
+		return
+	}
+	pos := g.audioPlayer.Position()
+	start := pos - pos%length
+	at := max(0, min(pos%length+delta, length-time.Millisecond))
+	if err := g.audioPlayer.SetPosition(start + at); err != nil {
+		log.Printf("Failed to seek the music: %v", err)
+		return
+	}
+	g.seekBar = seekBarFrames
+}
+
+// drawSeekBar shows the position in the tune after seeking
+func (g *Game) drawSeekBar(screen *ebiten.Image) {
+	length := g.musicLength()
+	if g.seekBar == 0 || length <= 0 {
+		return
+	}
+	at := g.audioPlayer.Position() % length
+
+	y := float32(screenHeight - seekBarMargin)
+	w := float32(screenWidth - 2*seekBarMargin)
+	vector.DrawFilledRect(screen, seekBarMargin, y, w, seekBarHeight, color.RGBA{0x40, 0x40, 0x40, 0xc0}, false)
+	vector.DrawFilledRect(screen, seekBarMargin, y, w*float32(at)/float32(length), seekBarHeight, color.White, false)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("%s / %s", clockTime(at), clockTime(length)), seekBarMargin, int(y)-20)
+}
+
+// clockTime formats d as minutes and seconds
+func clockTime(d time.Duration) string {
+	secs := int(d / time.Second)
+	return fmt.Sprintf("%02d:%02d", secs/60, secs%60)
+}
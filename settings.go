@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Settings is what the demo remembers between runs
+type Settings struct {
+	// Achievements unlocked, by id, and when
+	Achievements map[string]time.Time `json:"achievements,omitempty"`
+}
+
+// defaultSettingsPath returns the settings file in the user config
+// directory, empty when there is none
+func defaultSettingsPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "tcb-multi-plane-3d-scroller", "settings.json")
+}
+
+// LoadSettings reads the settings file, a missing file giving empty
+// settings
+func LoadSettings(path string) (*Settings, error) {
+	s := &Settings{Achievements: make(map[string]time.Time)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, fmt.Errorf("failed to read settings: %w", err)
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return s, fmt.Errorf("failed to parse settings: %w", err)
+	}
+	if s.Achievements == nil {
+		s.Achievements = make(map[string]time.Time)
+	}
+	return s, nil
+}
+
+// Save writes the settings file, creating its directory
+func (s *Settings) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create settings directory: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write settings: %w", err)
+	}
+	return nil
+}
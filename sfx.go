@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+)
+
+// Sound effects
+const (
+	SFXKey         = iota // key press blip
+	SFXSecret             // hidden page found
+	SFXAchievement        // achievement unlocked
+	sfxCount
+)
+
+// sfxVoices is how many effects play at once, the oldest is cut off
+const sfxVoices = 4
+
+// sfxVoice is an effect being played
+type sfxVoice struct {
+	sample []float64
+	pos    int
+}
+
+// SFXMixer plays short samples on top of the music stream. The audio
+// player only pulls one stream, so the effects are added to the music
+// samples as they go through.
+type SFXMixer struct {
+	mu      sync.Mutex
+	samples [sfxCount][]float64
+	voices  []sfxVoice
+	volume  float64
+}
+
+// NewSFXMixer synthesizes the effects at sampleRate
+func NewSFXMixer(sampleRate int, volume float64) *SFXMixer {
+	m := &SFXMixer{volume: volume}
+	m.samples[SFXKey] = sfxTone(sampleRate, 0.03, 1200, 1200)
+	m.samples[SFXSecret] = sfxArpeggio(sampleRate, 0.07, 523, 659, 784, 1047)
+	m.samples[SFXAchievement] = sfxTone(sampleRate, 0.25, 440, 1760)
+	return m
+}
+
+// sfxTone is a square wave sweeping from hz to toHz over seconds, fading
+// out as a PSG envelope would
+func sfxTone(sampleRate int, seconds, hz, toHz float64) []float64 {
+	n := int(seconds * float64(sampleRate))
+	out := make([]float64, n)
+	phase := 0.0
+	for i := range out {
+		t := float64(i) / float64(n)
+		phase += (hz + (toHz-hz)*t) / float64(sampleRate)
+		v := 1.0
+		if math.Mod(phase, 1) >= 0.5 {
+			v = -1
+		}
+		out[i] = v * (1 - t)
+	}
+	return out
+}
+
+// sfxArpeggio plays the notes one after the other, each lasting seconds
+func sfxArpeggio(sampleRate int, seconds float64, notes ...float64) []float64 {
+	var out []float64
+	for _, hz := range notes {
+		out = append(out, sfxTone(sampleRate, seconds, hz, hz)...)
+	}
+	return out
+}
+
+// Play starts effect id
+func (m *SFXMixer) Play(id int) {
+	if m == nil || id < 0 || id >= sfxCount {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.voices) == sfxVoices {
+		m.voices = m.voices[1:]
+	}
+	m.voices = append(m.voices, sfxVoice{sample: m.samples[id]})
+}
+
+// mix adds the effects playing to p, 16-bit stereo frames
+func (m *SFXMixer) mix(p []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.voices) == 0 {
+		return
+	}
+	for i := 0; i+4 <= len(p); i += 4 {
+		v := 0.0
+		for j := range m.voices {
+			voice := &m.voices[j]
+			if voice.pos < len(voice.sample) {
+				v += voice.sample[voice.pos] * m.volume
+				voice.pos++
+			}
+		}
+		for c := 0; c < 4; c += 2 {
+			s := float64(int16(binary.LittleEndian.Uint16(p[i+c:]))) / 32768
+			putSample(p[i+c:], s+v)
+		}
+	}
+
+	live := m.voices[:0]
+	for _, voice := range m.voices {
+		if voice.pos < len(voice.sample) {
+			live = append(live, voice)
+		}
+	}
+	m.voices = live
+}
+
+// Stream returns src with the effects mixed on top
+func (m *SFXMixer) Stream(src io.Reader) io.Reader {
+	return &sfxStream{src: src, mixer: m}
+}
+
+// sfxStream mixes the effects into the samples read from src. It
+// forwards Seek so the audio player can still rewind the music.
+type sfxStream struct {
+	src   io.Reader
+	mixer *SFXMixer
+}
+
+func (s *sfxStream) Read(p []byte) (int, error) {
+	n, err := s.src.Read(p)
+	s.mixer.mix(p[:n-n%4])
+	return n, err
+}
+
+// Seek keeps the wrapped stream seekable
+func (s *sfxStream) Seek(offset int64, whence int) (int64, error) {
+	sk, ok := s.src.(io.Seeker)
+	if !ok {
+		return 0, fmt.Errorf("music stream is not seekable")
+	}
+	return sk.Seek(offset, whence)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"image"
+	"math"
+)
+
+// Where the distorted part of the logo sits on the logo plane
+const (
+	logoX    = 8
+	logoTop  = 96
+	logoRows = 32
+)
+
+// topContour returns, for every column of r, the distance from the top
+// of r to the first opaque pixel of img, or r.Dy() for empty columns.
+// The result is smoothed over a few columns so a path following it
+// does not jitter on single pixels.
+func topContour(img image.Image, r image.Rectangle) []float64 {
+	raw := make([]float64, r.Dx())
+	for x := r.Min.X; x < r.Max.X; x++ {
+		top := r.Dy()
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a > 0x8000 {
+				top = y - r.Min.Y
+				break
+			}
+		}
+		raw[x-r.Min.X] = float64(top)
+	}
+
+	const radius = 2
+	contour := make([]float64, len(raw))
+	for i := range raw {
+		sum, n := 0.0, 0
+		for j := max(0, i-radius); j <= min(len(raw)-1, i+radius); j++ {
+			sum += raw[j]
+			n++
+		}
+		contour[i] = sum / float64(n)
+	}
+	return contour
+}
+
+// logoRowX returns how far row i of the distorted logo is shifted, by
+// the distortion and the camera pan
+func (g *Game) logoRowX(i int) float64 {
+	x := g.logoSin[g.dcounter+i]*(1+g.pulse/2) + g.camera.Shift(logoDepth)
+	if g.cfg.FixedPoint {
+		x = math.Floor(x)
+	}
+	return x
+}
+
+// logoPath returns the y of the logo's top edge at column x of the logo
+// plane as it is drawn, or the bottom of the logo beside it. Every row
+// is shifted on its own, so the contour is looked up where the row at the
+// edge comes from: first the top row's, then the row found there.
+func (g *Game) logoPath(x float64) float64 {
+	top := 0.0
+	for range 2 {
+		row := min(int(top), logoRows-1)
+		i := int(math.Floor(x-g.logoRowX(row))) - logoX
+		if i < 0 || i >= len(g.logoContour) {
+			return logoTop + logoRows
+		}
+		top = g.logoContour[i]
+	}
+	return logoTop + top
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const (
+	// soakCheckEvery is how often the slow invariants are checked
+	soakCheckEvery = 10 * time.Second
+	// soakWarmup is how long the heap may grow before it is measured
+	soakWarmup = time.Minute
+	// soakHeapSlack is how far the heap may grow past twice its size
+	// after the warmup
+	soakHeapSlack = 64 << 20
+)
+
+// soakViolation is an invariant broken during a soak test, with the
+// first time it was
+type soakViolation struct {
+	count  int
+	first  time.Duration
+	detail string
+}
+
+// Soak runs the demo for hours, minimized, checking its invariants on
+// the way, and writes a report: the slow leaks and the bugs at the
+// wraps of the text only show up after a night at a party
+type Soak struct {
+	runner ebiten.Game
+	game   *Game // the one whose state is checked
+	length time.Duration
+	report string
+
+	start      time.Time
+	lastCheck  time.Time
+	updates    uint64
+	wraps      int
+	lastAddi   int
+	heapBase   uint64 // 0 until measured after the warmup
+	heapPeak   uint64
+	goroutines [2]int // at the start and at the most
+	audioPos   time.Duration
+	violations map[string]*soakViolation
+	panicked   string
+	panicErr   error // ends the test on the update after a panic in Draw
+	stopped    bool  // ended before its time
+}
+
+// NewSoak wraps runner, which runs g, in a soak test of length
+func NewSoak(runner ebiten.Game, g *Game, length time.Duration, report string) *Soak {
+	n := runtime.NumGoroutine()
+	return &Soak{
+		runner:     runner,
+		game:       g,
+		length:     length,
+		report:     report,
+		goroutines: [2]int{n, n},
+		audioPos:   -1,
+		violations: make(map[string]*soakViolation),
+	}
+}
+
+// Update steps the demo and checks it, ending the test when its time
+// is up or the demo panicked
+func (s *Soak) Update() (err error) {
+	if s.panicErr != nil {
+		return s.panicErr
+	}
+	now := time.Now()
+	if s.start.IsZero() {
+		s.start, s.lastCheck = now, now
+		ebiten.MinimizeWindow()
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = s.recordPanic(r)
+		}
+	}()
+	if err := s.runner.Update(); err != nil {
+		s.stopped = true
+		s.finish()
+		return err
+	}
+	s.updates++
+
+	s.checkFrame()
+	if now.Sub(s.lastCheck) >= soakCheckEvery {
+		s.lastCheck = now
+		s.checkSlow()
+	}
+	if s.elapsed() >= s.length {
+		s.finish()
+		return ebiten.Termination
+	}
+	return nil
+}
+
+// Draw draws the demo. A panic ends the test on the next update, as
+// Draw can't return an error.
+func (s *Soak) Draw(screen *ebiten.Image) {
+	if s.panicErr != nil {
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			s.panicErr = s.recordPanic(r)
+		}
+	}()
+	s.runner.Draw(screen)
+}
+
+// recordPanic reports the panic r of the demo and writes the report
+func (s *Soak) recordPanic(r any) error {
+	s.panicked = fmt.Sprintf("%v\n%s", r, debug.Stack())
+	s.finish()
+	return fmt.Errorf("soak test: demo panicked after %v: %v", s.elapsed(), r)
+}
+
+// Layout keeps the layout of the demo
+func (s *Soak) Layout(outsideWidth, outsideHeight int) (int, int) {
+	return s.runner.Layout(outsideWidth, outsideHeight)
+}
+
+func (s *Soak) elapsed() time.Duration {
+	return time.Since(s.start)
+}
+
+// violate records a broken invariant
+func (s *Soak) violate(name, format string, args ...any) {
+	v := s.violations[name]
+	if v == nil {
+		v = &soakViolation{first: s.elapsed(), detail: fmt.Sprintf(format, args...)}
+		s.violations[name] = v
+		log.Printf("Soak test: %s: %s", name, v.detail)
+	}
+	v.count++
+}
+
+// checkFrame checks the letters laid out and the text positions of
+// every scroller
+func (s *Soak) checkFrame() {
+	g := s.game
+	for i, sc := range g.allScrollers() {
+		for _, p := range sc.printPos {
+			if !finite(p.x) || !finite(p.y) || !finite(p.z) {
+				s.violate("nan-position", "scroller %d slot %d at (%g, %g, %g)", i, p.slot, p.x, p.y, p.z)
+			}
+		}
+		if !finite(sc.scrollX) {
+			s.violate("nan-scroll", "scroller %d scrolled to %g", i, sc.scrollX)
+		}
+		if sc.addi < 0 || sc.addi > len(sc.Text) {
+			s.violate("text-position", "scroller %d at %d of a %d byte text", i, sc.addi, len(sc.Text))
+		}
+	}
+
+	// The text wraps when it moves against the scroll direction
+	if float64(g.scroller.addi-s.lastAddi)*g.scroller.dir*g.scroller.lineDir() < 0 {
+		s.wraps++
+	}
+	s.lastAddi = g.scroller.addi
+}
+
+// checkSlow checks the memory and the music
+func (s *Soak) checkSlow() {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	s.heapPeak = max(s.heapPeak, ms.HeapInuse)
+	switch {
+	case s.heapBase == 0 && s.elapsed() >= soakWarmup:
+		s.heapBase = ms.HeapInuse
+	case s.heapBase > 0 && ms.HeapInuse > 2*s.heapBase+soakHeapSlack:
+		s.violate("heap-growth", "heap in use %s, %s after the warmup", megabytes(ms.HeapInuse), megabytes(s.heapBase))
+	}
+	s.goroutines[1] = max(s.goroutines[1], runtime.NumGoroutine())
+
+	g := s.game
+	if g.audioPlayer == nil || g.paused || g.quitting || !g.audioPlayer.IsPlaying() {
+		s.audioPos = -1
+		return
+	}
+	pos := g.audioPlayer.Position()
+	if pos == s.audioPos {
+		s.violate("audio-stalled", "music stuck at %v", pos)
+	}
+	s.audioPos = pos
+}
+
+// finish writes the report
+func (s *Soak) finish() {
+	if err := os.WriteFile(s.report, []byte(s.String()), 0o644); err != nil {
+		log.Printf("Failed to write soak report: %v", err)
+		return
+	}
+	log.Printf("Soak report written to %s", s.report)
+}
+
+// Failed reports whether an invariant was broken or the demo panicked
+func (s *Soak) Failed() bool {
+	return len(s.violations) > 0 || s.panicked != ""
+}
+
+// String formats the report
+func (s *Soak) String() string {
+	var b strings.Builder
+	result := "PASSED"
+	if s.Failed() {
+		result = "FAILED"
+	}
+	fmt.Fprintf(&b, "Soak test:  %s\n", result)
+	fmt.Fprintf(&b, "Started:    %s\n", s.start.Format(time.RFC3339))
+	fmt.Fprintf(&b, "Ran:        %v of %v", s.elapsed().Round(time.Second), s.length)
+	if s.stopped {
+		b.WriteString(", stopped early")
+	}
+	fmt.Fprintf(&b, "\nUpdates:    %d, %d text wraps\n", s.updates, s.wraps)
+	fmt.Fprintf(&b, "Heap:       %s after the warmup, %s at the most\n", megabytes(s.heapBase), megabytes(s.heapPeak))
+	fmt.Fprintf(&b, "Goroutines: %d at the start, %d at the most\n", s.goroutines[0], s.goroutines[1])
+
+	if len(s.violations) > 0 {
+		b.WriteString("\nViolations:\n")
+		names := make([]string, 0, len(s.violations))
+		for name := range s.violations {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			v := s.violations[name]
+			fmt.Fprintf(&b, "  %-14s %d times, first after %v: %s\n", name, v.count, v.first.Round(time.Second), v.detail)
+		}
+	}
+	if s.panicked != "" {
+		fmt.Fprintf(&b, "\nPanic:\n%s\n", s.panicked)
+	}
+	return b.String()
+}
+
+func finite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+func megabytes(n uint64) string {
+	return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+}
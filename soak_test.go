@@ -0,0 +1,28 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// panickyDraw is a demo whose Draw panics
+type panickyDraw struct{}
+
+func (panickyDraw) Update() error              { return nil }
+func (panickyDraw) Draw(*ebiten.Image)         { panic("lost a letter") }
+func (panickyDraw) Layout(w, h int) (int, int) { return w, h }
+
+func TestSoakRecoversDrawPanic(t *testing.T) {
+	s := NewSoak(panickyDraw{}, nil, time.Hour, filepath.Join(t.TempDir(), "soak-report.txt"))
+	s.Draw(nil)
+	if err := s.Update(); err == nil || !strings.Contains(err.Error(), "lost a letter") {
+		t.Fatalf("update after the panic returned %v", err)
+	}
+	if !s.Failed() || !strings.Contains(s.String(), "lost a letter") {
+		t.Errorf("the report misses the panic:\n%s", s)
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"tcb-multi-plane-3d-scroller/hooks"
+)
+
+// stampMarker stands for an image stamp in the laid out text
+const stampMarker = '\x02'
+
+// stampPrefix starts the code of an image stamp, ^[img:name]
+const stampPrefix = "^[img:"
+
+// codeChars are typed in the editor for the control codes, besides the
+// letters of the font
+const codeChars = "^[]-_"
+
+// maxStampName bounds the names of the stamps
+const maxStampName = 32
+
+// validStampName reports whether name can be written in a stamp code:
+// letters, digits, '-' and '_'
+func validStampName(name string) bool {
+	if name == "" || len(name) > maxStampName {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// stampCodeLen returns the length of the stamp code starting at text[i],
+// 0 when there is none. Like the other codes it is read in either case,
+// as the texts are upper-cased for the font.
+func stampCodeLen(text string, i int) int {
+	if len(text)-i < len(stampPrefix) || !strings.EqualFold(text[i:i+len(stampPrefix)], stampPrefix) {
+		return 0
+	}
+	start := i + len(stampPrefix)
+	end := strings.IndexByte(text[start:], ']')
+	if end < 0 || !validStampName(text[start:start+end]) {
+		return 0
+	}
+	return len(stampPrefix) + end + 1
+}
+
+// stampName returns the name in the stamp code starting at text[i],
+// lower-cased as the names of the registry
+func stampName(text string, i, size int) string {
+	return strings.ToLower(text[i+len(stampPrefix) : i+size-1])
+}
+
+// LoadStamps reads a stamp registry, a JSON object naming PNG images
+// relative to it, e.g. {"smiley": "smiley.png"}. The names are
+// returned lower-cased.
+func LoadStamps(path string) (map[string]image.Image, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stamps: %w", err)
+	}
+	var files map[string]string
+	if err := json.Unmarshal(data, &files); err != nil {
+		return nil, fmt.Errorf("failed to parse stamps: %w", err)
+	}
+
+	stamps := make(map[string]image.Image, len(files))
+	for name, file := range files {
+		if !validStampName(name) {
+			return nil, fmt.Errorf("%s: stamp name %q is not letters, digits, '-' and '_'", path, name)
+		}
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(filepath.Dir(path), file)
+		}
+		img, err := decodeStamp(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: stamp %s: %w", path, name, err)
+		}
+		stamps[strings.ToLower(name)] = img
+	}
+	return stamps, nil
+}
+
+func decodeStamp(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stamp image: %w", err)
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode stamp image: %w", err)
+	}
+	return img, nil
+}
+
+// Preload decodes the images of -stamps the ^[img:name] codes show.
+// Every scene holds its own, uploaded when it is entered.
+func (s *demoScene) Preload() error {
+	if s.g.cfg.Stamps == "" {
+		return nil
+	}
+	images, err := LoadStamps(s.g.cfg.Stamps)
+	if err != nil {
+		return err
+	}
+	s.images = images
+	return nil
+}
+
+// Release frees the stamp images of the scene
+func (s *demoScene) Release() {
+	for _, img := range s.stamps {
+		img.Deallocate()
+	}
+	s.images = nil
+	s.stamps = nil
+}
+
+// VRAM returns the bytes of the stamp images of the scene
+func (s *demoScene) VRAM() int64 {
+	var n int64
+	for _, img := range s.stamps {
+		n += imageBytes(img)
+	}
+	return n
+}
+
+// useStamps uploads the decoded stamp images and hands them to every
+// scroller of the scene
+func (s *demoScene) useStamps() {
+	if s.stamps == nil && s.images != nil {
+		s.stamps = make(map[string]*ebiten.Image, len(s.images))
+		for name, img := range s.images {
+			s.stamps[name] = ebiten.NewImageFromImage(img)
+		}
+		s.images = nil
+	}
+	s.scroller.Stamps = s.stamps
+	for _, sc := range s.g.allScrollers() {
+		sc.Stamps = s.stamps
+	}
+}
+
+// stampFit returns the scale fitting img in a font tile, images smaller
+// than a tile keeping their size
+func stampFit(img *ebiten.Image) float64 {
+	b := img.Bounds()
+	return min(1, float64(fontTileWidth)/float64(b.Dx()), float64(fontTileHeight)/float64(b.Dy()))
+}
+
+// stampAdvance returns the room the stamp name takes on a proportional
+// line: its width and a gap, centered on the tile like the glyphs
+func (s *Scroller) stampAdvance(name string) letterAdvance {
+	img, ok := s.Stamps[name]
+	if !ok {
+		return letterAdvance{width: letterSpace}
+	}
+	w := float64(img.Bounds().Dx()) * stampFit(img)
+	return letterAdvance{width: w + letterGap, offset: letterGap/2 - (fontTileWidth-w)/2}
+}
+
+// drawStamp draws the stamp name centered on the position of l, as a
+// letter. Unknown stamps leave a gap.
+func (s *Scroller) drawStamp(l *hooks.Letter, name string) {
+	img, ok := s.Stamps[name]
+	if !ok {
+		return
+	}
+	// Keep the depth order of the letters batched so far
+	s.flushLetters()
+
+	b := img.Bounds()
+	var local ebiten.GeoM
+	local.Translate(-float64(b.Dx())/2, -float64(b.Dy())/2)
+	local.Rotate(l.Angle)
+	local.Scale(stampFit(img), stampFit(img))
+	geoM := local
+	geoM.Scale(l.Scale, l.Scale)
+	x := l.X + s.camera.Shift(l.Scale)
+	geoM.Translate(x, l.Y)
+
+	if l.Tilt != [2]float64{} {
+		q := letterQuad(local, float64(b.Dx()), float64(b.Dy()), l, x, l.Y)
+		s.DrawList.addQuad("scroller", "stamp:"+name, b, geoM, q, l.Color)
+		drawQuad(s.canvas, img, q, l.Color)
+		return
+	}
+	if s.DrawList != nil {
+		s.DrawList.addGeoM("scroller", "stamp:"+name, b, geoM, "source-over", l.Color)
+	}
+
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM = geoM
+	op.ColorScale = l.Color
+	op.Filter = ebiten.FilterNearest
+	s.canvas.DrawImage(img, op)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// statsScreenFrames is how long the statistics stay up when quitting
+const statsScreenFrames = 5 * 60
+
+// Stats counts what happened during the run
+type Stats struct {
+	start          time.Time
+	Updates        int
+	FramesRendered int
+	CharsScrolled  int
+}
+
+// NewStats starts counting from now
+func NewStats() *Stats {
+	return &Stats{start: time.Now()}
+}
+
+// StatsReport is the summary shown at exit and written as JSON
+type StatsReport struct {
+	Duration         string         `json:"duration"`
+	FramesRendered   int            `json:"frames_rendered"`
+	AverageFPS       float64        `json:"average_fps"`
+	DroppedFrames    int            `json:"dropped_frames"`
+	MusicLoops       int            `json:"music_loops"`
+	CharsScrolled    int            `json:"characters_scrolled"`
+	Achievements     []string       `json:"achievements"`
+	EffectsTriggered map[string]int `json:"effects_triggered"`
+	SceneMemory      []SceneMemory  `json:"scene_memory"`
+}
+
+// statsReport gathers the counters of every module
+func (g *Game) statsReport() StatsReport {
+	elapsed := time.Since(g.stats.start)
+	r := StatsReport{
+		Duration:         elapsed.Round(time.Second).String(),
+		FramesRendered:   g.stats.FramesRendered,
+		CharsScrolled:    g.stats.CharsScrolled,
+		EffectsTriggered: g.effects.Triggers(),
+		SceneMemory:      g.memory.Scenes(),
+	}
+	if s := elapsed.Seconds(); s > 0 {
+		r.AverageFPS = float64(g.stats.FramesRendered) / s
+	}
+	// Ebiten skips drawing when it cannot keep up with the updates, the
+	// frames left out on purpose by -draw-fps do not count
+	if d := g.stats.Updates/g.drawEvery() - g.stats.FramesRendered; d > 0 {
+		r.DroppedFrames = d
+	}
+	if g.music != nil {
+		r.MusicLoops = g.music.Loops()
+	}
+	for _, a := range achievementList {
+		if g.achievements.Unlocked(a.ID) {
+			r.Achievements = append(r.Achievements, a.ID)
+		}
+	}
+	return r
+}
+
+// writeStats saves the statistics report as JSON
+func (g *Game) writeStats(path string) error {
+	data, err := json.MarshalIndent(g.statsReport(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// startQuit freezes the demo and shows the statistics screen
+func (g *Game) startQuit() {
+	g.quitting = true
+	g.quitFrames = 0
+	if g.audioPlayer != nil {
+		g.audioPlayer.Pause()
+	}
+}
+
+// updateQuit closes the demo once the statistics were shown long enough
+// or a key is pressed
+func (g *Game) updateQuit() error {
+	g.quitFrames++
+	if g.quitFrames > statsScreenFrames || ebiten.IsWindowBeingClosed() ||
+		(g.quitFrames > 1 && len(inpututil.AppendJustPressedKeys(nil)) > 0) {
+		return ebiten.Termination
+	}
+	return nil
+}
+
+// drawStats renders the statistics screen while quitting
+func (g *Game) drawStats(screen *ebiten.Image) {
+	if !g.quitting {
+		return
+	}
+
+	b := screen.Bounds()
+	vector.DrawFilledRect(screen, 0, 0, float32(b.Dx()), float32(b.Dy()), color.RGBA{0, 0, 0, 0xd0}, false)
+
+	r := g.statsReport()
+	lines := []string{
+		"DEMO STATISTICS",
+		"",
+		fmt.Sprintf("Running time        %s", r.Duration),
+		fmt.Sprintf("Frames rendered     %d", r.FramesRendered),
+		fmt.Sprintf("Average FPS         %.1f", r.AverageFPS),
+		fmt.Sprintf("Dropped frames      %d", r.DroppedFrames),
+		fmt.Sprintf("Music loops         %d", r.MusicLoops),
+		fmt.Sprintf("Characters scrolled %d", r.CharsScrolled),
+		fmt.Sprintf("Achievements        %d of %d", len(r.Achievements), len(achievementList)),
+		"",
+		"Effects triggered",
+	}
+
+	names := make([]string, 0, len(r.EffectsTriggered))
+	for name := range r.EffectsTriggered {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("  %-17s %d", name, r.EffectsTriggered[name]))
+	}
+	if len(names) == 0 {
+		lines = append(lines, "  none")
+	}
+	lines = append(lines, "", "Memory by scene     heap     VRAM")
+	for _, m := range r.SceneMemory {
+		lines = append(lines, fmt.Sprintf("  %-17s %4d MB  %4d MB", m.Scene, m.Heap>>20, m.VRAM>>20))
+	}
+	lines = append(lines, "", "Press any key to exit")
+
+	for i, line := range lines {
+		ebitenutil.DebugPrintAt(screen, line, 240, 120+i*16)
+	}
+}
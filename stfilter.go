@@ -0,0 +1,47 @@
+package main
+
+// stFilterCutoff approximates the output filter and speaker of the
+// Atari ST, rounding off the edges of the PSG square waves
+const stFilterCutoff = 4800
+
+// stFilter is a one-pole low-pass filter on the YM output
+type stFilter struct {
+	enabled bool
+	coef    float64
+	state   float64
+}
+
+func newSTFilter(sampleRate int) stFilter {
+	return stFilter{coef: onePole(stFilterCutoff, float64(sampleRate))}
+}
+
+// process filters one sample, passing it through when disabled
+func (f *stFilter) process(x float64) float64 {
+	if !f.enabled {
+		f.state = x
+		return x
+	}
+	f.state += (x - f.state) * f.coef
+	return f.state
+}
+
+// SetLowPass switches the ST output filter on or off
+func (y *YMPlayer) SetLowPass(on bool) {
+	y.mutex.Lock()
+	defer y.mutex.Unlock()
+	y.filter[0].enabled = on
+	y.filter[1].enabled = on
+}
+
+// setLowPass switches the ST output filter of the YM music
+func (g *Game) setLowPass(on bool) {
+	g.cfg.LowPass = on
+	if y, ok := g.music.(*YMPlayer); ok {
+		y.SetLowPass(on)
+	}
+	if on {
+		g.notice.Show("ST filter ON")
+	} else {
+		g.notice.Show("ST filter OFF")
+	}
+}
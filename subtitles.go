@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// subtitleGap is the run of spaces ending a sentence without a full stop
+const subtitleGap = 5
+
+// SubtitleCue is a sentence of the scroll text and when it is readable
+type SubtitleCue struct {
+	Start, End time.Duration
+	Text       string
+}
+
+// subtitleChar is a character of the scroll text and when it appeared
+type subtitleChar struct {
+	c     byte
+	index int // position in the stream of characters entered
+	at    time.Duration
+}
+
+// SubtitleRecorder follows the characters entering the scroller and
+// times every sentence: from when it is fully on screen, or fills the
+// screen when it is longer, until it starts leaving. Times count from
+// the start of the demo, as in a capture of the screen.
+type SubtitleRecorder struct {
+	start   time.Time
+	pos     int // characters scrolled off so far
+	entered int // characters entered so far
+
+	sentence []subtitleChar
+	spaces   int
+	pending  []pendingCue
+	cues     []SubtitleCue
+}
+
+// pendingCue is a sentence waiting to start leaving the screen
+type pendingCue struct {
+	cue   SubtitleCue
+	leave int // stream index whose departure ends the cue
+}
+
+// NewSubtitleRecorder starts timing the sentences of s, which has the
+// first characters of its text on screen already
+func NewSubtitleRecorder(s *Scroller) *SubtitleRecorder {
+	r := &SubtitleRecorder{start: time.Now()}
+	for i := 0; i < scrollLetters; i++ {
+		r.enter(s.letterAt(i).Letter, 0)
+	}
+	prev := s.OnAdvance
+	s.OnAdvance = func() {
+		if prev != nil {
+			prev()
+		}
+		// Characters scrolling back in ping-pong mode were timed already
+		if s.dir > 0 {
+			r.advance(s.letterAt(scrollLetters - 1).Letter)
+		}
+	}
+	return r
+}
+
+// advance moves the text on by one character, c entering the screen
+func (r *SubtitleRecorder) advance(c byte) {
+	now := time.Since(r.start)
+	r.pos++
+	for len(r.pending) > 0 && r.pending[0].leave < r.pos {
+		p := r.pending[0]
+		p.cue.End = now
+		r.cues = append(r.cues, p.cue)
+		r.pending = r.pending[1:]
+	}
+	r.enter(c, now)
+}
+
+// enter adds a character to the sentence being read, closing it on a
+// full stop or a long gap
+func (r *SubtitleRecorder) enter(c byte, at time.Duration) {
+	index := r.entered
+	r.entered++
+
+	if c == ' ' {
+		r.spaces++
+		n := len(r.sentence)
+		if r.spaces == subtitleGap || n > 0 && isSentenceEnd(r.sentence[n-1].c) {
+			r.close()
+		}
+	} else {
+		r.spaces = 0
+	}
+	if len(r.sentence) == 0 && c == ' ' {
+		return
+	}
+	r.sentence = append(r.sentence, subtitleChar{c, index, at})
+}
+
+func isSentenceEnd(c byte) bool {
+	return c == '.' || c == '!' || c == '?'
+}
+
+// close times the sentence read so far
+func (r *SubtitleRecorder) close() {
+	chars := r.sentence
+	r.sentence = nil
+	for len(chars) > 0 && chars[len(chars)-1].c == ' ' {
+		chars = chars[:len(chars)-1]
+	}
+	if len(chars) == 0 {
+		return
+	}
+
+	var b strings.Builder
+	for _, ch := range chars {
+		b.WriteByte(ch.c)
+	}
+	text := strings.Join(strings.Fields(b.String()), " ")
+	if strings.Trim(text, ".!? ") == "" {
+		return
+	}
+
+	// Readable once the whole sentence, or a screen of it, is shown
+	first, last := chars[0].index, chars[len(chars)-1].index
+	start := chars[0].at
+	for _, ch := range chars {
+		if ch.index <= first+scrollLetters-1 {
+			start = ch.at
+		}
+	}
+	r.pending = append(r.pending, pendingCue{
+		cue:   SubtitleCue{Start: start, Text: text},
+		leave: max(first, last-scrollLetters+1),
+	})
+}
+
+// Cues returns the sentences timed so far, the ones still on screen
+// ending now
+func (r *SubtitleRecorder) Cues() []SubtitleCue {
+	now := time.Since(r.start)
+	cues := append([]SubtitleCue(nil), r.cues...)
+	for _, p := range r.pending {
+		p.cue.End = now
+		cues = append(cues, p.cue)
+	}
+	return cues
+}
+
+// WriteFile exports the cues as WebVTT when path ends in .vtt, SRT
+// otherwise
+func (r *SubtitleRecorder) WriteFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create subtitles: %w", err)
+	}
+	defer f.Close()
+
+	// The sentence being read ends with the demo
+	r.close()
+
+	w := bufio.NewWriter(f)
+	vtt := strings.EqualFold(filepath.Ext(path), ".vtt")
+	if vtt {
+		fmt.Fprint(w, "WEBVTT\n\n")
+	}
+	for i, cue := range r.Cues() {
+		if vtt {
+			fmt.Fprintf(w, "%s --> %s\n%s\n\n", subtitleTime(cue.Start, '.'), subtitleTime(cue.End, '.'), cue.Text)
+		} else {
+			fmt.Fprintf(w, "%d\n%s --> %s\n%s\n\n", i+1, subtitleTime(cue.Start, ','), subtitleTime(cue.End, ','), cue.Text)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write subtitles: %w", err)
+	}
+	return nil
+}
+
+// subtitleTime formats hh:mm:ss followed by the milliseconds, SRT using a
+// comma before them and WebVTT a dot
+func subtitleTime(d time.Duration, sep byte) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%c%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TextEndMode selects what happens when the scroll text runs out
+type TextEndMode int
+
+const (
+	// TextEndLoop wraps seamlessly to the start of the text
+	TextEndLoop TextEndMode = iota
+	// TextEndPingPong scrolls the text back to the start, then forward again
+	TextEndPingPong
+	// TextEndStop halts the scroller with a blinking WRAP cursor
+	TextEndStop
+	// TextEndNext advances the timeline to the next scene
+	TextEndNext
+)
+
+var textEndNames = []string{"loop", "pingpong", "stop", "next"}
+
+func (m TextEndMode) String() string {
+	if m < TextEndLoop || m > TextEndNext {
+		return "unknown"
+	}
+	return textEndNames[m]
+}
+
+// Set implements flag.Value
+func (m *TextEndMode) Set(s string) error {
+	for i, name := range textEndNames {
+		if strings.EqualFold(s, name) {
+			*m = TextEndMode(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown text end mode %q (want %s)", s, strings.Join(textEndNames, ", "))
+}
+
+// scrollLetters is the number of letters on screen at once
+const scrollLetters = 30
+
+// Restart rewinds the scroller to the beginning of the text
+func (s *Scroller) Restart() {
+	s.addi = 0
+	s.scrollX = 0
+	s.dir = 1
+	s.stopped = false
+	s.rtl = s.RightToLeft && s.Feed == nil
+	if s.rtl {
+		s.addi = s.lastLetter()
+	}
+	s.form = 0
+	s.speed = 0
+	s.pause = 0
+}
+
+// advanceText handles the scroller reaching a new letter, applying the
+// end-of-text behavior when the text ran out
+func (s *Scroller) advanceText(ranOut bool) {
+	atEnd := s.lettersLeft() <= s.onScreen()
+
+	switch s.TextEnd {
+	case TextEndPingPong:
+		if atEnd {
+			s.dir = -1
+		}
+	case TextEndStop:
+		if atEnd {
+			s.stopped = true
+			s.scrollX = 0
+		}
+	case TextEndNext:
+		if ranOut {
+			s.wrap()
+			if s.OnTextEnd != nil {
+				s.OnTextEnd()
+			}
+		}
+	default:
+		if ranOut {
+			s.wrap()
+		}
+	}
+}
+
+// wrap restarts the text from its first character, its last letter right
+// to left, the one moment the text can be replaced without a visible cut
+func (s *Scroller) wrap() {
+	s.addi = 0
+	if s.OnWrap != nil {
+		s.OnWrap()
+	}
+	if s.rtl {
+		s.addi = s.lastLetter()
+	}
+}
+
+// drawWrapCursor blinks "WRAP" in the corner of the stopped scroller
+func (s *Scroller) drawWrapCursor() {
+	if !s.stopped || (s.ticks/30)%2 == 1 {
+		return
+	}
+
+	const scale = 0.5
+	x := float64(s.canvas.Bounds().Dx()) - 4*32*scale - 8
+	y := float64(s.canvas.Bounds().Dy()) - 33*scale - 8
+	s.drawText("WRAP", x, y, scale)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// textlintTimeout bounds a textlint run on a remote text
+const textlintTimeout = 10 * time.Second
+
+// Textlint checks texts with the textlint command line tool, which
+// picks its rules from the .textlintrc of the working directory
+type Textlint struct {
+	Command []string // textlint and its leading arguments, e.g. npx textlint
+}
+
+// NewTextlint returns the checker running command, split on spaces,
+// once it is found
+func NewTextlint(command string) (*Textlint, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, errors.New("empty textlint command")
+	}
+	if _, err := exec.LookPath(args[0]); err != nil {
+		return nil, fmt.Errorf("failed to find textlint: %w", err)
+	}
+	return &Textlint{Command: args}, nil
+}
+
+// textlintResult is a file of the JSON report of textlint
+type textlintResult struct {
+	Messages []struct {
+		RuleID  string `json:"ruleId"`
+		Message string `json:"message"`
+		Line    int    `json:"line"`
+		Column  int    `json:"column"`
+	} `json:"messages"`
+}
+
+// Check lints text and returns the problems textlint reports, one line
+// each. The error is for textlint failing to run.
+func (l *Textlint) Check(ctx context.Context, text string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, textlintTimeout)
+	defer cancel()
+
+	args := append(l.Command[1:len(l.Command):len(l.Command)], "--stdin", "--stdin-filename", "scroll.txt", "--format", "json")
+	cmd := exec.CommandContext(ctx, l.Command[0], args...)
+	cmd.Stdin = strings.NewReader(text)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err == nil {
+		return nil, nil
+	}
+	// textlint exits with 1 when it reports problems
+	var exit *exec.ExitError
+	if !errors.As(err, &exit) || exit.ExitCode() != 1 {
+		return nil, fmt.Errorf("failed to run textlint: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var results []textlintResult
+	if err := json.Unmarshal(out, &results); err != nil {
+		return nil, fmt.Errorf("failed to read textlint report: %w", err)
+	}
+	var problems []string
+	for _, r := range results {
+		for _, m := range r.Messages {
+			problems = append(problems, fmt.Sprintf("%d:%d %s (%s)", m.Line, m.Column, m.Message, m.RuleID))
+		}
+	}
+	if len(problems) == 0 {
+		return nil, fmt.Errorf("textlint failed without reporting a problem: %s", strings.TrimSpace(stderr.String()))
+	}
+	return problems, nil
+}
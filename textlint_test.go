@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeTextlint writes a stand-in for textlint reporting the word BAD
+func fakeTextlint(t *testing.T) *Textlint {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the stand-in is a shell script")
+	}
+	script := `#!/bin/sh
+if grep -q BAD; then
+	echo '[{"filePath":"scroll.txt","messages":[{"ruleId":"no-bad","message":"BAD is bad","line":1,"column":7}]}]'
+	exit 1
+fi
+echo '[{"filePath":"scroll.txt","messages":[]}]'
+`
+	path := filepath.Join(t.TempDir(), "textlint")
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	lint, err := NewTextlint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lint
+}
+
+func TestTextlintCheck(t *testing.T) {
+	lint := fakeTextlint(t)
+	problems, err := lint.Check(context.Background(), "HELLO WORLD")
+	if err != nil || len(problems) != 0 {
+		t.Errorf("clean text: problems %q, error %v", problems, err)
+	}
+	problems, err = lint.Check(context.Background(), "HELLO BAD WORLD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 1 || !strings.Contains(problems[0], "1:7 BAD is bad (no-bad)") {
+		t.Errorf("problems %q, want the one of no-bad", problems)
+	}
+}
+
+func TestTextlintFailure(t *testing.T) {
+	lint := fakeTextlint(t)
+	lint.Command = []string{"sh", "-c", "exit 2", "--"}
+	if _, err := lint.Check(context.Background(), "HELLO"); err == nil {
+		t.Error("a crashing textlint passed the text")
+	}
+}
+
+func TestTextlintMissing(t *testing.T) {
+	if _, err := NewTextlint("no-such-textlint-command"); err == nil {
+		t.Error("found a command that doesn't exist")
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import "math"
+
+// Ticks counts updates as an integer, from which the phases and
+// positions moving a fixed step every update are derived. Summing the
+// steps instead gathers the rounding error of every addition, and a
+// float growing through a kiosk run of days loses the precision of its
+// fraction, so waves and strips slowly drift from where they should be.
+type Ticks uint64
+
+// At returns how far something moving rate a tick has gone after t
+// ticks
+func (t Ticks) At(rate float64) float64 {
+	return float64(t) * rate
+}
+
+// Phase returns the angle of a wave turning rate radians a tick after t
+// ticks, wrapped into [0, 2π)
+func (t Ticks) Phase(rate float64) float64 {
+	return t.Wrap(rate, 2*math.Pi)
+}
+
+// Wrap returns the distance of At wrapped into [0, period)
+func (t Ticks) Wrap(rate, period float64) float64 {
+	v := math.Mod(t.At(rate), period)
+	if v < 0 {
+		v += period
+	}
+	return v
+}
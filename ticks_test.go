@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/base64"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// longRun is about a day of updates at 60 a second
+const longRun = 5_000_000
+
+// wrapTolerance is how far a wrapped phase or position may be from the
+// exact closed form: the rounding of one multiplication, not of millions
+// of additions
+const wrapTolerance = 1e-9
+
+// placeTolerance is how far in pixels a letter may be from where the
+// closed form puts it, the wave sizes scaling the rounding of the phases
+const placeTolerance = 1e-6
+
+// closedForm returns n*rate mod period worked out exactly from the float64
+// rate and period, and rounded once
+func closedForm(n uint64, rate, period float64) float64 {
+	const prec = 256
+	x := new(big.Float).SetPrec(prec).SetUint64(n)
+	x.Mul(x, new(big.Float).SetPrec(prec).SetFloat64(rate))
+	p := new(big.Float).SetPrec(prec).SetFloat64(period)
+	q, _ := new(big.Float).SetPrec(prec).Quo(x, p).Int(nil)
+	if x.Sign() < 0 {
+		q.Sub(q, big.NewInt(1))
+	}
+	x.Sub(x, new(big.Float).SetPrec(prec).Mul(new(big.Float).SetPrec(prec).SetInt(q), p))
+	v, _ := x.Float64()
+	return v
+}
+
+// near reports whether the wrapped a and b are within wrapTolerance,
+// either side of the wrap counting as the same point
+func near(a, b, period float64) bool {
+	d := math.Abs(a - b)
+	return d <= wrapTolerance || math.Abs(d-period) <= wrapTolerance
+}
+
+func TestTicksPhase(t *testing.T) {
+	rates := []float64{waveStep, waveStep * 3, waveStep * -2.5, 0.0031, 1.7}
+	for _, rate := range rates {
+		var n Ticks
+		for i := 0; i < longRun; i++ {
+			n++
+			p := n.Phase(rate)
+			if p < 0 || p >= 2*math.Pi {
+				t.Fatalf("rate %g, tick %d: phase %g outside [0, 2π)", rate, n, p)
+			}
+			if i%9973 == 0 || i == longRun-1 {
+				if want := closedForm(uint64(n), rate, 2*math.Pi); !near(p, want, 2*math.Pi) {
+					t.Fatalf("rate %g, tick %d: phase %.15g, want %.15g", rate, n, p, want)
+				}
+			}
+		}
+	}
+}
+
+func TestTicksWrap(t *testing.T) {
+	for _, rate := range []float64{0.25, 0.3, 8, -1.1} {
+		const period = 256
+		var n Ticks
+		for i := 0; i < longRun; i++ {
+			n++
+			v := n.Wrap(rate, period)
+			if v < 0 || v >= period {
+				t.Fatalf("rate %g, tick %d: %g outside [0, %d)", rate, n, v, period)
+			}
+			if i%9973 == 0 || i == longRun-1 {
+				if want := closedForm(uint64(n), rate, period); !near(v, want, period) {
+					t.Fatalf("rate %g, tick %d: %.15g, want %.15g", rate, n, v, want)
+				}
+			}
+		}
+		if got, want := n.At(rate), float64(longRun)*rate; got != want {
+			t.Errorf("rate %g: At %g, want %g", rate, got, want)
+		}
+	}
+}
+
+func TestScrollerWaveLongRun(t *testing.T) {
+	s := &Scroller{canvas: ebiten.NewImage(screenWidth, screenHeight)}
+	sf := scrollForms[len(scrollForms)-1]
+	const n = 7.0 // letter of the text
+	for i := 0; i < longRun; i++ {
+		s.wave++
+		if i%99991 != 0 && i != longRun-1 {
+			continue
+		}
+		phases := sf.phasesAt(s.wave)
+		x, y, scale := s.place(0, fixedAdvance, n, sf, &phases)
+		w := uint64(s.wave)
+		z := sf.zSize*math.Sin(sf.zAdd+n*sf.zAmount*0.01+closedForm(w, waveStep*sf.zSpeed, 2*math.Pi)) + 150
+		swing := sf.ySize * math.Cos(1.5+n*sf.yAmount*0.01+closedForm(w, waveStep*sf.ySpeed, 2*math.Pi))
+		wobble := sf.xSize * math.Sin(n*sf.xAmount*0.01+closedForm(w, waveStep*sf.xSpeed, 2*math.Pi))
+		wx, wy, wscale := s.project(-450+fixedAdvance.offset+wobble, swing-4, z)
+		if math.Abs(x-wx) > placeTolerance || math.Abs(y-wy) > placeTolerance || math.Abs(scale-wscale) > wrapTolerance {
+			t.Fatalf("update %d: letter at %g, %g scale %g, want %g, %g scale %g", w, x, y, scale, wx, wy, wscale)
+		}
+	}
+}
+
+func TestCameraLongRun(t *testing.T) {
+	c := NewCamera(24, 0.007, true)
+	for i := 1; i <= longRun; i++ {
+		c.Update()
+		if i%9973 != 0 && i != longRun {
+			continue
+		}
+		want := c.Amplitude * math.Sin(closedForm(uint64(i), c.Speed, 2*math.Pi))
+		if math.Abs(c.X-want) > wrapTolerance {
+			t.Fatalf("update %d: pan %.15g, want %.15g", i, c.X, want)
+		}
+	}
+}
+
+func TestLandscapeLongRun(t *testing.T) {
+	// A few layers far apart in speed, all of them take a while
+	var g Game
+	for i, l := range defaultMountainLayers() {
+		if i%8 == 0 {
+			g.layers = append(g.layers, l)
+		}
+	}
+	g.bgPos = make([]float64, len(g.layers))
+	for i := 1; i <= longRun; i++ {
+		g.scrollLandscape()
+		if i%9973 != 0 && i != longRun {
+			continue
+		}
+		for j, l := range g.layers {
+			want := -closedForm(uint64(i), l.Speed, 256)
+			if !near(g.bgPos[j], want, 256) {
+				t.Fatalf("update %d, layer %d: strip at %.15g, want %.15g", i, j, g.bgPos[j], want)
+			}
+		}
+	}
+}
+
+func TestMomentRoundTrip(t *testing.T) {
+	m := Moment{
+		TextSum: 0xdeadbeef,
+		TextPos: 1234,
+		ScrollX: 17.5,
+		Wave:    longRun,
+		Music:   98765,
+		Form:    3,
+		Palette: 2,
+	}
+	token := m.String()
+	got, err := ParseMoment(token)
+	if err != nil {
+		t.Fatalf("ParseMoment(%q): %v", token, err)
+	}
+	if got != m {
+		t.Errorf("ParseMoment(%q) = %+v, want %+v", token, got, m)
+	}
+
+	// Tokens of the first version held the wave as a float phase
+	old := []byte{momentVersion - 1}
+	old = append(old, make([]byte, 22)...)
+	if _, err := ParseMoment(base64.RawURLEncoding.EncodeToString(old)); err == nil {
+		t.Error("ParseMoment accepted a version 1 token")
+	}
+}
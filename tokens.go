@@ -0,0 +1,214 @@
+package main
+
+// ControlCode is a parsed control code, Kind being the byte after '^'
+// and Arg its digit
+type ControlCode struct {
+	Kind byte
+	Arg  int
+}
+
+// ScrollToken is a letter of the scroll text, or one of its control
+// codes when Letter is 0. Image stamps are letters.
+type ScrollToken struct {
+	Letter byte
+	Code   ControlCode
+	Pos    int // offset of the token in the text
+	N      int // letters before the token in the text
+	Color  int // color bank in effect
+	Font   int // font face in effect
+	// Emphasis is the ^W effect on the letter's word, 0 for none
+	Emphasis int
+	// Stamp names the image of a stampMarker letter
+	Stamp string
+}
+
+// IsCode reports whether t is a control code
+func (t ScrollToken) IsCode() bool {
+	return t.Letter == 0
+}
+
+// TokenizeScrollText parses text into the stream of letters and control
+// codes the scroller consumes. The color and font codes hold until the
+// next one, from the start of the text, the ^W codes until the end of
+// the word after them.
+func TokenizeScrollText(text string) []ScrollToken {
+	tokens := make([]ScrollToken, 0, len(text))
+	color, font, n := 0, 0, 0
+	emphasis, inWord := emphasisNone, false
+	for i := 0; i < len(text); {
+		size := controlCodeLen(text, i)
+		if size == 0 {
+			c := text[i]
+			if c == ' ' && inWord {
+				emphasis, inWord = emphasisNone, false
+			}
+			inWord = inWord || (c != ' ' && emphasis != emphasisNone)
+			tokens = append(tokens, ScrollToken{Letter: c, Pos: i, N: n, Color: color, Font: font, Emphasis: emphasis})
+			n++
+			i++
+			continue
+		}
+
+		if text[i+1] == '[' {
+			// Image stamps take a letter slot
+			inWord = inWord || emphasis != emphasisNone
+			tokens = append(tokens, ScrollToken{Letter: stampMarker, Stamp: stampName(text, i, size), Pos: i, N: n, Color: color, Font: font, Emphasis: emphasis})
+			n++
+			i += size
+			continue
+		}
+
+		code := ControlCode{Kind: upper(text[i+1])}
+		switch {
+		case isFormCode(code.Kind):
+			code.Arg = int(code.Kind - '0')
+		case size == 3:
+			code.Arg = int(text[i+2] - '0')
+		}
+		switch code.Kind {
+		case 'C':
+			color = min(code.Arg, len(colorBanks)-1)
+		case 'F':
+			font = code.Arg
+		case 'W':
+			emphasis, inWord = code.Arg, false
+		}
+		tokens = append(tokens, ScrollToken{Code: code, Pos: i, N: n, Color: color, Font: font})
+		i += size
+	}
+	return tokens
+}
+
+// tokenStream is the scroll text parsed once for the scroller
+type tokenStream struct {
+	text    string
+	tokens  []ScrollToken
+	at      []int // token holding each byte of the text
+	letters int
+}
+
+// tokens returns the tokens of the current text, parsed again when it
+// changed
+func (s *Scroller) tokens() []ScrollToken {
+	st := &s.stream
+	if st.text == s.Text && st.at != nil {
+		return st.tokens
+	}
+	st.text = s.Text
+	st.tokens = TokenizeScrollText(s.Text)
+	st.at = make([]int, len(s.Text))
+	st.letters = 0
+	for k, t := range st.tokens {
+		end := len(s.Text)
+		if k+1 < len(st.tokens) {
+			end = st.tokens[k+1].Pos
+		}
+		for i := t.Pos; i < end; i++ {
+			st.at[i] = k
+		}
+		if !t.IsCode() {
+			st.letters++
+		}
+	}
+	return st.tokens
+}
+
+// tokenAt returns the index of the token holding text offset pos, the
+// number of tokens past the end of the text
+func (s *Scroller) tokenAt(pos int) int {
+	tokens := s.tokens()
+	if pos >= len(s.Text) {
+		return len(tokens)
+	}
+	return s.stream.at[max(pos, 0)]
+}
+
+// wrapToken returns the index of token k of a text of n tokens repeating
+// both ways
+func wrapToken(k, n int) int {
+	return (k%n + n) % n
+}
+
+// letterAt returns the letter shown in slot i, wrapping around the text
+func (s *Scroller) letterAt(i int) ScrollToken {
+	tokens := s.tokens()
+	if s.stream.letters == 0 {
+		return ScrollToken{Letter: ' '}
+	}
+	for k := s.tokenAt(s.addi); ; k += s.textDir() {
+		t := tokens[wrapToken(k, len(tokens))]
+		if t.IsCode() {
+			continue
+		}
+		if i == 0 {
+			return t
+		}
+		i--
+	}
+}
+
+// nextLetter returns the offset of the letter following the one in the
+// first slot, the length of the text when it is the last one
+func (s *Scroller) nextLetter() int {
+	tokens := s.tokens()
+	first := true
+	for k := s.tokenAt(s.addi); k < len(tokens); k++ {
+		if tokens[k].IsCode() {
+			continue
+		}
+		if !first {
+			return tokens[k].Pos
+		}
+		first = false
+	}
+	return len(s.Text)
+}
+
+// prevLetter returns the offset of the letter before the first slot, 0
+// at the start of the text
+func (s *Scroller) prevLetter() int {
+	tokens := s.tokens()
+	for k := s.tokenAt(s.addi) - 1; k >= 0; k-- {
+		if !tokens[k].IsCode() {
+			return tokens[k].Pos
+		}
+	}
+	return 0
+}
+
+// firstLetter returns the offset of the first letter of the text, 0
+// when it has none
+func (s *Scroller) firstLetter() int {
+	for _, t := range s.tokens() {
+		if !t.IsCode() {
+			return t.Pos
+		}
+	}
+	return 0
+}
+
+// lastLetter returns the offset of the last letter of the text, 0 when
+// it has none
+func (s *Scroller) lastLetter() int {
+	tokens := s.tokens()
+	for k := len(tokens) - 1; k >= 0; k-- {
+		if !tokens[k].IsCode() {
+			return tokens[k].Pos
+		}
+	}
+	return 0
+}
+
+// lettersLeft returns the letters from the first slot to the end of the
+// text, to its start right to left
+func (s *Scroller) lettersLeft() int {
+	tokens := s.tokens()
+	k := s.tokenAt(s.addi)
+	if k >= len(tokens) {
+		return 0
+	}
+	if s.rtl {
+		return tokens[k].N + 1
+	}
+	return s.stream.letters - tokens[k].N
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// ttfColumns is the width of the sheet the glyphs of a TrueType font
+// are rasterized into, in tiles
+const ttfColumns = 16
+
+// isTTF reports whether path names a TrueType or OpenType font
+func isTTF(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".ttf" || ext == ".otf"
+}
+
+// LoadTTFSheet rasterizes the printable ASCII characters of a TrueType
+// or OpenType font at size pixels, one tile each, white on transparent
+// as bgfont.png. It returns the sheet and the place of every glyph.
+func LoadTTFSheet(path string, size float64) (*image.RGBA, map[rune]image.Rectangle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read font: %w", err)
+	}
+	f, err := opentype.Parse(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse font: %w", err)
+	}
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create font face: %w", err)
+	}
+	defer face.Close()
+
+	// Center the line of the font in the tile height
+	m := face.Metrics()
+	height := (m.Ascent + m.Descent).Ceil()
+	if height > fontTileHeight {
+		return nil, nil, fmt.Errorf("%s at %g pixels is %d pixels high, more than the %d of a tile", path, size, height, fontTileHeight)
+	}
+	baseline := (fontTileHeight-height)/2 + m.Ascent.Ceil()
+
+	const first, last = '!', '~'
+	n := int(last - first + 1)
+	sheet := image.NewRGBA(image.Rect(0, 0, ttfColumns*fontTileWidth, (n+ttfColumns-1)/ttfColumns*fontTileHeight))
+	cells := make(map[rune]image.Rectangle, n)
+	d := &font.Drawer{Dst: sheet, Src: image.White, Face: face}
+	for r := rune(first); r <= last; r++ {
+		if _, ok := face.GlyphAdvance(r); !ok {
+			continue
+		}
+		i := int(r - first)
+		cell := image.Rect(0, 0, fontTileWidth, fontTileHeight).Add(image.Pt(i%ttfColumns*fontTileWidth, i/ttfColumns*fontTileHeight))
+
+		// Glyphs wider than a tile are clipped by drawing on the cell
+		width := font.MeasureString(face, string(r)).Ceil()
+		d.Dst = sheet.SubImage(cell).(draw.Image)
+		d.Dot = fixed.P(cell.Min.X+(fontTileWidth-width)/2, cell.Min.Y+baseline)
+		d.DrawString(string(r))
+		cells[r] = cell
+	}
+	return sheet, cells, nil
+}
+
+// ttfTiles cuts the tiles of a rasterized font out of img, which holds
+// sheet, with the columns each covers. Digits the font lacks are drawn.
+func ttfTiles(img *ebiten.Image, sheet image.Image, cells map[rune]image.Rectangle) (map[rune]*ebiten.Image, map[rune][2]int) {
+	tiles := map[rune]*ebiten.Image{' ': ebiten.NewImage(fontTileWidth, fontTileHeight)}
+	spans := make(map[rune][2]int)
+	addDigitTiles(tiles)
+	for r := '0'; r <= '9'; r++ {
+		spans[r] = [2]int{digitLeft, digitRight}
+	}
+	for r, cell := range cells {
+		tiles[r] = img.SubImage(cell).(*ebiten.Image)
+		spans[r] = glyphSpan(sheet, cell)
+	}
+	return tiles, spans
+}
+
+// loadTTFFont replaces the font with a TrueType or OpenType one
+func (g *Game) loadTTFFont(path string) error {
+	sheet, cells, err := LoadTTFSheet(path, g.cfg.TTFSize)
+	if err != nil {
+		return err
+	}
+
+	g.font = ebiten.NewImageFromImage(sheet)
+	tiles, spans := ttfTiles(g.font, sheet, cells)
+	clear(g.fontTiles)
+	clear(g.fontSpans)
+	maps.Copy(g.fontTiles, tiles)
+	maps.Copy(g.fontSpans, spans)
+	fontChars = tileChars(tiles, "")
+	g.fontName = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"image"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"tcb-multi-plane-3d-scroller/easings"
+)
+
+// wobbleEnvelope swells the wave and lets it die along a sine arch
+var wobbleEnvelope = easings.Mirror(easings.OutSine)
+
+// WobbleTransition shakes the whole frame with a swelling and dying
+// sine wave, used to mask cuts between scenes
+type WobbleTransition struct {
+	*DisplacementEffect
+	frame    int
+	duration int
+	peak     float64
+}
+
+// NewWobbleTransition creates a transition over a canvas of size w x h
+func NewWobbleTransition(displacer *Displacer, w, h int, duration int, peak float64) *WobbleTransition {
+	texture := NewSineDisplacementTexture(64, 96, 96, 64)
+
+	t := &WobbleTransition{
+		DisplacementEffect: NewDisplacementEffect(displacer, image.Rect(0, 0, w, h), texture, nil),
+		frame:              duration,
+		duration:           duration,
+		peak:               peak,
+	}
+	t.ScrollY = -3
+	t.ScrollX = 2
+	return t
+}
+
+// Start runs the transition from the beginning
+func (t *WobbleTransition) Start() {
+	t.frame = 0
+}
+
+// Active reports whether the transition is still running
+func (t *WobbleTransition) Active() bool {
+	return t.frame < t.duration
+}
+
+// Update advances the wave and its envelope
+func (t *WobbleTransition) Update() {
+	if !t.Active() {
+		t.AmountX, t.AmountY = 0, 0
+		return
+	}
+
+	t.frame++
+	envelope := wobbleEnvelope(float64(t.frame) / float64(t.duration))
+	t.AmountX = t.peak * envelope
+	t.AmountY = t.peak * envelope / 2
+	t.DisplacementEffect.Update()
+}
+
+// Apply warps the canvas while the transition runs
+func (t *WobbleTransition) Apply(canvas *ebiten.Image) {
+	if t.Active() {
+		t.DisplacementEffect.Apply(canvas)
+	}
+}
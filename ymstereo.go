@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// YMStereo selects where the three YM voices sit in the stereo field
+type YMStereo int
+
+const (
+	// YMStereoMono plays the mix of the replayer on both sides, as the
+	// Atari ST did
+	YMStereoMono YMStereo = iota
+	// YMStereoABC puts voice A left, B in the middle and C right
+	YMStereoABC
+	// YMStereoACB puts voice A left, C in the middle and B right
+	YMStereoACB
+)
+
+var ymStereoNames = []string{"mono", "abc", "acb"}
+
+func (m YMStereo) String() string {
+	if m < YMStereoMono || m > YMStereoACB {
+		return "unknown"
+	}
+	return ymStereoNames[m]
+}
+
+// Set implements flag.Value
+func (m *YMStereo) Set(s string) error {
+	for i, name := range ymStereoNames {
+		if strings.EqualFold(s, name) {
+			*m = YMStereo(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown YM stereo scheme %q (want %s)", s, strings.Join(ymStereoNames, ", "))
+}
+
+// ymStereoSeparation is how far the side voices are panned, full
+// separation sounds harsh on headphones
+const ymStereoSeparation = 0.75
+
+// ymStereoSteps is how many times a frame of the tune the registers are
+// read in stereo, as the voices follow them
+const ymStereoSteps = 4
+
+// ymDCCutoff is the corner of the high-pass taking the offset out of the
+// stereo voices, which the DAC only ever adds to
+const ymDCCutoff = 20
+
+// ymStereoScale brings the voices to the level of the replayer output
+const ymStereoScale = 32767
+
+// gains returns the left and right gains of every voice
+func (m YMStereo) gains() [3][2]float64 {
+	var pans [3]float64
+	switch m {
+	case YMStereoABC:
+		pans = [3]float64{-ymStereoSeparation, 0, ymStereoSeparation}
+	case YMStereoACB:
+		pans = [3]float64{-ymStereoSeparation, ymStereoSeparation, 0}
+	}
+	var g [3][2]float64
+	for c, pan := range pans {
+		// The middle keeps the mono loudness
+		g[c] = [2]float64{min(1, 1-pan), min(1, 1+pan)}
+	}
+	return g
+}
+
+// stereoFrame mixes the voices re-synthesized for the current sample on
+// their sides
+func (y *YMPlayer) stereoFrame(gains *[3][2]float64) (left, right float64) {
+	var side [2]float64
+	for c, v := range y.voices.dac {
+		side[0] += v * gains[c][0]
+		side[1] += v * gains[c][1]
+	}
+	coef := onePole(ymDCCutoff, float64(y.sampleRate))
+	for i := range side {
+		y.dc[i] += (side[i] - y.dc[i]) * coef
+		side[i] = (side[i] - y.dc[i]) * ymStereoScale
+	}
+	return side[0], side[1]
+}
+
+// SetStereo sets where the voices sit in the stereo field
+func (y *YMPlayer) SetStereo(m YMStereo) {
+	y.mutex.Lock()
+	defer y.mutex.Unlock()
+	y.stereo = m
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// voiceEnergy plays voice c alone as a 440 Hz tone at full volume in
+// scheme m and returns the energy of each side
+func voiceEnergy(m YMStereo, c int) (left, right float64) {
+	y := &YMPlayer{sampleRate: 44100, stereo: m}
+	period := psgClock / 16 / 440
+	y.regs[2*c] = period & 0xff
+	y.regs[2*c+1] = period >> 8
+	y.regs[7] = 0x3f &^ (1 << c)
+	y.regs[8+c] = 15
+	y.levels[c] = 1
+
+	gains := m.gains()
+	for range y.sampleRate {
+		y.voices.next(&y.regs, &y.levels, y.sampleRate)
+		l, r := y.stereoFrame(&gains)
+		left += l * l
+		right += r * r
+	}
+	return left, right
+}
+
+func TestYMStereoPlacesVoices(t *testing.T) {
+	side := (1 - ymStereoSeparation) * (1 - ymStereoSeparation)
+	tests := []struct {
+		m     YMStereo
+		voice int
+		ratio float64 // right energy over left
+	}{
+		{YMStereoABC, 0, side},
+		{YMStereoABC, 1, 1},
+		{YMStereoABC, 2, 1 / side},
+		{YMStereoACB, 1, 1 / side},
+		{YMStereoACB, 2, 1},
+	}
+	// The high-pass settling at the start moves the ratios a little
+	for _, tt := range tests {
+		left, right := voiceEnergy(tt.m, tt.voice)
+		if left == 0 {
+			t.Fatalf("%v, voice %d: silent", tt.m, tt.voice)
+		}
+		if got := right / left; math.Abs(got-tt.ratio) > 1e-3*tt.ratio {
+			t.Errorf("%v, voice %d: right/left energy %g, want %g", tt.m, tt.voice, got, tt.ratio)
+		}
+	}
+}